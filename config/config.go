@@ -1,11 +1,33 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+)
 
 //holds the config values for the application
 type Config struct {
 	ServerAddress string
 	OutputDir     string
+	PDFTheme      string
+	PDFThemesFile string
+	PDFPasswordRule  string
+	PDFOwnerPassword string
+	ShareBaseURL     string
+	ShareLinkSecret  string
+	ShareLinkTTLDays int
+	QuoteLinkTTLDays int
+	AgencyName       string
+	AgencyPhone      string
+	AgencyEmail      string
+	PDFBatchWorkers  int
+	MinConnectionMinutes int
+	TransferArrivalBufferMinutes   int
+	TransferDepartureBufferMinutes int
+	GeocoderURL       string
+	GeocoderUserAgent string
+	DefaultCurrency   string
+	ExchangeRates     string
 }
 
 //func NewConfig() initializes a new Config instance 
@@ -21,10 +43,114 @@ func NewConfig() *Config {
 	if outputDir == "" {
 		outputDir = "./output"
 	}
+
+	//gets the default PDF theme from .env default is classic
+	pdfTheme := os.Getenv("PDF_THEME")
+	if pdfTheme == "" {
+		pdfTheme = "classic"
+	}
+
+	//optional JSON file of PDF themes e.g. with the watermark texts of each theme
+	pdfThemesFile := os.Getenv("PDF_THEMES_FILE")
+
+	//optional rule used to password protect every PDF e.g. confirmation_number
+	pdfPasswordRule := os.Getenv("PDF_PASSWORD_RULE")
+
+	//owner password for protected PDFs, a random one is used when empty
+	pdfOwnerPassword := os.Getenv("PDF_OWNER_PASSWORD")
+
+	//public base url used in share links and QR codes default is http://localhost:8080
+	shareBaseURL := os.Getenv("SHARE_BASE_URL")
+	if shareBaseURL == "" {
+		shareBaseURL = "http://localhost:8080"
+	}
+
+	//secret used to sign share links, a random one is used when empty
+	shareLinkSecret := os.Getenv("SHARE_LINK_SECRET")
+
+	//gets how many days share links and printed QR codes stay valid default is 90
+	shareLinkTTLDays, err := strconv.Atoi(os.Getenv("SHARE_LINK_TTL_DAYS"))
+	if err != nil || shareLinkTTLDays < 1 {
+		shareLinkTTLDays = 90
+	}
+
+	//gets how many days the links customers answer quotes with stay valid default is 14
+	quoteLinkTTLDays, err := strconv.Atoi(os.Getenv("QUOTE_LINK_TTL_DAYS"))
+	if err != nil || quoteLinkTTLDays < 1 {
+		quoteLinkTTLDays = 14
+	}
+
+
+	//agency contact details printed on hotel and transfer vouchers
+	agencyName := os.Getenv("AGENCY_NAME")
+	agencyPhone := os.Getenv("AGENCY_PHONE")
+	agencyEmail := os.Getenv("AGENCY_EMAIL")
+
+
+	//gets the number of PDFs rendered in parallel by batch jobs default is 4
+	pdfBatchWorkers, err := strconv.Atoi(os.Getenv("PDF_BATCH_WORKERS"))
+	if err != nil || pdfBatchWorkers < 1 {
+		pdfBatchWorkers = 4
+	}
+
+	//gets the minimum time needed to change flights default is 60 minutes
+	minConnectionMinutes, err := strconv.Atoi(os.Getenv("MIN_CONNECTION_MINUTES"))
+	if err != nil || minConnectionMinutes < 1 {
+		minConnectionMinutes = 60
+	}
+
+	//gets the time between landing and the airport pick-up default is 45 minutes
+	transferArrivalBuffer, err := strconv.Atoi(os.Getenv("TRANSFER_ARRIVAL_BUFFER_MINUTES"))
+	if err != nil || transferArrivalBuffer < 1 {
+		transferArrivalBuffer = 45
+	}
+
+	//gets the time between the hotel pick-up and take-off default is 180 minutes
+	transferDepartureBuffer, err := strconv.Atoi(os.Getenv("TRANSFER_DEPARTURE_BUFFER_MINUTES"))
+	if err != nil || transferDepartureBuffer < 1 {
+		transferDepartureBuffer = 180
+	}
+
+	//optional Nominatim compatible geocoding service, the offline gazetteer is used when empty
+	geocoderURL := os.Getenv("GEOCODER_URL")
+
+	//user agent sent to the geocoding service default is itinerary-builder-api
+	geocoderUserAgent := os.Getenv("GEOCODER_USER_AGENT")
+	if geocoderUserAgent == "" {
+		geocoderUserAgent = "itinerary-builder-api"
+	}
+
+	//gets the ISO 4217 currency of amounts given without one default is INR
+	defaultCurrency := os.Getenv("DEFAULT_CURRENCY")
+	if defaultCurrency == "" {
+		defaultCurrency = "INR"
+	}
+
+	//optional exchange rate table e.g. EUR=1,USD=1.08,INR=90.12, amounts in different currencies can't be combined when empty
+	exchangeRates := os.Getenv("EXCHANGE_RATES")
 	
 	//returns pointer to new Config instance
 	return &Config{
 		ServerAddress: serverAddr,
 		OutputDir:     outputDir,
+		PDFTheme:      pdfTheme,
+		PDFThemesFile: pdfThemesFile,
+		PDFPasswordRule:  pdfPasswordRule,
+		PDFOwnerPassword: pdfOwnerPassword,
+		ShareBaseURL:     shareBaseURL,
+		ShareLinkSecret:  shareLinkSecret,
+		ShareLinkTTLDays: shareLinkTTLDays,
+		QuoteLinkTTLDays: quoteLinkTTLDays,
+		AgencyName:       agencyName,
+		AgencyPhone:      agencyPhone,
+		AgencyEmail:      agencyEmail,
+		PDFBatchWorkers:  pdfBatchWorkers,
+		MinConnectionMinutes: minConnectionMinutes,
+		TransferArrivalBufferMinutes:   transferArrivalBuffer,
+		TransferDepartureBufferMinutes: transferDepartureBuffer,
+		GeocoderURL:       geocoderURL,
+		GeocoderUserAgent: geocoderUserAgent,
+		DefaultCurrency:   defaultCurrency,
+		ExchangeRates:     exchangeRates,
 	}
 }
\ No newline at end of file
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/service"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+//acts as a handler for HTTP Requests related to batch PDF generation
+type BatchController struct {
+	batchService *service.BatchService
+}
+
+//NewBatchController creates and returns a new BatchController instance
+func NewBatchController(batchSvc *service.BatchService) *BatchController {
+	return &BatchController{
+		batchService: batchSvc,
+	}
+}
+
+// StartBatch handles POST /api/v1/pdf-batches
+//starts rendering every itinerary matching the filter (or list of ids) in the background
+func (bc *BatchController) StartBatch(c *gin.Context) {
+	var req service.BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := bc.batchService.StartBatch(&req)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrBatchEmpty) {
+			statusCode = http.StatusBadRequest
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetBatch handles GET /api/v1/pdf-batches/:id
+//reports the progress and per item errors of a batch
+func (bc *BatchController) GetBatch(c *gin.Context) {
+	job, err := bc.batchService.GetBatch(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// DownloadBatch handles GET /api/v1/pdf-batches/:id/download
+//serves the ZIP archive once the batch has finished
+func (bc *BatchController) DownloadBatch(c *gin.Context) {
+	archive, err := bc.batchService.ArchivePath(c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrBatchNotFound):
+			statusCode = http.StatusNotFound
+		case errors.Is(err, service.ErrBatchNotFinished):
+			statusCode = http.StatusConflict
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.FileAttachment(archive, path.Base(archive))
+}
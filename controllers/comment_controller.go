@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//acts as a handler for HTTP Requests on comments and internal notes
+type CommentController struct {
+	comments *service.CommentService
+}
+
+//NewCommentController creates and returns a new CommentController instance
+func NewCommentController(comments *service.CommentService) *CommentController {
+	return &CommentController{
+		comments: comments,
+	}
+}
+
+// GetComments handles GET /api/v1/itineraries/:id/comments
+//lists the comment threads of an itinerary, filtered by ?visibility=internal|customer
+func (cc *CommentController) GetComments(c *gin.Context) {
+	threads, err := cc.comments.Threads(c.Param("id"), c.Query("visibility"))
+	if err != nil {
+		c.JSON(commentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, threads)
+}
+
+// AddComment handles POST /api/v1/itineraries/:id/comments
+//adds a comment or a reply to the itinerary or one of its elements
+func (cc *CommentController) AddComment(c *gin.Context) {
+	var req models.CreateCommentReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := cc.comments.AddComment(c.Param("id"), &req)
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		c.JSON(commentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// UpdateComment handles PUT /api/v1/itineraries/:id/comments/:commentId
+//changes the text or visibility of a comment
+func (cc *CommentController) UpdateComment(c *gin.Context) {
+	var req models.UpdateCommentReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request payload",
+		})
+		return
+	}
+
+	comment, err := cc.comments.UpdateComment(c.Param("id"), c.Param("commentId"), &req)
+	if err != nil {
+		c.JSON(commentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment handles DELETE /api/v1/itineraries/:id/comments/:commentId
+//deletes a comment together with its replies
+func (cc *CommentController) DeleteComment(c *gin.Context) {
+	if err := cc.comments.DeleteComment(c.Param("id"), c.Param("commentId")); err != nil {
+		c.JSON(commentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Comment deleted successfully",
+	})
+}
+
+//commentErrorStatus maps comment errors to HTTP status codes
+func commentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrItineraryNotFound), errors.Is(err, service.ErrCommentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidVisibility):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+//acts as a handler for HTTP Requests on the visiting order of a day's activities
+type DayRouteController struct {
+	service *service.ItineraryService
+	routes  *service.DayRouteService
+}
+
+//NewDayRouteController creates and returns a new DayRouteController instance
+func NewDayRouteController(s *service.ItineraryService, routes *service.DayRouteService) *DayRouteController {
+	return &DayRouteController{
+		service: s,
+		routes:  routes,
+	}
+}
+
+// ProposeDayRoute handles GET /api/v1/itineraries/:id/days/:day/route
+//proposes the shortest visiting order of a day without changing the itinerary
+func (dc *DayRouteController) ProposeDayRoute(c *gin.Context) {
+	day, err := strconv.Atoi(c.Param("day"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid day number",
+		})
+		return
+	}
+
+	proposal, err := dc.routes.Propose(c.Param("id"), day)
+	if err != nil {
+		c.JSON(dayRouteErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, proposal)
+}
+
+// ApplyDayRoute handles POST /api/v1/itineraries/:id/days/:day/route/apply
+//reorders the day's activities as proposed and saves the itinerary
+func (dc *DayRouteController) ApplyDayRoute(c *gin.Context) {
+	day, err := strconv.Atoi(c.Param("day"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid day number",
+		})
+		return
+	}
+
+	itinerary, proposal, err := dc.routes.Apply(c.Param("id"), day)
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		c.JSON(dayRouteErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"route":     proposal,
+		"itinerary": withWarnings(dc.service, itinerary),
+	})
+}
+
+//dayRouteErrorStatus maps day route errors to HTTP status codes
+func dayRouteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrItineraryNotFound), errors.Is(err, service.ErrDayNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrTooManyActivities):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrItineraryLocked):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"example/vigovia-itenary-api/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//acts as a handler for HTTP Requests on the flight journey of an itinerary
+type JourneyController struct {
+	service  *service.ItineraryService
+	journeys *service.JourneyService
+}
+
+//NewJourneyController creates and returns a new JourneyController instance
+func NewJourneyController(s *service.ItineraryService, journeys *service.JourneyService) *JourneyController {
+	return &JourneyController{
+		service:  s,
+		journeys: journeys,
+	}
+}
+
+// GetJourney handles GET /api/v1/itineraries/:id/journey
+//chains the flights into journeys with their connections, layovers and travel time
+func (jc *JourneyController) GetJourney(c *gin.Context) {
+	itinerary, err := jc.service.GetItinerary(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Itinerary not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, jc.journeys.Analyze(itinerary))
+}
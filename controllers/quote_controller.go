@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//acts as a handler for HTTP Requests on customer quote responses and change requests
+type QuoteController struct {
+	service    *service.ItineraryService
+	quotes     *service.QuoteService
+	shareLinks *service.ShareLinkService
+}
+
+//NewQuoteController creates and returns a new QuoteController instance
+func NewQuoteController(s *service.ItineraryService, quotes *service.QuoteService, shareLinks *service.ShareLinkService) *QuoteController {
+	return &QuoteController{
+		service:    s,
+		quotes:     quotes,
+		shareLinks: shareLinks,
+	}
+}
+
+// CreateQuoteLink handles POST /api/v1/itineraries/:id/quote-link
+//issues the link the customer answers the current quote with, to be sent to them by the agent
+func (qc *QuoteController) CreateQuoteLink(c *gin.Context) {
+	id := c.Param("id")
+	revision, err := qc.quotes.NextRevision(id)
+	if err != nil {
+		c.JSON(quoteErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	link, expiresAt := qc.shareLinks.QuoteURL(id, revision)
+	c.JSON(http.StatusCreated, models.QuoteLink{
+		URL:       link,
+		Revision:  revision,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// RespondToQuote handles POST /api/v1/share/:id/quote
+//accepts, rejects or requests changes to a quote behind a quote link
+func (qc *QuoteController) RespondToQuote(c *gin.Context) {
+	id := c.Param("id")
+	revision, err := qc.shareLinks.VerifyQuote(id, c.Query("rev"), c.Query("exp"), c.Query("token"))
+	if err != nil {
+		c.JSON(shareErrorStatus(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	var req models.QuoteResponseReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := qc.quotes.Respond(id, revision, &req)
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		c.JSON(quoteErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":           response.ID,
+		"itinerary_id": response.ItineraryID,
+		"number":       response.Number,
+		"decision":     response.Decision,
+		"responded_by": response.RespondedBy,
+		"responded_at": response.RespondedAt,
+		"comments":     response.Comments,
+		"itinerary":    qc.service.CustomerView(&response.Itinerary),
+	})
+}
+
+// GetRevisions handles GET /api/v1/itineraries/:id/revisions
+//lists the customer responses to the quotes of an itinerary
+func (qc *QuoteController) GetRevisions(c *gin.Context) {
+	revisions, err := qc.quotes.Revisions(c.Param("id"))
+	if err != nil {
+		c.JSON(quoteErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+// GetChangeRequests handles GET /api/v1/change-requests
+//lists the change requests still waiting for a revised quote, oldest first
+func (qc *QuoteController) GetChangeRequests(c *gin.Context) {
+	queue, err := qc.quotes.PendingChangeRequests()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve change requests",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, queue)
+}
+
+//quoteErrorStatus maps quote response errors to HTTP status codes
+func quoteErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrItineraryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidDecision):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrQuoteLinkUsed):
+		return http.StatusGone
+	case errors.Is(err, service.ErrNotQuoted), errors.Is(err, service.ErrInvalidTransition):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
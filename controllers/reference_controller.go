@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"example/vigovia-itenary-api/reference"
+	"example/vigovia-itenary-api/service"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// autocomplete result limits
+const (
+	defaultLookupLimit = 10
+	maxLookupLimit     = 50
+)
+
+//acts as a handler for HTTP Requests on the airport, airline and currency reference data
+type ReferenceController struct {
+	catalog    *reference.Catalog
+	currencies *service.CurrencyService
+}
+
+//NewReferenceController creates and returns a new ReferenceController instance
+func NewReferenceController(catalog *reference.Catalog, currencies *service.CurrencyService) *ReferenceController {
+	return &ReferenceController{
+		catalog:    catalog,
+		currencies: currencies,
+	}
+}
+
+// SearchAirports handles GET /api/v1/reference/airports?q=
+//autocomplete on airport code, city or name
+func (rc *ReferenceController) SearchAirports(c *gin.Context) {
+	query, limit, ok := lookupParams(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, rc.catalog.SearchAirports(query, limit))
+}
+
+// GetAirport handles GET /api/v1/reference/airports/:code
+//looks up a single airport by IATA or ICAO code
+func (rc *ReferenceController) GetAirport(c *gin.Context) {
+	airport, ok := rc.catalog.Airport(c.Param("code"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "airport not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, airport)
+}
+
+// SearchAirlines handles GET /api/v1/reference/airlines?q=
+//autocomplete on airline designator or name
+func (rc *ReferenceController) SearchAirlines(c *gin.Context) {
+	query, limit, ok := lookupParams(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, rc.catalog.SearchAirlines(query, limit))
+}
+
+// GetCurrencies handles GET /api/v1/reference/currencies
+//lists the ISO 4217 currencies with the default currency and the exchange rate table
+func (rc *ReferenceController) GetCurrencies(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"default_currency": rc.currencies.DefaultCurrency(),
+		"exchange_rates":   rc.currencies.Rates(),
+		"currencies":       rc.catalog.Currencies(),
+	})
+}
+
+// lookupParams reads the search text and result limit of an autocomplete request
+func lookupParams(c *gin.Context) (string, int, bool) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "query parameter q is required"})
+		return "", 0, false
+	}
+
+	limit := defaultLookupLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive number"})
+			return "", 0, false
+		}
+		limit = n
+	}
+	if limit > maxLookupLimit {
+		limit = maxLookupLimit
+	}
+
+	return query, limit, true
+}
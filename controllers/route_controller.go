@@ -1,10 +1,13 @@
 package controllers
 
 import (
+	"errors"
 	"example/vigovia-itenary-api/models"
 	"example/vigovia-itenary-api/service"
 	"fmt"
 	"net/http"
+	"path"
+	"strconv"
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,13 +15,21 @@ import (
 type RouteController struct {
 	service *service.ItineraryService
 	pdfService *service.PDFService
+	shareLinks *service.ShareLinkService
+}
+
+//itineraryResponse is an itinerary with the validation warnings raised on save
+type itineraryResponse struct {
+	*models.Itinerary
+	Warnings []service.ValidationIssue `json:"warnings,omitempty"`
 }
 
 //NewRouteController acts as a constructor for RouteController and creates and returns a new RouteController Instance
-func NewRouteController(s *service.ItineraryService, pdfSvc *service.PDFService ) *RouteController {
+func NewRouteController(s *service.ItineraryService, pdfSvc *service.PDFService, shareLinks *service.ShareLinkService) *RouteController {
 	return &RouteController{
 		service: s,
 		pdfService: pdfSvc,
+		shareLinks: shareLinks,
 	}
 }
 
@@ -32,10 +43,25 @@ func (rc *RouteController) CreateItinerary(c *gin.Context){
 	}
 	itinerary,err:=rc.service.CreateItinerary(&req)
 	if err!=nil{
+		if respondValidationError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusCreated, itinerary)
+	c.JSON(http.StatusCreated, withWarnings(rc.service, itinerary))
+}
+
+// ValidateItinerary handles POST /api/itineraries/validate
+//dry run that returns the full validation report without saving anything
+func (rc *RouteController) ValidateItinerary(c *gin.Context) {
+	var req models.CreateItineraryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rc.service.ValidateItinerary(&req))
 }
 
 // GetItinerary handles GET /api/itineraries/:id
@@ -55,6 +81,94 @@ func (rc *RouteController) GetItinerary(c *gin.Context) {
 	c.JSON(http.StatusOK, itinerary)
 }
 
+// GetCoverage handles GET /api/itineraries/:id/coverage
+//reports uncovered nights, double bookings and hotel city mismatches
+func (rc *RouteController) GetCoverage(c *gin.Context) {
+	report, err := rc.service.GetCoverage(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Itinerary not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetTimeline handles GET /api/itineraries/:id/timeline
+//places every day's activities on the clock, estimating the ones without a start time
+func (rc *RouteController) GetTimeline(c *gin.Context) {
+	timeline, err := rc.service.GetTimeline(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Itinerary not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, timeline)
+}
+
+// GetFeasibility handles GET /api/itineraries/:id/feasibility
+//estimates the travel time between consecutive activities of every day
+func (rc *RouteController) GetFeasibility(c *gin.Context) {
+	report, err := rc.service.GetFeasibility(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Itinerary not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GeocodeItinerary handles POST /api/itineraries/:id/geocode
+//looks up the missing coordinates with the online geocoder and saves them
+func (rc *RouteController) GeocodeItinerary(c *gin.Context) {
+	itinerary, err := rc.service.GeocodeItinerary(c.Param("id"))
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		statusCode := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, service.ErrItineraryNotFound):
+			statusCode = http.StatusNotFound
+		case errors.Is(err, service.ErrNoRemoteGeocoder):
+			statusCode = http.StatusNotImplemented
+		case errors.Is(err, service.ErrItineraryLocked):
+			statusCode = http.StatusConflict
+		}
+
+		c.JSON(statusCode, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, withWarnings(rc.service, itinerary))
+}
+
+// GetPricing handles GET /api/itineraries/:id/pricing
+//itemises the price of every priced hotel, flight, transfer and activity
+func (rc *RouteController) GetPricing(c *gin.Context) {
+	breakdown, err := rc.service.GetPricing(c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusBadRequest
+		if errors.Is(err, service.ErrItineraryNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
 // GetAllItineraries handles GET /api/itineraries
 //gets all the itineraries
 func (h *RouteController) GetAllItineraries(c *gin.Context) {
@@ -99,9 +213,14 @@ func (rc *RouteController) UpdateItinerary(c *gin.Context) {
 	//calls UpdateItinerary from service to update the itinerary
 	itinerary, err := rc.service.UpdateItinerary(id, &req)
 	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
 		statusCode := http.StatusBadRequest
-		if err.Error() == "itinerary not found" {
+		if errors.Is(err, service.ErrItineraryNotFound) {
 			statusCode = http.StatusNotFound
+		} else if errors.Is(err, service.ErrItineraryLocked) {
+			statusCode = http.StatusConflict
 		}
 
 		c.JSON(statusCode, gin.H{
@@ -110,7 +229,178 @@ func (rc *RouteController) UpdateItinerary(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, itinerary)
+	c.JSON(http.StatusOK, withWarnings(rc.service, itinerary))
+}
+
+// GetStatus handles GET /api/itineraries/:id/status
+//returns the current status, the statuses it can move to and its history
+func (rc *RouteController) GetStatus(c *gin.Context) {
+	itinerary, err := rc.service.GetItinerary(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Itinerary not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":              itinerary.Status,
+		"allowed_transitions": service.AllowedTransitions(itinerary.Status),
+		"history":             itinerary.StatusHistory,
+	})
+}
+
+// TransitionStatus handles POST /api/itineraries/:id/status
+//moves the itinerary to a new status, recording who changed it and why
+func (rc *RouteController) TransitionStatus(c *gin.Context) {
+	var req models.StatusTransitionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	itinerary, err := rc.service.TransitionStatus(c.Param("id"), &req)
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		c.JSON(statusErrorStatus(err), gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, withWarnings(rc.service, itinerary))
+}
+
+// RescheduleItinerary handles POST /api/itineraries/:id/reschedule
+//moves every date of the itinerary by an offset or to a new start date
+func (rc *RouteController) RescheduleItinerary(c *gin.Context) {
+	var req models.RescheduleReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request payload",
+		})
+		return
+	}
+
+	itinerary, err := rc.service.Reschedule(c.Param("id"), &req)
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		statusCode := http.StatusBadRequest
+		if errors.Is(err, service.ErrItineraryNotFound) {
+			statusCode = http.StatusNotFound
+		} else if errors.Is(err, service.ErrItineraryLocked) {
+			statusCode = http.StatusConflict
+		}
+
+		c.JSON(statusCode, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, withWarnings(rc.service, itinerary))
+}
+
+// CloneItinerary handles POST /api/itineraries/:id/clone
+//copies the itinerary into a new draft, optionally for another user or start date
+func (rc *RouteController) CloneItinerary(c *gin.Context) {
+	var req models.CloneItineraryReq
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request payload",
+			})
+			return
+		}
+	}
+
+	itinerary, err := rc.service.CloneItinerary(c.Param("id"), &req)
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrItineraryNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, withWarnings(rc.service, itinerary))
+}
+
+// MergeItineraries handles POST /api/itineraries/:id/merge
+//combines the itinerary with another one into a new draft
+func (rc *RouteController) MergeItineraries(c *gin.Context) {
+	var req models.MergeItinerariesReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request payload",
+		})
+		return
+	}
+
+	itinerary, err := rc.service.MergeItineraries(c.Param("id"), &req)
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		statusCode := http.StatusBadRequest
+		if errors.Is(err, service.ErrItineraryNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, withWarnings(rc.service, itinerary))
+}
+
+// SplitItinerary handles POST /api/itineraries/:id/split
+//splits the itinerary at a day into two new drafts
+func (rc *RouteController) SplitItinerary(c *gin.Context) {
+	var req models.SplitItineraryReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request payload",
+		})
+		return
+	}
+
+	parts, err := rc.service.SplitItinerary(c.Param("id"), &req)
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		statusCode := http.StatusBadRequest
+		if errors.Is(err, service.ErrItineraryNotFound) {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	responses := make([]itineraryResponse, 0, len(parts))
+	for _, part := range parts {
+		responses = append(responses, withWarnings(rc.service, part))
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"itineraries": responses,
+	})
 }
 
 // DeleteItinerary handles DELETE /api/itineraries/:id
@@ -121,7 +411,7 @@ func (rc *RouteController) DeleteItinerary(c *gin.Context) {
 	//calls DeleteItinerary from service
 	if err := rc.service.DeleteItinerary(id); err != nil {
 		statusCode := http.StatusInternalServerError
-		if err.Error() == "itinerary not found" {
+		if errors.Is(err, service.ErrItineraryNotFound) {
 			statusCode = http.StatusNotFound
 		}
 
@@ -137,9 +427,21 @@ func (rc *RouteController) DeleteItinerary(c *gin.Context) {
 }
 
 
+// GeneratePDF handles POST /api/itineraries/:id/pdf
+//accepts optional PDF options (theme, protection) in the request body
 func (rc *RouteController) GeneratePDF(c *gin.Context) {
 	id := c.Param("id")
 
+	opts := rc.pdfOptions(c)
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid request payload",
+			})
+			return
+		}
+	}
+
 	//calls GetItinerary to fetch the itinerary
 	itinerary, err := rc.service.GetItinerary(id)
 	if itinerary==nil {
@@ -152,10 +454,10 @@ func (rc *RouteController) GeneratePDF(c *gin.Context) {
 		return
 	}
 
-	// calls GeneratePDF from pdfService to generate pdf
-	filepath, err := rc.pdfService.GeneratePDF(itinerary)
+	// calls GeneratePDFWithOptions from pdfService to generate pdf
+	filepath, err := rc.pdfService.GeneratePDFWithOptions(itinerary, opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(pdfErrorStatus(err), gin.H{
 			"error":err.Error(),
 		})
 		return
@@ -183,10 +485,10 @@ func (rc *RouteController) DownloadPDF(c *gin.Context) {
 		return
 	}
 
-	// calls GeneratePDF from pdfService to generate pdf
-	filepath, err := rc.pdfService.GeneratePDF(itinerary)
+	// calls GeneratePDFWithOptions from pdfService to generate pdf
+	filepath, err := rc.pdfService.GeneratePDFWithOptions(itinerary, rc.pdfOptions(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(pdfErrorStatus(err), gin.H{
 			"error":err.Error(),
 		})
 		return
@@ -195,3 +497,191 @@ func (rc *RouteController) DownloadPDF(c *gin.Context) {
 	// Serve PDF file 
 	c.FileAttachment(filepath, filepath[len(filepath)-48:])
 }
+
+// DownloadHotelVoucher handles GET /api/itineraries/:id/vouchers/hotels/:index
+//serves the voucher for a single hotel booking, index is 1-based
+func (rc *RouteController) DownloadHotelVoucher(c *gin.Context) {
+	rc.downloadVoucher(c, rc.pdfService.GenerateHotelVoucher)
+}
+
+// DownloadTransferVoucher handles GET /api/itineraries/:id/vouchers/transfers/:index
+//serves the voucher for a single transfer, index is 1-based
+func (rc *RouteController) DownloadTransferVoucher(c *gin.Context) {
+	rc.downloadVoucher(c, rc.pdfService.GenerateTransferVoucher)
+}
+
+// DownloadVouchers handles GET /api/itineraries/:id/vouchers
+//serves a ZIP archive with every hotel and transfer voucher of the itinerary
+func (rc *RouteController) DownloadVouchers(c *gin.Context) {
+	itinerary, err := rc.service.GetItinerary(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":err.Error(),
+		})
+		return
+	}
+
+	archive, err := rc.pdfService.GenerateVoucherBundle(itinerary, rc.pdfOptions(c))
+	if err != nil {
+		c.JSON(pdfErrorStatus(err), gin.H{
+			"error":err.Error(),
+		})
+		return
+	}
+
+	c.FileAttachment(archive, path.Base(archive))
+}
+
+//downloadVoucher loads the itinerary and serves a single voucher generated by render
+func (rc *RouteController) downloadVoucher(c *gin.Context, render func(*models.Itinerary, int, service.PDFOptions) (string, error)) {
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid voucher index",
+		})
+		return
+	}
+
+	itinerary, err := rc.service.GetItinerary(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":err.Error(),
+		})
+		return
+	}
+
+	filepath, err := render(itinerary, index, rc.pdfOptions(c))
+	if err != nil {
+		c.JSON(pdfErrorStatus(err), gin.H{
+			"error":err.Error(),
+		})
+		return
+	}
+
+	c.FileAttachment(filepath, path.Base(filepath))
+}
+
+// GetSharedItinerary handles GET /api/v1/share/:id
+//returns the live itinerary for a signed share link
+func (rc *RouteController) GetSharedItinerary(c *gin.Context) {
+	id := c.Param("id")
+	itinerary, ok := rc.sharedItinerary(c, rc.shareLinks.Verify(id, c.Query("exp"), c.Query("sig")))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, itinerary)
+}
+
+// GetSharedFlight handles GET /api/v1/share/:id/flights/:flightNumber
+//returns the latest details of a single flight for a signed share link
+func (rc *RouteController) GetSharedFlight(c *gin.Context) {
+	id := c.Param("id")
+	flightNumber := c.Param("flightNumber")
+	ref := c.Query("ref")
+	itinerary, ok := rc.sharedItinerary(c, rc.shareLinks.VerifyFlight(id, flightNumber, ref, c.Query("exp"), c.Query("sig")))
+	if !ok {
+		return
+	}
+
+	for _, flight := range itinerary.Flights {
+		if flight.FlightNumber == flightNumber && (ref == "" || flight.BookingReference == ref) {
+			c.JSON(http.StatusOK, gin.H{
+				"itinerary_id": itinerary.ID,
+				"title":        itinerary.Title,
+				"status":       itinerary.Status,
+				"updated_at":   itinerary.UpdatedAt,
+				"flight":       flight,
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": "Flight not found",
+	})
+}
+
+//sharedItinerary checks the result of verifying a share link and loads the customer view of the itinerary
+func (rc *RouteController) sharedItinerary(c *gin.Context, verifyErr error) (*models.CustomerItinerary, bool) {
+	if verifyErr != nil {
+		c.JSON(shareErrorStatus(verifyErr), gin.H{
+			"error": verifyErr.Error(),
+		})
+		return nil, false
+	}
+
+	itinerary, err := rc.service.GetItinerary(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Itinerary not found",
+		})
+		return nil, false
+	}
+
+	return rc.service.CustomerView(itinerary), true
+}
+
+//shareErrorStatus maps share link errors to HTTP status codes
+func shareErrorStatus(err error) int {
+	if errors.Is(err, service.ErrShareLinkExpired) {
+		return http.StatusGone
+	}
+	return http.StatusForbidden
+}
+
+//withWarnings attaches the validation warnings of a saved itinerary to the response
+func withWarnings(s *service.ItineraryService, itinerary *models.Itinerary) itineraryResponse {
+	return itineraryResponse{
+		Itinerary: itinerary,
+		Warnings:  s.Validate(itinerary).Warnings,
+	}
+}
+
+//respondValidationError writes every rule violation when err is a validation error
+func respondValidationError(c *gin.Context, err error) bool {
+	var validationErr *service.ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "validation_error",
+		"message": "Invalid itinerary",
+		"details": validationErr.Issues,
+		"warnings": validationErr.Warnings,
+	})
+	return true
+}
+
+//pdfOptions reads the per request PDF options from the query string
+func (rc *RouteController) pdfOptions(c *gin.Context) service.PDFOptions {
+	return service.PDFOptions{
+		Theme: c.Query("theme"),
+	}
+}
+
+//statusErrorStatus maps status transition errors to HTTP status codes
+func statusErrorStatus(err error) int {
+	if errors.Is(err, service.ErrItineraryNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, service.ErrInvalidTransition) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, service.ErrInvalidStatus) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+//pdfErrorStatus maps PDF generation errors to HTTP status codes
+func pdfErrorStatus(err error) int {
+	if errors.Is(err, service.ErrUnknownTheme) || errors.Is(err, service.ErrPasswordRequired) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, service.ErrVoucherNotFound) || errors.Is(err, service.ErrNoVouchers) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
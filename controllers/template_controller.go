@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+//acts as a handler for HTTP Requests on the itinerary template library
+type TemplateController struct {
+	service   *service.ItineraryService
+	templates *service.TemplateService
+}
+
+//NewTemplateController creates and returns a new TemplateController instance
+func NewTemplateController(s *service.ItineraryService, templates *service.TemplateService) *TemplateController {
+	return &TemplateController{
+		service:   s,
+		templates: templates,
+	}
+}
+
+// CreateTemplate handles POST /api/v1/templates
+//stores a reusable package without customer, dates or payment
+func (tc *TemplateController) CreateTemplate(c *gin.Context) {
+	var template models.Template
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	created, err := tc.templates.CreateTemplate(&template)
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// SearchTemplates handles GET /api/v1/templates
+//lists the templates, filtered by destination and number of nights
+func (tc *TemplateController) SearchTemplates(c *gin.Context) {
+	search := service.TemplateSearch{Destination: c.Query("destination")}
+	for param, value := range map[string]*int{
+		"nights":     &search.Nights,
+		"min_nights": &search.MinNights,
+		"max_nights": &search.MaxNights,
+	} {
+		if c.Query(param) == "" {
+			continue
+		}
+		n, err := strconv.Atoi(c.Query(param))
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid " + param,
+			})
+			return
+		}
+		*value = n
+	}
+
+	templates, err := tc.templates.SearchTemplates(search)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve templates",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// GetTemplate handles GET /api/v1/templates/:id
+func (tc *TemplateController) GetTemplate(c *gin.Context) {
+	template, err := tc.templates.GetTemplate(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Template not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate handles DELETE /api/v1/templates/:id
+func (tc *TemplateController) DeleteTemplate(c *gin.Context) {
+	if err := tc.templates.DeleteTemplate(c.Param("id")); err != nil {
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrTemplateNotFound) {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Template deleted successfully",
+	})
+}
+
+// InstantiateTemplate handles POST /api/v1/templates/:id/instantiate
+//builds a full itinerary from the template for a customer and start date
+func (tc *TemplateController) InstantiateTemplate(c *gin.Context) {
+	var req models.InstantiateTemplateReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	itinerary, err := tc.templates.Instantiate(c.Param("id"), &req)
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrTemplateNotFound) {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, withWarnings(tc.service, itinerary))
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//acts as a handler for HTTP Requests on airport transfers aligned with flights
+type TransferController struct {
+	service   *service.ItineraryService
+	transfers *service.TransferService
+}
+
+//NewTransferController creates and returns a new TransferController instance
+func NewTransferController(s *service.ItineraryService, transfers *service.TransferService) *TransferController {
+	return &TransferController{
+		service:   s,
+		transfers: transfers,
+	}
+}
+
+// SuggestTransfers handles GET /api/v1/itineraries/:id/transfers/suggestions
+//proposes the missing airport transfers and reports conflicts with the linked flights
+func (tc *TransferController) SuggestTransfers(c *gin.Context) {
+	plan, err := tc.transfers.Suggest(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Itinerary not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// GenerateTransfers handles POST /api/v1/itineraries/:id/transfers/generate
+//adds the suggested transfers to the itinerary and saves it
+func (tc *TransferController) GenerateTransfers(c *gin.Context) {
+	itinerary, err := tc.transfers.Generate(c.Param("id"))
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+		statusCode := http.StatusInternalServerError
+		if errors.Is(err, service.ErrItineraryNotFound) {
+			statusCode = http.StatusNotFound
+		} else if errors.Is(err, service.ErrItineraryLocked) {
+			statusCode = http.StatusConflict
+		}
+		c.JSON(statusCode, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, withWarnings(tc.service, itinerary))
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"log"
+	_ "time/tzdata" // embedded IANA time zone database
 )
 
 func main(){
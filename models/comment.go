@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// comment visibilities
+const (
+	VisibilityInternal = "internal"
+	VisibilityCustomer = "customer"
+)
+
+// ElementRef points at one part of an itinerary: a day, an activity (its ID,
+// or day, slot and index in the slot), a hotel (its ID, or index in hotels),
+// a flight (flight number) or an installment (installment number). An empty
+// reference points at the whole itinerary. Activities and hotels are kept by
+// ID, so the reference follows them when they move; the day, slot and
+// indexes are where they are now.
+type ElementRef struct {
+	Day         int    `json:"day,omitempty"`
+	Slot        string `json:"slot,omitempty"`
+	Activity    *int   `json:"activity,omitempty"`
+	ActivityID  string `json:"activity_id,omitempty"`
+	Hotel       *int   `json:"hotel,omitempty"`
+	HotelID     string `json:"hotel_id,omitempty"`
+	Flight      string `json:"flight,omitempty"`
+	Installment int    `json:"installment,omitempty"`
+}
+
+// Comment is a note left on an itinerary or one of its elements. Replies
+// point at their parent and share its element. Internal notes are only seen
+// by agents; customer notes are also printed in the PDF.
+type Comment struct {
+	ID          string `json:"id"`
+	ItineraryID string `json:"itinerary_id"`
+	ParentID    string `json:"parent_id,omitempty"`
+	ElementRef
+	Author     string    `json:"author"`
+	Text       string    `json:"text"`
+	Visibility string    `json:"visibility"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// the element was removed from the itinerary
+	Detached bool `json:"detached,omitempty"`
+}
+
+// CommentThread is a comment with its replies, oldest first
+type CommentThread struct {
+	Comment
+	Replies []CommentThread `json:"replies"`
+}
+
+type CreateCommentReq struct {
+	ParentID string `json:"parent_id"`
+	ElementRef
+	Author     string `json:"author" binding:"required"`
+	Text       string `json:"text" binding:"required"`
+	Visibility string `json:"visibility"`
+}
+
+type UpdateCommentReq struct {
+	Text       *string `json:"text"`
+	Visibility *string `json:"visibility"`
+}
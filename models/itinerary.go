@@ -3,13 +3,26 @@ package models
 import (
 	"time"
 	"github.com/go-playground/validator/v10"
+	"github.com/shopspring/decimal"
 )
 
 var validate=validator.New()
 
+// itinerary document statuses
+const (
+	StatusDraft      = "draft"
+	StatusQuoted     = "quoted"
+	StatusConfirmed  = "confirmed"
+	StatusInProgress = "in_progress"
+	StatusCompleted  = "completed"
+	StatusCancelled  = "cancelled"
+	StatusExpired    = "expired"
+)
+
 type Itinerary struct {
 	ID		string   `json:"id" binding:"required" gorm:"primary_key;"`
-	UserID  string   `json:"user_id" binding:"required"`
+	UserID  string   `json:"user_id,omitempty" binding:"required"`
+	CustomerName string `json:"customer_name"`
 	Title   string      `json:"title" binding:"required"`
 	Destination string  `json:"destination" binding:"required"`
 	StartDate time.Time `json:"start_date" binding:"required" validate:"datetime=2006-01-02"`
@@ -21,6 +34,11 @@ type Itinerary struct {
 	PaymentPlan PaymentPlan `json:"payment_plan" binding:"required" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 	Inclusions []string	`json:"inclusions" binding:"required" gorm:"type:text[]"`
 	Exclusions []string	`json:"exclusions" binding:"required" gorm:"type:text[]"`
+	Travellers []Traveller `json:"travellers" binding:"dive"`
+	Status     string     `json:"status"`
+	ConfirmationNumber string `json:"confirmation_number,omitempty"`
+	ConfirmedAt *time.Time `json:"confirmed_at,omitempty"`
+	StatusHistory []StatusChange `json:"status_history,omitempty"`
 	CreatedAt time.Time   `json:"created_at"`
 	UpdatedAt time.Time   `json:"updated_at"`
 }
@@ -30,6 +48,7 @@ type Day struct {
 	DayNumber   int       `json:"day_number" binding:"required" min:"1" gorm:"uniqueIndex:idx_itinerary_daynumber"`
 	Date		time.Time `json:"date" binding:"required" validate:"datetime=2006-01-02"`
 	Title	   string    `json:"title" binding:"required"`
+	TimeZone    string    `json:"time_zone,omitempty"`
 	Activities  Activities `json:"activities"`
 }
 
@@ -40,20 +59,38 @@ type Activities struct {
 }
 
 type Activity struct{
+	ID          string `json:"id,omitempty"`
 	Name		string `json:"name" binding:"required"`
 	Description string `json:"description" binding:"required"`
 	Location	string `json:"location" binding:"required"`
 	Duration	string `json:"duration"`
+	DurationMinutes int `json:"duration_minutes,omitempty"`
+	StartTime   *TimeOfDay `json:"start_time,omitempty"`
+	EndTime     *TimeOfDay `json:"end_time,omitempty"`
+	Coordinates *Coordinates `json:"coordinates,omitempty"`
+	TravelMode  string     `json:"travel_mode,omitempty"`
+	Price       *Price     `json:"price,omitempty"`
+}
+
+type Coordinates struct {
+	Latitude  float64 `json:"latitude" binding:"min=-90,max=90"`
+	Longitude float64 `json:"longitude" binding:"min=-180,max=180"`
 }
 
 type Hotel struct {
 	// ItineraryID	   string `json:"itinerary_id" gorm:"foreignKey:ItineraryID"`
+	ID          string    `json:"id,omitempty"`
 	Name		string    `json:"name" binding:"required"`
 	City 	 string    `json:"city" binding:"required"`
 	CheckInDate  time.Time `json:"check_in_date" binding:"required" validate:"datetime=2006-01-02"`
 	CheckOutDate time.Time `json:"check_out_date" binding:"required" validate:"datetime=2006-01-02"`
 	Nights		int       `json:"nights" binding:"required" min:"1"`
 	Address		string    `json:"address" binding:"required"`
+	TimeZone    string    `json:"time_zone,omitempty"`
+	BookingReference string `json:"booking_reference"`
+	Coordinates *Coordinates `json:"coordinates,omitempty"`
+	Rooms       []RoomAllocation `json:"rooms,omitempty" binding:"dive"`
+	Price       *Price      `json:"price,omitempty"`
 }
 
 type Flight struct {
@@ -64,6 +101,11 @@ type Flight struct {
 	To   string   `json:"to" binding:"required"`
 	Departure time.Time `json:"departure" binding:"required" validate:"datetime=2006-01-02"`
 	Arrival   time.Time `json:"arrival" binding:"required" validate:"datetime=2006-01-02"`
+	DepartureTimeZone string `json:"departure_time_zone,omitempty"`
+	ArrivalTimeZone   string `json:"arrival_time_zone,omitempty"`
+	BookingReference string `json:"booking_reference"`
+	Passengers  []FlightPassenger `json:"passengers,omitempty" binding:"dive"`
+	Price       *Price      `json:"price,omitempty"`
 }
 
 type Transfer struct {
@@ -72,39 +114,48 @@ type Transfer struct {
 	To     string    `json:"to" binding:"required"`
 	Mode   string    `json:"mode" binding:"required"`
 	Timing   time.Time `json:"time" binding:"required"`
+	BookingReference string `json:"booking_reference"`
+	FlightNumber string `json:"flight_number,omitempty"`
+	Price       *Price      `json:"price,omitempty"`
 }
 
 type PaymentPlan struct {
 	// ItineraryID	  string `json:"itinerary_id" gorm:"foreignKey:ItineraryID"`
-	AmountDue   float64   `json:"amount_due" binding:"required"`
+	AmountDue   Money     `json:"amount_due"`
 	DueDate     time.Time `json:"due_date" binding:"required"`
 	Installments []Installment `json:"installments" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// exchange rates the amounts were set with, in the form of EXCHANGE_RATES
+	ExchangeRates map[string]decimal.Decimal `json:"exchange_rates,omitempty"`
 }
 
 type Installment struct {
 	// ItineraryID	   string `json:"itinerary_id" gorm:"foreignKey:ItineraryID"`
 	InstallmentNumber int       `json:"installment_number" binding:"required" min:"1"`
-	Amount			float64   `json:"amount" binding:"required"`
+	Amount			Money     `json:"amount"`
 	DueDate		time.Time `json:"due_date" binding:"required"`
 	Status		string    `json:"status" binding:"required"`
 }
 
 type CreateItineraryReq struct {
 	UserID     string   `json:"user_id" binding:"required"`
+	CustomerName string    `json:"customer_name"`
 	Title      string      `json:"title" binding:"required"`
 	Destination string     `json:"destination" binding:"required"`
 	StartDate  time.Time   `json:"start_date" binding:"required"`
 	EndDate    time.Time   `json:"end_date" binding:"required"`
-	Days       []Day	    `json:"days" binding:"required,dive"`
+	Days       []Day	    `json:"days" binding:"dive"`
+	GenerateDays bool      `json:"generate_days"`
 	Hotels     []Hotel     `json:"hotels" binding:"dive"`
 	Flights    []Flight	`json:"flights" binding:"dive"`
 	Transfers  []Transfer   `json:"transfers" binding:"dive"`
 	PaymentPlan PaymentPlan `json:"payment_plan" binding:"required"`
 	Inclusions  []string	`json:"inclusions"`
 	Exclusions  []string	`json:"exclusions"`
+	Travellers  []Traveller `json:"travellers" binding:"dive"`
 }
 
 type UpdateItineraryReq struct {
+	CustomerName *string    `json:"customer_name"`
 	Title      *string      `json:"title"`
 	Destination *string     `json:"destination"`
 	StartDate  *time.Time   `json:"start_date"`
@@ -116,4 +167,54 @@ type UpdateItineraryReq struct {
 	PaymentPlan *PaymentPlan `json:"payment_plan"`
 	Inclusions  []string	`json:"inclusions"`
 	Exclusions  []string	`json:"exclusions"`
+	Travellers  []Traveller `json:"travellers" binding:"dive"`
+	Status      *string     `json:"status"`
+}
+
+// StatusChange records a move of an itinerary from one status to another
+type StatusChange struct {
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	ChangedBy string    `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+// CustomerItinerary is an itinerary as shown to its customer. The elements
+// carry no price; Prices lists what the customer pays for each priced
+// element instead, so net costs and markups stay with the agency.
+type CustomerItinerary struct {
+	Itinerary
+	Prices []CustomerPrice `json:"prices,omitempty"`
+}
+
+type StatusTransitionReq struct {
+	Status    string `json:"status" binding:"required"`
+	ChangedBy string `json:"changed_by" binding:"required"`
+	Reason    string `json:"reason"`
+}
+
+type RescheduleReq struct {
+	OffsetDays   *int       `json:"offset_days"`
+	NewStartDate *time.Time `json:"new_start_date"`
+	KeepInstallmentDates bool `json:"keep_installment_dates"`
+}
+
+// day conflict resolutions when merging itineraries
+const (
+	MergeCombine    = "combine"
+	MergeKeepFirst  = "keep_first"
+	MergeKeepSecond = "keep_second"
+)
+
+type MergeItinerariesReq struct {
+	OtherID     string  `json:"other_id" binding:"required"`
+	DayConflict string  `json:"day_conflict"`
+	Title       *string `json:"title"`
+	UserID      string  `json:"user_id"`
+}
+
+type SplitItineraryReq struct {
+	DayNumber   int      `json:"day_number" binding:"required"`
+	FirstAmount *Money   `json:"first_amount"`
 }
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"example/vigovia-itenary-api/reference"
+
+	"github.com/shopspring/decimal"
+)
+
+// Money is an exact decimal amount in an ISO 4217 currency. It is written
+// as {"amount": "1234.50", "currency": "EUR"}. A bare number or numeric
+// string is read as an amount without a currency, which takes the currency
+// of the payment plan it belongs to.
+type Money struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency,omitempty"`
+}
+
+// NewMoney returns an amount in a currency
+func NewMoney(amount decimal.Decimal, currency string) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// IsZero tells whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount.IsZero()
+}
+
+// Equal tells whether two amounts are the same amount in the same currency
+func (m Money) Equal(other Money) bool {
+	return m.Currency == other.Currency && m.Amount.Equal(other.Amount)
+}
+
+// MarshalJSON writes the amount with the minor units of its currency, e.g.
+// "1234.50" in EUR. Amounts with more decimals than their currency, and
+// amounts in an unknown currency, are written as they are.
+func (m Money) MarshalJSON() ([]byte, error) {
+	amount := m.Amount.String()
+	if currency, ok := reference.Default().Currency(m.Currency); ok {
+		units := int32(currency.MinorUnits)
+		if m.Amount.Equal(m.Amount.Round(units)) {
+			amount = m.Amount.StringFixed(units)
+		}
+	}
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency,omitempty"`
+	}{amount, m.Currency})
+}
+
+// UnmarshalJSON reads either the object form or a bare amount
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		type plain Money
+		var decoded plain
+		if err := json.Unmarshal(trimmed, &decoded); err != nil {
+			return err
+		}
+		*m = Money(decoded)
+		return nil
+	}
+
+	var amount decimal.Decimal
+	if err := amount.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	*m = Money{Amount: amount}
+	return nil
+}
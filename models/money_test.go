@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMoneyMarshalJSON(t *testing.T) {
+	tests := []struct {
+		amount Money
+		want   string
+	}{
+		{NewMoney(decimal.RequireFromString("1234.5"), "EUR"), `{"amount":"1234.50","currency":"EUR"}`},
+		{NewMoney(decimal.NewFromInt(1500), "JPY"), `{"amount":"1500","currency":"JPY"}`},
+		{NewMoney(decimal.RequireFromString("2.5"), "KWD"), `{"amount":"2.500","currency":"KWD"}`},
+		// more decimals than the currency has, or an unknown currency, are written as they are
+		{NewMoney(decimal.RequireFromString("10.005"), "EUR"), `{"amount":"10.005","currency":"EUR"}`},
+		{NewMoney(decimal.RequireFromString("7.5"), "ABC"), `{"amount":"7.5","currency":"ABC"}`},
+		{NewMoney(decimal.RequireFromString("7.5"), ""), `{"amount":"7.5"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			got, err := json.Marshal(test.amount)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    Money
+		wantErr bool
+	}{
+		{`{"amount":"1234.50","currency":"EUR"}`, NewMoney(decimal.RequireFromString("1234.5"), "EUR"), false},
+		{`{"amount":99.9,"currency":"USD"}`, NewMoney(decimal.RequireFromString("99.9"), "USD"), false},
+		{`1500`, NewMoney(decimal.NewFromInt(1500), ""), false},
+		{`"1500.25"`, NewMoney(decimal.RequireFromString("1500.25"), ""), false},
+		{`"lots"`, Money{}, true},
+		{`{"amount":"lots"}`, Money{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.data, func(t *testing.T) {
+			var got Money
+			err := json.Unmarshal([]byte(test.data), &got)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if err == nil && !got.Equal(test.want) {
+				t.Errorf("got %s %s, want %s %s", got.Amount, got.Currency, test.want.Amount, test.want.Currency)
+			}
+		})
+	}
+}
+
+func TestMoneyRoundTrip(t *testing.T) {
+	original := NewMoney(decimal.RequireFromString("0.1"), "INR")
+	encoded, err := json.Marshal(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Money
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(original) {
+		t.Errorf("got %s %s back from %s", decoded.Amount, decoded.Currency, encoded)
+	}
+}
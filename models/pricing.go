@@ -0,0 +1,59 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// Price is the optional price of a hotel stay, flight, transfer or activity,
+// in the currency the supplier charges. The net cost is the fixed cost plus
+// the per-adult and per-child rates times the number of adults and children;
+// the markup and taxes are added on top as fixed amounts, percentages, or
+// both. Taxes are charged on the net cost plus markup. Without explicit
+// counts the travellers of the itinerary are counted.
+type Price struct {
+	Currency      string          `json:"currency,omitempty"`
+	NetCost       decimal.Decimal `json:"net_cost"`
+	AdultRate     decimal.Decimal `json:"adult_rate"`
+	ChildRate     decimal.Decimal `json:"child_rate"`
+	Adults        *int            `json:"adults,omitempty"`
+	Children      *int            `json:"children,omitempty"`
+	Markup        decimal.Decimal `json:"markup"`
+	MarkupPercent decimal.Decimal `json:"markup_percent"`
+	Taxes         decimal.Decimal `json:"taxes"`
+	TaxPercent    decimal.Decimal `json:"tax_percent"`
+}
+
+// PriceLine is the priced cost of one element of an itinerary. Net, Markup,
+// Taxes and Total are in the currency of the element's price; CustomerTotal
+// is the total converted into the currency of the payment plan.
+type PriceLine struct {
+	Element       string `json:"element"`
+	Description   string `json:"description"`
+	Adults        int    `json:"adults"`
+	Children      int    `json:"children"`
+	Net           Money  `json:"net"`
+	Markup        Money  `json:"markup"`
+	Taxes         Money  `json:"taxes"`
+	Total         Money  `json:"total"`
+	CustomerTotal Money  `json:"customer_total"`
+}
+
+// CustomerPrice is what the customer pays for one priced element, in the
+// currency of the payment plan. Element is the path of the element in the
+// itinerary, as in PriceLine.
+type CustomerPrice struct {
+	Element       string `json:"element"`
+	Description   string `json:"description"`
+	CustomerTotal Money  `json:"customer_total"`
+}
+
+// PriceBreakdown itemises the price of an itinerary. The totals are in the
+// currency of the payment plan. Derived is set when the amount due comes
+// from the price components rather than being typed in.
+type PriceBreakdown struct {
+	Lines     []PriceLine `json:"lines"`
+	Net       Money       `json:"net"`
+	Markup    Money       `json:"markup"`
+	Taxes     Money       `json:"taxes"`
+	Total     Money       `json:"total"`
+	AmountDue Money       `json:"amount_due"`
+	Derived   bool        `json:"derived"`
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// customer decisions on a quoted itinerary
+const (
+	DecisionAccept         = "accept"
+	DecisionReject         = "reject"
+	DecisionRequestChanges = "request_changes"
+)
+
+// QuoteRevision records one customer response to a quote together with the
+// itinerary exactly as it was quoted. Revisions are numbered per itinerary.
+type QuoteRevision struct {
+	ID          string         `json:"id"`
+	ItineraryID string         `json:"itinerary_id"`
+	Number      int            `json:"number"`
+	Decision    string         `json:"decision"`
+	RespondedBy string         `json:"responded_by"`
+	RespondedAt time.Time      `json:"responded_at"`
+	Comments    []QuoteComment `json:"comments"`
+	Itinerary   Itinerary      `json:"itinerary"`
+}
+
+// QuoteComment is a customer comment on the whole quote or on one element of it
+type QuoteComment struct {
+	ElementRef
+	Text string `json:"text" binding:"required"`
+}
+
+type QuoteResponseReq struct {
+	Decision    string         `json:"decision" binding:"required"`
+	RespondedBy string         `json:"responded_by" binding:"required"`
+	Comments    []QuoteComment `json:"comments" binding:"dive"`
+}
+
+// QuoteLink is the link a customer answers a quote with
+type QuoteLink struct {
+	URL       string    `json:"url"`
+	Revision  int       `json:"revision"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ChangeRequest is a request for changes still waiting for the agent to send
+// a revised quote
+type ChangeRequest struct {
+	ItineraryID  string         `json:"itinerary_id"`
+	Title        string         `json:"title"`
+	CustomerName string         `json:"customer_name"`
+	UserID       string         `json:"user_id"`
+	Revision     int            `json:"revision"`
+	RespondedBy  string         `json:"responded_by"`
+	RespondedAt  time.Time      `json:"responded_at"`
+	Comments     []QuoteComment `json:"comments"`
+}
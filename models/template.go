@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// Template is a reusable package without a customer, dates or payment plan.
+// Hotels, flights and transfers refer to days by number, so the same template
+// can be instantiated for any start date.
+type Template struct {
+	ID          string             `json:"id"`
+	Title       string             `json:"title" binding:"required"`
+	Destination string             `json:"destination" binding:"required"`
+	Description string             `json:"description"`
+	Nights      int                `json:"nights"`
+	Days        []TemplateDay      `json:"days" binding:"required,min=1,dive"`
+	Hotels      []TemplateHotel    `json:"hotels" binding:"dive"`
+	Flights     []TemplateFlight   `json:"flights" binding:"dive"`
+	Transfers   []TemplateTransfer `json:"transfers" binding:"dive"`
+	Inclusions  []string           `json:"inclusions"`
+	Exclusions  []string           `json:"exclusions"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+}
+
+// TemplateDay is a day of a template, without a date
+type TemplateDay struct {
+	DayNumber  int        `json:"day_number" binding:"required,min=1"`
+	Title      string     `json:"title" binding:"required"`
+	TimeZone   string     `json:"time_zone,omitempty"`
+	Activities Activities `json:"activities"`
+}
+
+// TemplateHotel is a stay from the check-in day to the check-out day
+type TemplateHotel struct {
+	Name        string       `json:"name" binding:"required"`
+	City        string       `json:"city" binding:"required"`
+	Address     string       `json:"address" binding:"required"`
+	CheckInDay  int          `json:"check_in_day" binding:"required,min=1"`
+	CheckOutDay int          `json:"check_out_day" binding:"required,min=1"`
+	TimeZone    string       `json:"time_zone,omitempty"`
+	Coordinates *Coordinates `json:"coordinates,omitempty"`
+}
+
+// TemplateFlight is a flight on given days at local times. Time zones default
+// to those of the airports.
+type TemplateFlight struct {
+	FlightNumber      string    `json:"flight_number" binding:"required"`
+	Airline           string    `json:"airline" binding:"required"`
+	From              string    `json:"from" binding:"required"`
+	To                string    `json:"to" binding:"required"`
+	DepartureDay      int       `json:"departure_day" binding:"required,min=1"`
+	DepartureTime     TimeOfDay `json:"departure_time"`
+	ArrivalDay        int       `json:"arrival_day" binding:"required,min=1"`
+	ArrivalTime       TimeOfDay `json:"arrival_time"`
+	DepartureTimeZone string    `json:"departure_time_zone,omitempty"`
+	ArrivalTimeZone   string    `json:"arrival_time_zone,omitempty"`
+}
+
+// TemplateTransfer is a transfer on a given day at a local time of that day
+type TemplateTransfer struct {
+	From         string    `json:"from" binding:"required"`
+	To           string    `json:"to" binding:"required"`
+	Mode         string    `json:"mode" binding:"required"`
+	Day          int       `json:"day" binding:"required,min=1"`
+	Time         TimeOfDay `json:"time"`
+	FlightNumber string    `json:"flight_number,omitempty"`
+}
+
+type InstantiateTemplateReq struct {
+	UserID       string      `json:"user_id" binding:"required"`
+	CustomerName string      `json:"customer_name"`
+	StartDate    time.Time   `json:"start_date" binding:"required"`
+	PaymentPlan  PaymentPlan `json:"payment_plan" binding:"required"`
+}
+
+type CloneItineraryReq struct {
+	UserID       string     `json:"user_id"`
+	CustomerName *string    `json:"customer_name"`
+	StartDate    *time.Time `json:"start_date"`
+}
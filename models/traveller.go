@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Traveller is a person on the trip. Flights and hotels refer to travellers
+// by ID; an ID is generated when none is given.
+type Traveller struct {
+	ID               string            `json:"id"`
+	Name             string            `json:"name" binding:"required"`
+	DateOfBirth      *time.Time        `json:"date_of_birth,omitempty"`
+	Nationality      string            `json:"nationality,omitempty"`
+	PassportNumber   string            `json:"passport_number,omitempty"`
+	PassportExpiry   *time.Time        `json:"passport_expiry,omitempty"`
+	DietaryNeeds     []string          `json:"dietary_needs,omitempty"`
+	EmergencyContact *EmergencyContact `json:"emergency_contact,omitempty"`
+}
+
+type EmergencyContact struct {
+	Name         string `json:"name" binding:"required"`
+	Phone        string `json:"phone" binding:"required"`
+	Relationship string `json:"relationship,omitempty"`
+}
+
+// FlightPassenger assigns a traveller a seat and ticket on a flight
+type FlightPassenger struct {
+	TravellerID  string `json:"traveller_id" binding:"required"`
+	Seat         string `json:"seat,omitempty"`
+	TicketNumber string `json:"ticket_number,omitempty"`
+}
+
+// RoomAllocation puts travellers in a room of a hotel stay
+type RoomAllocation struct {
+	RoomType     string   `json:"room_type,omitempty"`
+	RoomNumber   string   `json:"room_number,omitempty"`
+	TravellerIDs []string `json:"traveller_ids" binding:"required,min=1"`
+}
@@ -0,0 +1,379 @@
+// Package reference provides an offline dataset of airports, airlines,
+// places and currencies used to validate flights, resolve codes into display
+// names, geocode locations and round amounts without a network connection
+package reference
+
+import (
+	"embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed data/*.csv
+var data embed.FS
+
+// Airport is a single airport of the reference dataset
+type Airport struct {
+	IATA      string  `json:"iata"`
+	ICAO      string  `json:"icao"`
+	Name      string  `json:"name"`
+	City      string  `json:"city"`
+	Country   string  `json:"country"`
+	TimeZone  string  `json:"time_zone"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// DisplayName returns the airport for display, e.g. "Charles de Gaulle Airport (CDG), Paris"
+func (a Airport) DisplayName() string {
+	return fmt.Sprintf("%s (%s), %s", a.Name, a.IATA, a.City)
+}
+
+// Airline is a single airline of the reference dataset
+type Airline struct {
+	IATA    string `json:"iata"`
+	ICAO    string `json:"icao"`
+	Name    string `json:"name"`
+	Country string `json:"country"`
+}
+
+// Place is a city centre or landmark of the offline gazetteer
+type Place struct {
+	Name      string  `json:"name"`
+	Kind      string  `json:"kind"`
+	City      string  `json:"city"`
+	Country   string  `json:"country"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+
+	pattern *regexp.Regexp
+}
+
+// Currency is an ISO 4217 currency with the number of digits after the
+// decimal separator its amounts are rounded to
+type Currency struct {
+	Code       string `json:"code"`
+	Number     string `json:"number"`
+	Name       string `json:"name"`
+	MinorUnits int    `json:"minor_units"`
+}
+
+// place kinds
+const (
+	PlaceCity     = "city"
+	PlaceLandmark = "landmark"
+)
+
+// Catalog indexes the airports, airlines and currencies by code along with
+// the places
+type Catalog struct {
+	airports       []Airport
+	airlines       []Airline
+	places         []Place
+	currencies     []Currency
+	airportByCode  map[string]int
+	airlineByCode  map[string]int
+	airlineByName  map[string]int
+	currencyByCode map[string]int
+}
+
+var (
+	defaultCatalog *Catalog
+	loadOnce       sync.Once
+)
+
+// Default returns the catalog built from the embedded dataset
+func Default() *Catalog {
+	loadOnce.Do(func() {
+		catalog, err := load()
+		if err != nil {
+			// the dataset is compiled in, failing to parse it is a programming error
+			panic(err)
+		}
+		defaultCatalog = catalog
+	})
+	return defaultCatalog
+}
+
+// load parses the embedded CSV files
+func load() (*Catalog, error) {
+	c := &Catalog{
+		airportByCode:  make(map[string]int),
+		airlineByCode:  make(map[string]int),
+		airlineByName:  make(map[string]int),
+		currencyByCode: make(map[string]int),
+	}
+
+	err := readCSV("data/airports.csv", func(record []string) error {
+		lat, err := strconv.ParseFloat(record[6], 64)
+		if err != nil {
+			return err
+		}
+		lon, err := strconv.ParseFloat(record[7], 64)
+		if err != nil {
+			return err
+		}
+		c.airports = append(c.airports, Airport{
+			IATA: record[0], ICAO: record[1], Name: record[2], City: record[3],
+			Country: record[4], TimeZone: record[5], Latitude: lat, Longitude: lon,
+		})
+		i := len(c.airports) - 1
+		c.airportByCode[record[0]] = i
+		c.airportByCode[record[1]] = i
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readCSV("data/airlines.csv", func(record []string) error {
+		c.airlines = append(c.airlines, Airline{IATA: record[0], ICAO: record[1], Name: record[2], Country: record[3]})
+		i := len(c.airlines) - 1
+		c.airlineByCode[record[0]] = i
+		c.airlineByCode[record[1]] = i
+		c.airlineByName[strings.ToLower(record[2])] = i
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readCSV("data/places.csv", func(record []string) error {
+		lat, err := strconv.ParseFloat(record[4], 64)
+		if err != nil {
+			return err
+		}
+		lon, err := strconv.ParseFloat(record[5], 64)
+		if err != nil {
+			return err
+		}
+		// landmarks match in any case, city names only when capitalised so
+		// "a nice dinner" is not taken for Nice
+		flags := "(?i)"
+		if record[1] == PlaceCity {
+			flags = ""
+		}
+		c.places = append(c.places, Place{
+			Name: record[0], Kind: record[1], City: record[2], Country: record[3], Latitude: lat, Longitude: lon,
+			pattern: regexp.MustCompile(flags + `\b` + regexp.QuoteMeta(record[0]) + `\b`),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readCSV("data/currencies.csv", func(record []string) error {
+		minorUnits, err := strconv.Atoi(record[3])
+		if err != nil {
+			return err
+		}
+		c.currencies = append(c.currencies, Currency{Code: record[0], Number: record[1], Name: record[2], MinorUnits: minorUnits})
+		c.currencyByCode[record[0]] = len(c.currencies) - 1
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// readCSV calls fn for every record of an embedded CSV file, skipping the header
+func readCSV(name string, fn func(record []string) error) error {
+	file, err := data.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	if _, err := r.Read(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := fn(record); err != nil {
+			return fmt.Errorf("%s line %d: %w", name, line, err)
+		}
+	}
+}
+
+// codeInText matches a code given in parentheses, e.g. "Paris (CDG)"
+var codeInText = regexp.MustCompile(`\(([A-Za-z]{3,4})\)`)
+
+// Airport resolves an airport from an IATA or ICAO code, a text containing a
+// code in parentheses such as "Paris (CDG)", or the airport's exact name
+func (c *Catalog) Airport(text string) (Airport, bool) {
+	text = strings.TrimSpace(text)
+	if i, ok := c.airportByCode[strings.ToUpper(text)]; ok {
+		return c.airports[i], true
+	}
+	if m := codeInText.FindStringSubmatch(text); m != nil {
+		if i, ok := c.airportByCode[strings.ToUpper(m[1])]; ok {
+			return c.airports[i], true
+		}
+	}
+	for _, airport := range c.airports {
+		if strings.EqualFold(airport.Name, text) {
+			return airport, true
+		}
+	}
+	return Airport{}, false
+}
+
+// CityTimeZone returns the time zone of a city served by one of the
+// airports, e.g. "Europe/Paris" for "Paris"
+func (c *Catalog) CityTimeZone(city string) (string, bool) {
+	city = strings.TrimSpace(city)
+	for _, airport := range c.airports {
+		if strings.EqualFold(airport.City, city) {
+			return airport.TimeZone, true
+		}
+	}
+	return "", false
+}
+
+// Place finds the landmark or city named in a free text location, e.g.
+// "Eiffel Tower, Paris" resolves to the Eiffel Tower. The longest name found
+// wins so a landmark is preferred over the city it is in.
+func (c *Catalog) Place(text string) (Place, bool) {
+	best := -1
+	for i, place := range c.places {
+		if place.pattern.MatchString(text) && (best < 0 || len(place.Name) > len(c.places[best].Name)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return Place{}, false
+	}
+	return c.places[best], true
+}
+
+// Airline resolves an airline from its IATA designator, ICAO code or name
+func (c *Catalog) Airline(text string) (Airline, bool) {
+	text = strings.TrimSpace(text)
+	if i, ok := c.airlineByCode[strings.ToUpper(text)]; ok {
+		return c.airlines[i], true
+	}
+	if i, ok := c.airlineByName[strings.ToLower(text)]; ok {
+		return c.airlines[i], true
+	}
+	return Airline{}, false
+}
+
+// Currency resolves an ISO 4217 currency from its alphabetic code
+func (c *Catalog) Currency(code string) (Currency, bool) {
+	if i, ok := c.currencyByCode[strings.ToUpper(strings.TrimSpace(code))]; ok {
+		return c.currencies[i], true
+	}
+	return Currency{}, false
+}
+
+// Currencies lists every currency ordered by code
+func (c *Catalog) Currencies() []Currency {
+	return append([]Currency{}, c.currencies...)
+}
+
+// flightNumberPattern is a two character designator (letters or one digit and
+// one letter) followed by a 1 to 4 digit number and an optional suffix
+var flightNumberPattern = regexp.MustCompile(`^([A-Z][A-Z0-9]|[0-9][A-Z])\s?([0-9]{1,4}[A-Z]?)$`)
+
+// ParseFlightNumber splits a flight number such as "AF1234" or "6E 512" into
+// the airline designator and the number
+func ParseFlightNumber(flightNumber string) (designator, number string, ok bool) {
+	m := flightNumberPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(flightNumber)))
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], m[2], true
+}
+
+// match ranks how well a query matches a set of fields, lower is better and
+// -1 means no match
+func match(query string, codes []string, names []string) int {
+	for _, code := range codes {
+		if strings.EqualFold(code, query) {
+			return 0
+		}
+	}
+	q := strings.ToLower(query)
+	for _, name := range names {
+		if strings.HasPrefix(strings.ToLower(name), q) {
+			return 1
+		}
+	}
+	for _, name := range names {
+		if strings.Contains(strings.ToLower(name), q) {
+			return 2
+		}
+	}
+	return -1
+}
+
+// SearchAirports returns up to limit airports matching the query on code,
+// city or name. Exact code matches come first, then prefix and substring matches.
+func (c *Catalog) SearchAirports(query string, limit int) []Airport {
+	query = strings.TrimSpace(query)
+	type ranked struct {
+		rank    int
+		airport Airport
+	}
+
+	var found []ranked
+	for _, airport := range c.airports {
+		rank := match(query, []string{airport.IATA, airport.ICAO}, []string{airport.City, airport.Name})
+		if rank >= 0 {
+			found = append(found, ranked{rank, airport})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].rank < found[j].rank })
+
+	results := []Airport{}
+	for _, r := range found {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, r.airport)
+	}
+	return results
+}
+
+// SearchAirlines returns up to limit airlines matching the query on code or name
+func (c *Catalog) SearchAirlines(query string, limit int) []Airline {
+	query = strings.TrimSpace(query)
+	type ranked struct {
+		rank    int
+		airline Airline
+	}
+
+	var found []ranked
+	for _, airline := range c.airlines {
+		rank := match(query, []string{airline.IATA, airline.ICAO}, []string{airline.Name})
+		if rank >= 0 {
+			found = append(found, ranked{rank, airline})
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].rank < found[j].rank })
+
+	results := []Airline{}
+	for _, r := range found {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, r.airline)
+	}
+	return results
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrCommentNotFound = errors.New("comment not found")
+)
+
+type CommentRepository interface {
+	Create(comment *models.Comment) error
+	GetByID(id string) (*models.Comment, error)
+	GetByItineraryID(itineraryID string) ([]*models.Comment, error)
+	Update(id string, comment *models.Comment) error
+	Delete(id string) error
+}
+
+//implements the CommentRepository using an in-memory map, safe for concurrent use
+type InMemoryCommentRepo struct {
+	mu       sync.RWMutex
+	comments map[string]*models.Comment
+}
+
+//creates and returns a new instance of InMemoryCommentRepo
+func NewInMemoryCommentRepo() *InMemoryCommentRepo {
+	return &InMemoryCommentRepo{
+		comments: make(map[string]*models.Comment),
+	}
+}
+
+//adds a new comment to the in-memory db (map)
+func (r *InMemoryCommentRepo) Create(comment *models.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.comments[comment.ID]; exists {
+		return errors.New("comment already exists")
+	}
+	r.comments[comment.ID] = comment
+	return nil
+}
+
+//gets comment by ID
+func (r *InMemoryCommentRepo) GetByID(id string) (*models.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	comment, exists := r.comments[id]
+	if !exists {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+//gets the comments of an itinerary, oldest first
+func (r *InMemoryCommentRepo) GetByItineraryID(itineraryID string) ([]*models.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var comments []*models.Comment
+	for _, comment := range r.comments {
+		if comment.ItineraryID == itineraryID {
+			comments = append(comments, comment)
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].CreatedAt.Before(comments[j].CreatedAt)
+	})
+	return comments, nil
+}
+
+//updates an existing comment
+func (r *InMemoryCommentRepo) Update(id string, comment *models.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.comments[id]; !exists {
+		return ErrCommentNotFound
+	}
+	r.comments[id] = comment
+	return nil
+}
+
+//delete comment by ID
+func (r *InMemoryCommentRepo) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.comments[id]; !exists {
+		return ErrCommentNotFound
+	}
+	delete(r.comments, id)
+	return nil
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"errors"
 	"example/vigovia-itenary-api/models"
+	"sync"
 )
 
 var(
@@ -18,8 +19,9 @@ type ItineraryRepository interface {
 	Delete(id string) error
 }
 
-//implements the InMemoryRepo using an in-memory map
+//implements the InMemoryRepo using an in-memory map, safe for concurrent use
 type InMemoryRepo struct {
+	mu          sync.RWMutex
 	itineraries map[string]*models.Itinerary
 }
 
@@ -32,6 +34,8 @@ func NewInMemoryRepo() *InMemoryRepo {
 
 //adds a new itinerary to the in-memory db (map)
 func(r *InMemoryRepo) Create(itinerary *models.Itinerary) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if _,exists:=r.itineraries[itinerary.ID];exists{
 		return errors.New("itinerary already exists")
 	}
@@ -41,6 +45,8 @@ func(r *InMemoryRepo) Create(itinerary *models.Itinerary) error {
 
 //gets all the itineraries from the in-memory db
 func(r *InMemoryRepo) GetAll()([]*models.Itinerary,error){
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	itineraries:=make([]*models.Itinerary,0,len(r.itineraries))
 	for _,itinerary:=range r.itineraries{
 		itineraries=append(itineraries,itinerary)
@@ -50,15 +56,19 @@ func(r *InMemoryRepo) GetAll()([]*models.Itinerary,error){
 
 //gets itinerary by ID 
 func(r*InMemoryRepo) GetByID(id string)(*models.Itinerary,error){
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	itinerary,exists:=r.itineraries[id]
 	if(!exists){
-		return  nil, errors.New("itinerary not found")
+		return  nil, ErrNotFound
 	}
 	return itinerary,nil
 }
 
 //gets itineraries by UserID
 func(r *InMemoryRepo) GetByUserID(userID string)([]*models.Itinerary,error){
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	var userItineraries []*models.Itinerary
 	for _,itinerary:=range r.itineraries{
 		if itinerary.UserID==userID{
@@ -71,8 +81,10 @@ func(r *InMemoryRepo) GetByUserID(userID string)([]*models.Itinerary,error){
 
 //update itinerary by ID
 func(r *InMemoryRepo) Update(id string, itinerary *models.Itinerary) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if _,exists:=r.itineraries[id];!exists{
-		return errors.New("itinerary not found")
+		return ErrNotFound
 	}
 	r.itineraries[id]=itinerary
 	return nil
@@ -80,8 +92,10 @@ func(r *InMemoryRepo) Update(id string, itinerary *models.Itinerary) error {
 
 //delete itinerary by ID
 func(r *InMemoryRepo) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	if _,exists :=r.itineraries[id];!exists{
-		return errors.New("itinerary not found")
+		return ErrNotFound
 	}
 	delete(r.itineraries,id)
 	return nil
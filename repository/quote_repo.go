@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrRevisionExists = errors.New("revision already exists")
+)
+
+type QuoteRevisionRepository interface {
+	Create(revision *models.QuoteRevision) error
+	GetAll() ([]*models.QuoteRevision, error)
+	GetByItineraryID(itineraryID string) ([]*models.QuoteRevision, error)
+	Delete(id string) error
+}
+
+//implements the QuoteRevisionRepository using an in-memory map keyed by itinerary, safe for concurrent use
+type InMemoryQuoteRevisionRepo struct {
+	mu        sync.RWMutex
+	revisions map[string][]*models.QuoteRevision
+}
+
+//creates and returns a new instance of InMemoryQuoteRevisionRepo
+func NewInMemoryQuoteRevisionRepo() *InMemoryQuoteRevisionRepo {
+	return &InMemoryQuoteRevisionRepo{
+		revisions: make(map[string][]*models.QuoteRevision),
+	}
+}
+
+//adds a new revision after the existing revisions of its itinerary
+func (r *InMemoryQuoteRevisionRepo) Create(revision *models.QuoteRevision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.revisions[revision.ItineraryID] {
+		if existing.Number == revision.Number {
+			return ErrRevisionExists
+		}
+	}
+	r.revisions[revision.ItineraryID] = append(r.revisions[revision.ItineraryID], revision)
+	return nil
+}
+
+//gets all the revisions of every itinerary, oldest response first
+func (r *InMemoryQuoteRevisionRepo) GetAll() ([]*models.QuoteRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var revisions []*models.QuoteRevision
+	for _, list := range r.revisions {
+		revisions = append(revisions, list...)
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].RespondedAt.Before(revisions[j].RespondedAt)
+	})
+	return revisions, nil
+}
+
+//gets the revisions of an itinerary in order
+func (r *InMemoryQuoteRevisionRepo) GetByItineraryID(itineraryID string) ([]*models.QuoteRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]*models.QuoteRevision{}, r.revisions[itineraryID]...), nil
+}
+
+//delete revision by ID
+func (r *InMemoryQuoteRevisionRepo) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for itineraryID, list := range r.revisions {
+		for i, revision := range list {
+			if revision.ID == id {
+				r.revisions[itineraryID] = append(list[:i:i], list[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return errors.New("revision not found")
+}
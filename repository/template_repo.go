@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"sync"
+)
+
+var (
+	ErrTemplateNotFound = errors.New("template not found")
+)
+
+type TemplateRepository interface {
+	Create(template *models.Template) error
+	GetAll() ([]*models.Template, error)
+	GetByID(id string) (*models.Template, error)
+	Delete(id string) error
+}
+
+//implements the TemplateRepository using an in-memory map, safe for concurrent use
+type InMemoryTemplateRepo struct {
+	mu        sync.RWMutex
+	templates map[string]*models.Template
+}
+
+//creates and returns a new instance of InMemoryTemplateRepo
+func NewInMemoryTemplateRepo() *InMemoryTemplateRepo {
+	return &InMemoryTemplateRepo{
+		templates: make(map[string]*models.Template),
+	}
+}
+
+//adds a new template to the in-memory db (map)
+func (r *InMemoryTemplateRepo) Create(template *models.Template) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.templates[template.ID]; exists {
+		return errors.New("template already exists")
+	}
+	r.templates[template.ID] = template
+	return nil
+}
+
+//gets all the templates from the in-memory db
+func (r *InMemoryTemplateRepo) GetAll() ([]*models.Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	templates := make([]*models.Template, 0, len(r.templates))
+	for _, template := range r.templates {
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+//gets template by ID
+func (r *InMemoryTemplateRepo) GetByID(id string) (*models.Template, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	template, exists := r.templates[id]
+	if !exists {
+		return nil, ErrTemplateNotFound
+	}
+	return template, nil
+}
+
+//delete template by ID
+func (r *InMemoryTemplateRepo) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.templates[id]; !exists {
+		return ErrTemplateNotFound
+	}
+	delete(r.templates, id)
+	return nil
+}
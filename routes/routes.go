@@ -5,6 +5,9 @@ import(
 	"example/vigovia-itenary-api/service"
 	"example/vigovia-itenary-api/repository"
 	"example/vigovia-itenary-api/config"
+	"example/vigovia-itenary-api/reference"
+	"log"
+	"time"
 	"github.com/gin-gonic/gin"
 )
 
@@ -12,14 +15,105 @@ func SetupRoutes(router *gin.Engine , cfg *config.Config){
 	//initializes the in memory rep
 	repo:=repository.NewInMemoryRepo()
 	
-	//initializes and creates the itinerary service with repository
-	itiSvc:=service.NewItineraryService(repo)
+	//initializes the journey service chaining flights and checking connections
+	journeys:=service.NewJourneyService(time.Duration(cfg.MinConnectionMinutes)*time.Minute)
+
+	//initializes and creates the itinerary service with repository, connections are checked by the journey service
+	itiSvc:=service.NewItineraryService(repo,journeys)
+
+	//saves geocode with the offline gazetteer, the online provider is only asked on POST /:id/geocode
+	if cfg.GeocoderURL != "" {
+		itiSvc.SetRemoteGeocoder(service.NewCachingGeocoder(service.NewHTTPGeocoder(cfg.GeocoderURL, cfg.GeocoderUserAgent)))
+	}
+
+	//initializes the currency service rounding amounts and converting them with the local exchange rate table
+	rates,err:=service.ParseExchangeRates(cfg.ExchangeRates)
+	if err!=nil{
+		log.Fatalf("EXCHANGE_RATES: %v", err)
+	}
+	currencies:=service.NewCurrencyService(cfg.DefaultCurrency,rates)
+	itiSvc.SetCurrencies(currencies)
+
+	//initializes the transfer service aligning airport transfers with flights and hotels
+	transfers:=service.NewTransferService(itiSvc,journeys,service.TransferBuffers{
+		AfterArrival: time.Duration(cfg.TransferArrivalBufferMinutes)*time.Minute,
+		BeforeDeparture: time.Duration(cfg.TransferDepartureBufferMinutes)*time.Minute,
+	})
+	itiSvc.AddValidationRules(transfers.ValidationRule())
+
+	//initializes the share link service used for signed links to live itineraries
+	if cfg.ShareLinkSecret==""{
+		log.Printf("WARNING: SHARE_LINK_SECRET is not set, share links and the QR codes printed on PDFs stop working when the server restarts")
+	}
+	shareLinks,err:=service.NewShareLinkService(cfg.ShareBaseURL, cfg.ShareLinkSecret, time.Duration(cfg.ShareLinkTTLDays)*24*time.Hour)
+	if err!=nil{
+		log.Fatalf("SHARE_LINK_SECRET: %v", err)
+	}
+	shareLinks.SetQuoteLinkTTL(time.Duration(cfg.QuoteLinkTTLDays)*24*time.Hour)
+
+	//initializes the comment service on its own in memory repo, customer notes are printed in the pdf
+	comments:=service.NewCommentService(repository.NewInMemoryCommentRepo(),itiSvc)
+	itiSvc.OnDelete(comments.DeleteItineraryComments)
+
+	//loads the optional PDF themes, e.g. with translated watermarks
+	var themes []service.PDFTheme
+	if cfg.PDFThemesFile!=""{
+		themes,err=service.LoadThemes(cfg.PDFThemesFile)
+		if err!=nil{
+			log.Fatalf("PDF_THEMES_FILE: %v", err)
+		}
+	}
 
 	//initializes and creates the itinerary service using outputDir from Config
-	pdfService:=service.NewPDFService(cfg.OutputDir)
+	pdfService,err:=service.NewPDFService(cfg.OutputDir, service.PDFSettings{
+		DefaultTheme: cfg.PDFTheme,
+		Themes: themes,
+		PasswordRule: cfg.PDFPasswordRule,
+		OwnerPassword: cfg.PDFOwnerPassword,
+		ShareLinks: shareLinks,
+		Journeys: journeys,
+		Comments: comments,
+		Currencies: currencies,
+		Agency: service.AgencyContact{
+			Name: cfg.AgencyName,
+			Phone: cfg.AgencyPhone,
+			Email: cfg.AgencyEmail,
+		},
+	})
+	if err!=nil{
+		log.Fatalf("PDF_THEME: %v", err)
+	}
+
+	//initializes the route controller with itinerary service, pdfService and share links
+	rc:=controllers.NewRouteController(itiSvc,pdfService,shareLinks)
+
+	//initializes the batch service rendering many itineraries with bounded parallelism
+	batchService:=service.NewBatchService(itiSvc,pdfService,cfg.OutputDir,cfg.PDFBatchWorkers)
+	bc:=controllers.NewBatchController(batchService)
+
+	//initializes the journey controller for the connection and layover view
+	jc:=controllers.NewJourneyController(itiSvc,journeys)
 
-	//initializes the route controller with itinerary service and pdfService
-	rc:=controllers.NewRouteController(itiSvc,pdfService)
+	//initializes the transfer controller for suggested and generated transfers
+	tc:=controllers.NewTransferController(itiSvc,transfers)
+
+	//initializes the day route controller reordering activities around the hotel
+	drc:=controllers.NewDayRouteController(itiSvc,service.NewDayRouteService(itiSvc))
+
+	//initializes the template service and controller on their own in memory repo
+	templates:=service.NewTemplateService(repository.NewInMemoryTemplateRepo(),itiSvc)
+	tplc:=controllers.NewTemplateController(itiSvc,templates)
+
+	//initializes the quote service and controller recording customer responses as revisions
+	quotes:=service.NewQuoteService(repository.NewInMemoryQuoteRevisionRepo(),itiSvc)
+	itiSvc.OnDelete(quotes.DeleteItineraryRevisions)
+	qc:=controllers.NewQuoteController(itiSvc,quotes,shareLinks)
+
+	//initializes the comment controller for notes on itineraries and their elements
+	cc:=controllers.NewCommentController(comments)
+
+	//initializes the reference controller on the embedded airport and airline data
+	refc:=controllers.NewReferenceController(reference.Default(),currencies)
 
 	//sets up the api version group
 	v1:=router.Group("/api/v1")
@@ -28,12 +122,76 @@ func SetupRoutes(router *gin.Engine , cfg *config.Config){
 		itineraries:=v1.Group("/itineraries")
 		{
 			itineraries.POST("",rc.CreateItinerary) //create a new itinerary
+			itineraries.POST("/validate",rc.ValidateItinerary) //dry run validation without saving
 			itineraries.GET("",rc.GetAllItineraries) // get all the itineraries
 			itineraries.GET("/:id",rc.GetItinerary)  // get itinerary by id
 			itineraries.PUT("/:id",rc.UpdateItinerary) //update itinerary
 			itineraries.DELETE("/:id",rc.DeleteItinerary) //delete itinerary by id
+			itineraries.GET("/:id/status",rc.GetStatus) //current status, allowed transitions and history
+			itineraries.POST("/:id/status",rc.TransitionStatus) //move to another status in the workflow
+			itineraries.POST("/:id/quote-link",qc.CreateQuoteLink) //link the customer answers the current quote with
+			itineraries.GET("/:id/revisions",qc.GetRevisions) //customer responses to the quotes
+			itineraries.GET("/:id/comments",cc.GetComments) //comment threads, optionally by visibility
+			itineraries.POST("/:id/comments",cc.AddComment) //comment on the itinerary or one of its elements
+			itineraries.PUT("/:id/comments/:commentId",cc.UpdateComment) //change the text or visibility of a comment
+			itineraries.DELETE("/:id/comments/:commentId",cc.DeleteComment) //delete a comment and its replies
+			itineraries.POST("/:id/reschedule",rc.RescheduleItinerary) //move every date by an offset or to a new start date
+			itineraries.POST("/:id/clone",rc.CloneItinerary) //copy into a new draft for another user or date
+			itineraries.POST("/:id/merge",rc.MergeItineraries) //combine with another itinerary into a new draft
+			itineraries.POST("/:id/split",rc.SplitItinerary) //split at a day into two new drafts
+			itineraries.GET("/:id/coverage",rc.GetCoverage) //hotel coverage of every night
+			itineraries.GET("/:id/timeline",rc.GetTimeline) //activities of every day on the clock
+			itineraries.GET("/:id/feasibility",rc.GetFeasibility) //travel time between consecutive activities
+			itineraries.POST("/:id/geocode",rc.GeocodeItinerary) //fill in missing coordinates with the online geocoder
+			itineraries.GET("/:id/pricing",rc.GetPricing) //itemised price breakdown
+			itineraries.GET("/:id/journey",jc.GetJourney) //flights chained into journeys with connections
+			itineraries.GET("/:id/transfers/suggestions",tc.SuggestTransfers) //missing airport transfers and conflicts
+			itineraries.POST("/:id/transfers/generate",tc.GenerateTransfers) //add the suggested transfers to the itinerary
+			itineraries.GET("/:id/days/:day/route",drc.ProposeDayRoute) //shortest visiting order of a day's activities
+			itineraries.POST("/:id/days/:day/route/apply",drc.ApplyDayRoute) //reorder the day's activities as proposed
 			itineraries.POST("/:id/pdf", rc.GeneratePDF) //generate pdf for an itinerary by id
 			itineraries.GET("/:id/pdf/download", rc.DownloadPDF)  //downloading the pdf for the itinerary
+			itineraries.GET("/:id/vouchers", rc.DownloadVouchers) //zip of all hotel and transfer vouchers
+			itineraries.GET("/:id/vouchers/hotels/:index", rc.DownloadHotelVoucher) //voucher for a single hotel
+			itineraries.GET("/:id/vouchers/transfers/:index", rc.DownloadTransferVoucher) //voucher for a single transfer
+		}
+
+		//queue of change requests waiting for a revised quote
+		v1.GET("/change-requests",qc.GetChangeRequests)
+
+		//group for batch pdf generation
+		batches:=v1.Group("/pdf-batches")
+		{
+			batches.POST("",bc.StartBatch) //start rendering many itineraries
+			batches.GET("/:id",bc.GetBatch) //progress and per item errors
+			batches.GET("/:id/download",bc.DownloadBatch) //zip archive of the rendered pdfs
+		}
+
+		//group for reusable itinerary templates
+		tpl:=v1.Group("/templates")
+		{
+			tpl.POST("",tplc.CreateTemplate) //create a template
+			tpl.GET("",tplc.SearchTemplates) //search by destination and number of nights
+			tpl.GET("/:id",tplc.GetTemplate) //get template by id
+			tpl.DELETE("/:id",tplc.DeleteTemplate) //delete template by id
+			tpl.POST("/:id/instantiate",tplc.InstantiateTemplate) //build an itinerary for a user and start date
+		}
+
+		//group for airport and airline lookups
+		ref:=v1.Group("/reference")
+		{
+			ref.GET("/airports",refc.SearchAirports) //autocomplete airports by code, city or name
+			ref.GET("/airports/:code",refc.GetAirport) //single airport by IATA or ICAO code
+			ref.GET("/airlines",refc.SearchAirlines) //autocomplete airlines by designator or name
+			ref.GET("/currencies",refc.GetCurrencies) //ISO 4217 currencies with the configured exchange rates
+		}
+
+		//group for signed public share links
+		share:=v1.Group("/share")
+		{
+			share.GET("/:id",rc.GetSharedItinerary) //live itinerary behind a signed link
+			share.GET("/:id/flights/:flightNumber",rc.GetSharedFlight) //latest details of a single flight
+			share.POST("/:id/quote",qc.RespondToQuote) //accept, reject or request changes to a quote
 		}
 	}
 
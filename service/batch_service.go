@@ -0,0 +1,327 @@
+package service
+
+import (
+	"archive/zip"
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrBatchNotFound    = errors.New("batch job not found")
+	ErrBatchEmpty       = errors.New("no itineraries match the batch filter")
+	ErrBatchNotFinished = errors.New("batch job has not finished yet")
+)
+
+// batch job statuses
+const (
+	BatchStatusRunning   = "running"
+	BatchStatusCompleted = "completed"
+	BatchStatusFailed    = "failed"
+)
+
+// BatchJobRetention is how long a finished batch job and its archive are
+// kept before they are removed
+const BatchJobRetention = 24 * time.Hour
+
+// BatchRequest selects the itineraries to render in a batch. When IDs are
+// given the other filters are ignored.
+type BatchRequest struct {
+	IDs    []string `json:"ids"`
+	UserID string   `json:"user_id"`
+	Status string   `json:"status"`
+
+	// trips starting within the window, both ends inclusive
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+
+	Options PDFOptions `json:"options"`
+}
+
+// BatchItemError records why a single itinerary failed to render
+type BatchItemError struct {
+	ItineraryID string `json:"itinerary_id"`
+	Error       string `json:"error"`
+}
+
+// BatchJob reports the progress of a batch render
+type BatchJob struct {
+	ID          string           `json:"id"`
+	Status      string           `json:"status"`
+	Total       int              `json:"total"`
+	Completed   int              `json:"completed"`
+	Failed      int              `json:"failed"`
+	Errors      []BatchItemError `json:"errors"`
+	ArchivePath string           `json:"archive_path,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	FinishedAt  *time.Time       `json:"finished_at,omitempty"`
+}
+
+// BatchService renders many itineraries concurrently and archives the result
+type BatchService struct {
+	itineraries *ItineraryService
+	pdfService  *PDFService
+	outputDir   string
+	workers     int
+
+	mu   sync.RWMutex
+	jobs map[string]*BatchJob
+}
+
+// NewBatchService creates a new batch service rendering with at most workers
+// PDFs in parallel
+func NewBatchService(itineraries *ItineraryService, pdfService *PDFService, outputDir string, workers int) *BatchService {
+	if workers < 1 {
+		workers = 1
+	}
+
+	return &BatchService{
+		itineraries: itineraries,
+		pdfService:  pdfService,
+		outputDir:   outputDir,
+		workers:     workers,
+		jobs:        make(map[string]*BatchJob),
+	}
+}
+
+// StartBatch selects the matching itineraries and starts rendering them in
+// the background. The returned job can be polled for progress.
+func (s *BatchService) StartBatch(req *BatchRequest) (*BatchJob, error) {
+	itineraries, missing, err := s.selectItineraries(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(itineraries) == 0 && len(missing) == 0 {
+		return nil, ErrBatchEmpty
+	}
+
+	// render from copies, the stored itineraries keep changing while the
+	// workers read them
+	for i, itinerary := range itineraries {
+		itineraries[i] = copyItinerary(itinerary)
+	}
+
+	job := &BatchJob{
+		ID:        uuid.New().String(),
+		Status:    BatchStatusRunning,
+		Total:     len(itineraries) + len(missing),
+		Errors:    []BatchItemError{},
+		CreatedAt: time.Now(),
+	}
+	for _, id := range missing {
+		job.Failed++
+		job.Errors = append(job.Errors, BatchItemError{ItineraryID: id, Error: ErrItineraryNotFound.Error()})
+	}
+
+	s.mu.Lock()
+	s.expireJobs(job.CreatedAt)
+	s.jobs[job.ID] = job
+	s.mu.Unlock()
+
+	go s.run(job, itineraries, req.Options)
+
+	return s.GetBatch(job.ID)
+}
+
+// GetBatch returns a snapshot of the batch job progress
+func (s *BatchService) GetBatch(id string) (*BatchJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrBatchNotFound
+	}
+
+	snapshot := *job
+	snapshot.Errors = append([]BatchItemError{}, job.Errors...)
+	return &snapshot, nil
+}
+
+// expireJobs removes the jobs that finished more than BatchJobRetention
+// before now along with their archives. The caller holds the lock.
+func (s *BatchService) expireJobs(now time.Time) {
+	for id, job := range s.jobs {
+		if job.FinishedAt == nil || now.Sub(*job.FinishedAt) < BatchJobRetention {
+			continue
+		}
+		if job.ArchivePath != "" {
+			os.Remove(job.ArchivePath)
+		}
+		delete(s.jobs, id)
+	}
+}
+
+// ArchivePath returns the ZIP archive of a finished batch job
+func (s *BatchService) ArchivePath(id string) (string, error) {
+	job, err := s.GetBatch(id)
+	if err != nil {
+		return "", err
+	}
+	if job.Status == BatchStatusRunning {
+		return "", ErrBatchNotFinished
+	}
+	if job.ArchivePath == "" {
+		return "", fmt.Errorf("batch job %s produced no archive", id)
+	}
+	return job.ArchivePath, nil
+}
+
+// selectItineraries resolves the request into itineraries and unknown IDs
+func (s *BatchService) selectItineraries(req *BatchRequest) ([]*models.Itinerary, []string, error) {
+	if len(req.IDs) > 0 {
+		var found []*models.Itinerary
+		var missing []string
+		for _, id := range req.IDs {
+			itinerary, err := s.itineraries.GetItinerary(id)
+			if errors.Is(err, ErrItineraryNotFound) {
+				missing = append(missing, id)
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			found = append(found, itinerary)
+		}
+		return found, missing, nil
+	}
+
+	var candidates []*models.Itinerary
+	var err error
+	if req.UserID != "" {
+		candidates, err = s.itineraries.GetUserItineraries(req.UserID)
+	} else {
+		candidates, err = s.itineraries.GetAllItineraries()
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var matched []*models.Itinerary
+	for _, itinerary := range candidates {
+		if req.Status != "" && itinerary.Status != req.Status {
+			continue
+		}
+		if req.From != nil && itinerary.StartDate.Before(*req.From) {
+			continue
+		}
+		if req.To != nil && itinerary.StartDate.After(*req.To) {
+			continue
+		}
+		matched = append(matched, itinerary)
+	}
+	return matched, nil, nil
+}
+
+// renderResult is the outcome of rendering one itinerary
+type renderResult struct {
+	itineraryID string
+	pdf         []byte
+	err         error
+}
+
+// run renders the itineraries with bounded parallelism and streams the
+// results into a single ZIP archive
+func (s *BatchService) run(job *BatchJob, itineraries []*models.Itinerary, opts PDFOptions) {
+	results := make(chan renderResult)
+	queue := make(chan *models.Itinerary)
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for itinerary := range queue {
+				pdf, err := s.pdfService.RenderPDF(itinerary, opts)
+				results <- renderResult{itineraryID: itinerary.ID, pdf: pdf, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		for _, itinerary := range itineraries {
+			queue <- itinerary
+		}
+		close(queue)
+		wg.Wait()
+		close(results)
+	}()
+
+	archivePath, err := s.writeArchive(job, results)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = BatchStatusCompleted
+	if err != nil {
+		job.Status = BatchStatusFailed
+		job.Errors = append(job.Errors, BatchItemError{Error: err.Error()})
+		return
+	}
+	job.ArchivePath = archivePath
+}
+
+// writeArchive consumes render results, adds the successful ones to the
+// archive and records progress on the job
+func (s *BatchService) writeArchive(job *BatchJob, results <-chan renderResult) (string, error) {
+	// keep draining so the workers never block on a failed archive
+	defer func() {
+		for range results {
+		}
+	}()
+
+	if err := os.MkdirAll(s.outputDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory : %w", err)
+	}
+
+	path := filepath.Join(s.outputDir, fmt.Sprintf("batch_%s_%s.zip", job.ID, time.Now().Format("20060102_150405")))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create batch archive: %w", err)
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+	for result := range results {
+		if result.err == nil {
+			result.err = writeArchiveEntry(archive, fmt.Sprintf("itinerary_%s.pdf", result.itineraryID), result.pdf)
+		}
+
+		s.mu.Lock()
+		if result.err != nil {
+			job.Failed++
+			job.Errors = append(job.Errors, BatchItemError{ItineraryID: result.itineraryID, Error: result.err.Error()})
+		} else {
+			job.Completed++
+		}
+		s.mu.Unlock()
+	}
+
+	if err := archive.Close(); err != nil {
+		return "", fmt.Errorf("failed to finalise batch archive: %w", err)
+	}
+	return path, nil
+}
+
+// writeArchiveEntry stores an already rendered file in the archive
+func writeArchiveEntry(archive *zip.Writer, name string, content []byte) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", name, err)
+	}
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s to archive: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"archive/zip"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/repository"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// batchFixture holds an itinerary service with a few stored trips and a
+// batch service rendering them into a temporary directory
+type batchFixture struct {
+	itineraries *ItineraryService
+	batches     *BatchService
+	ids         []string
+}
+
+func newBatchFixture(t *testing.T, trips int) *batchFixture {
+	t.Helper()
+	itineraries := NewItineraryService(repository.NewInMemoryRepo(), nil)
+	pdfs, err := NewPDFService(t.TempDir(), PDFSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixture := &batchFixture{
+		itineraries: itineraries,
+		batches:     NewBatchService(itineraries, pdfs, t.TempDir(), 3),
+	}
+	start := time.Date(2025, time.September, 10, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < trips; i++ {
+		amount := models.NewMoney(decimal.NewFromInt(50000), "INR")
+		itinerary, err := itineraries.CreateItinerary(&models.CreateItineraryReq{
+			UserID:       fmt.Sprintf("agent-%d", i%2),
+			Title:        fmt.Sprintf("Kerala backwaters %d", i),
+			Destination:  "Kochi",
+			StartDate:    start.AddDate(0, 0, i),
+			EndDate:      start.AddDate(0, 0, i+2),
+			GenerateDays: true,
+			PaymentPlan: models.PaymentPlan{
+				AmountDue: amount,
+				DueDate:   start,
+				Installments: []models.Installment{
+					{InstallmentNumber: 1, Amount: amount, DueDate: start, Status: "Pending"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fixture.ids = append(fixture.ids, itinerary.ID)
+	}
+	return fixture
+}
+
+// waitForBatch polls a batch job until it finishes
+func (f *batchFixture) waitForBatch(t *testing.T, id string) *BatchJob {
+	t.Helper()
+	deadline := time.Now().Add(30 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := f.batches.GetBatch(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.Status != BatchStatusRunning {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("batch %s still running", id)
+	return nil
+}
+
+// archiveEntries lists the file names in a ZIP archive
+func archiveEntries(t *testing.T, path string) []string {
+	t.Helper()
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestBatchSelection(t *testing.T) {
+	fixture := newBatchFixture(t, 4)
+	from := time.Date(2025, time.September, 11, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	tests := []struct {
+		name       string
+		req        BatchRequest
+		wantTotal  int
+		wantFailed int
+		wantErr    error
+	}{
+		{"every itinerary", BatchRequest{}, 4, 0, nil},
+		{"one agent", BatchRequest{UserID: "agent-1"}, 2, 0, nil},
+		{"start date window", BatchRequest{From: &from, To: &to}, 2, 0, nil},
+		{"explicit ids with an unknown one", BatchRequest{IDs: []string{fixture.ids[0], "missing"}}, 2, 1, nil},
+		{"nothing matches", BatchRequest{Status: models.StatusConfirmed}, 0, 0, ErrBatchEmpty},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			job, err := fixture.batches.StartBatch(&test.req)
+			if err != test.wantErr {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			job = fixture.waitForBatch(t, job.ID)
+			if job.Total != test.wantTotal || job.Failed != test.wantFailed || job.Completed != test.wantTotal-test.wantFailed {
+				t.Errorf("got %d total, %d completed, %d failed", job.Total, job.Completed, job.Failed)
+			}
+			if got := len(archiveEntries(t, job.ArchivePath)); got != job.Completed {
+				t.Errorf("got %d files in the archive, want %d", got, job.Completed)
+			}
+		})
+	}
+}
+
+// TestBatchWithConcurrentEdits renders while the same itineraries are
+// updated and others are created and deleted; run it with -race
+func TestBatchWithConcurrentEdits(t *testing.T) {
+	fixture := newBatchFixture(t, 6)
+
+	job, err := fixture.batches.StartBatch(&BatchRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i, id := range fixture.ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			for n := 0; n < 5; n++ {
+				title := fmt.Sprintf("Kerala backwaters %d, revision %d", i, n)
+				if _, err := fixture.itineraries.UpdateItinerary(id, &models.UpdateItineraryReq{Title: &title}); err != nil {
+					t.Error(err)
+				}
+				if _, err := fixture.itineraries.GetAllItineraries(); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i, id)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		clone, err := fixture.itineraries.CloneItinerary(fixture.ids[0], &models.CloneItineraryReq{})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if err := fixture.itineraries.DeleteItinerary(clone.ID); err != nil {
+			t.Error(err)
+		}
+	}()
+	wg.Wait()
+
+	job = fixture.waitForBatch(t, job.ID)
+	if job.Status != BatchStatusCompleted || job.Completed != len(fixture.ids) {
+		t.Fatalf("got status %s with %d of %d rendered: %v", job.Status, job.Completed, len(fixture.ids), job.Errors)
+	}
+
+	var want []string
+	for _, id := range fixture.ids {
+		want = append(want, fmt.Sprintf("itinerary_%s.pdf", id))
+	}
+	sort.Strings(want)
+	if got := archiveEntries(t, job.ArchivePath); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
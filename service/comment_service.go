@@ -0,0 +1,421 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/repository"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCommentNotFound   = repository.ErrCommentNotFound
+	ErrInvalidVisibility = errors.New("invalid comment visibility")
+)
+
+// CodeCommentTargetNotFound flags a comment on a day, activity, hotel, flight
+// or installment the itinerary doesn't have
+const CodeCommentTargetNotFound = "comment_target_not_found"
+
+// CommentService manages threaded notes on itineraries and their elements
+type CommentService struct {
+	repo        repository.CommentRepository
+	itineraries *ItineraryService
+}
+
+// NewCommentService creates a new comment service
+func NewCommentService(repo repository.CommentRepository, itineraries *ItineraryService) *CommentService {
+	return &CommentService{
+		repo:        repo,
+		itineraries: itineraries,
+	}
+}
+
+// AddComment adds a note to an itinerary. Notes are internal unless marked
+// for the customer. A reply takes the element of the comment it answers and
+// stays internal when that comment is internal.
+func (s *CommentService) AddComment(itineraryID string, req *models.CreateCommentReq) (*models.Comment, error) {
+	itinerary, err := s.itineraries.GetItinerary(itineraryID)
+	if err != nil {
+		return nil, err
+	}
+
+	visibility := req.Visibility
+	if visibility == "" {
+		visibility = models.VisibilityInternal
+	}
+	if visibility != models.VisibilityInternal && visibility != models.VisibilityCustomer {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidVisibility, visibility)
+	}
+
+	target := req.ElementRef
+	if req.ParentID != "" {
+		parent, err := s.comment(itineraryID, req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if parent.Visibility == models.VisibilityInternal && visibility == models.VisibilityCustomer {
+			return nil, fmt.Errorf("%w: replies to internal notes stay internal", ErrInvalidVisibility)
+		}
+		target = parent.ElementRef
+	} else if issues := checkElementRef(itinerary, "", target); len(issues) > 0 {
+		return nil, &ValidationError{Issues: issues}
+	} else {
+		target = anchorElementRef(itinerary, target)
+	}
+
+	now := time.Now()
+	comment := &models.Comment{
+		ID:          uuid.New().String(),
+		ItineraryID: itineraryID,
+		ParentID:    req.ParentID,
+		ElementRef:  target,
+		Author:      req.Author,
+		Text:        req.Text,
+		Visibility:  visibility,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := s.repo.Create(comment); err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+	return comment, nil
+}
+
+// Threads returns the comment threads of an itinerary, oldest first. With a
+// visibility only the comments with that visibility are returned. Comments
+// point at where their element is now, and are marked detached when it was
+// removed.
+func (s *CommentService) Threads(itineraryID, visibility string) ([]models.CommentThread, error) {
+	itinerary, err := s.itineraries.GetItinerary(itineraryID)
+	if err != nil {
+		return nil, err
+	}
+	if visibility != "" && visibility != models.VisibilityInternal && visibility != models.VisibilityCustomer {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidVisibility, visibility)
+	}
+
+	comments, err := s.repo.GetByItineraryID(itineraryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	var selected []*models.Comment
+	for _, comment := range comments {
+		if visibility == "" || comment.Visibility == visibility {
+			located := *comment
+			ref, found := locateElementRef(itinerary, comment.ElementRef)
+			located.ElementRef, located.Detached = ref, !found
+			selected = append(selected, &located)
+		}
+	}
+	return buildThreads(selected), nil
+}
+
+// CustomerNotes returns the customer comment threads of an itinerary
+func (s *CommentService) CustomerNotes(itineraryID string) ([]models.CommentThread, error) {
+	return s.Threads(itineraryID, models.VisibilityCustomer)
+}
+
+// UpdateComment changes the text or visibility of a comment. Making a
+// comment internal makes its replies internal too; a reply can only be
+// shown to the customer when the comment it answers is.
+func (s *CommentService) UpdateComment(itineraryID, commentID string, req *models.UpdateCommentReq) (*models.Comment, error) {
+	stored, err := s.comment(itineraryID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *stored
+	comment := &updated
+	if req.Text != nil {
+		comment.Text = *req.Text
+	}
+	if req.Visibility != nil {
+		switch *req.Visibility {
+		case models.VisibilityInternal:
+		case models.VisibilityCustomer:
+			if comment.ParentID != "" {
+				parent, err := s.comment(itineraryID, comment.ParentID)
+				if err != nil {
+					return nil, err
+				}
+				if parent.Visibility == models.VisibilityInternal {
+					return nil, fmt.Errorf("%w: replies to internal notes stay internal", ErrInvalidVisibility)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidVisibility, *req.Visibility)
+		}
+		comment.Visibility = *req.Visibility
+	}
+	comment.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(commentID, comment); err != nil {
+		return nil, fmt.Errorf("failed to update comment: %w", err)
+	}
+	if comment.Visibility == models.VisibilityInternal && stored.Visibility != models.VisibilityInternal {
+		if err := s.hideReplies(itineraryID, commentID); err != nil {
+			return nil, err
+		}
+	}
+	return comment, nil
+}
+
+// DeleteComment deletes a comment and every reply to it
+func (s *CommentService) DeleteComment(itineraryID, commentID string) error {
+	if _, err := s.comment(itineraryID, commentID); err != nil {
+		return err
+	}
+
+	comments, err := s.repo.GetByItineraryID(itineraryID)
+	if err != nil {
+		return fmt.Errorf("failed to get comments: %w", err)
+	}
+	for _, reply := range descendants(comments, commentID) {
+		if err := s.repo.Delete(reply.ID); err != nil {
+			return fmt.Errorf("failed to delete comment: %w", err)
+		}
+	}
+	if err := s.repo.Delete(commentID); err != nil {
+		return fmt.Errorf("failed to delete comment: %w", err)
+	}
+	return nil
+}
+
+// DeleteItineraryComments deletes every comment of an itinerary, used when
+// the itinerary is deleted
+func (s *CommentService) DeleteItineraryComments(itineraryID string) error {
+	comments, err := s.repo.GetByItineraryID(itineraryID)
+	if err != nil {
+		return fmt.Errorf("failed to get comments: %w", err)
+	}
+	for _, comment := range comments {
+		if err := s.repo.Delete(comment.ID); err != nil {
+			return fmt.Errorf("failed to delete comment: %w", err)
+		}
+	}
+	return nil
+}
+
+// comment loads a comment and checks it belongs to the itinerary
+func (s *CommentService) comment(itineraryID, commentID string) (*models.Comment, error) {
+	comment, err := s.repo.GetByID(commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.ItineraryID != itineraryID {
+		return nil, ErrCommentNotFound
+	}
+	return comment, nil
+}
+
+// hideReplies makes every reply below a comment internal
+func (s *CommentService) hideReplies(itineraryID, commentID string) error {
+	comments, err := s.repo.GetByItineraryID(itineraryID)
+	if err != nil {
+		return fmt.Errorf("failed to get comments: %w", err)
+	}
+	for _, reply := range descendants(comments, commentID) {
+		if reply.Visibility == models.VisibilityInternal {
+			continue
+		}
+		hidden := *reply
+		hidden.Visibility = models.VisibilityInternal
+		hidden.UpdatedAt = time.Now()
+		if err := s.repo.Update(reply.ID, &hidden); err != nil {
+			return fmt.Errorf("failed to update comment: %w", err)
+		}
+	}
+	return nil
+}
+
+// descendants returns every reply below a comment, directly or indirectly
+func descendants(comments []*models.Comment, id string) []*models.Comment {
+	var found []*models.Comment
+	for _, comment := range comments {
+		if comment.ParentID == id {
+			found = append(found, comment)
+			found = append(found, descendants(comments, comment.ID)...)
+		}
+	}
+	return found
+}
+
+// buildThreads nests the selected comments under the comments they answer,
+// keeping their order. Replies to comments that weren't selected start a
+// thread of their own.
+func buildThreads(comments []*models.Comment) []models.CommentThread {
+	selected := make(map[string]bool, len(comments))
+	for _, comment := range comments {
+		selected[comment.ID] = true
+	}
+
+	var nest func(answers func(*models.Comment) bool) []models.CommentThread
+	nest = func(answers func(*models.Comment) bool) []models.CommentThread {
+		threads := []models.CommentThread{}
+		for _, comment := range comments {
+			if !answers(comment) {
+				continue
+			}
+			id := comment.ID
+			threads = append(threads, models.CommentThread{
+				Comment: *comment,
+				Replies: nest(func(reply *models.Comment) bool { return reply.ParentID == id }),
+			})
+		}
+		return threads
+	}
+	return nest(func(comment *models.Comment) bool { return !selected[comment.ParentID] })
+}
+
+// checkElementRef reports a reference to an element the itinerary doesn't
+// have, or to more than one element at once
+func checkElementRef(itinerary *models.Itinerary, path string, ref models.ElementRef) []ValidationIssue {
+	field := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+
+	targets := 0
+	for _, set := range []bool{ref.Day != 0 || ref.ActivityID != "", ref.Hotel != nil || ref.HotelID != "", ref.Flight != "", ref.Installment != 0} {
+		if set {
+			targets++
+		}
+	}
+	if targets > 1 {
+		return []ValidationIssue{issue(field("day"), CodeCommentTargetNotFound,
+			"a comment can point at a day or activity, a hotel, a flight or an installment, not several")}
+	}
+
+	switch {
+	case ref.ActivityID != "" && ref.Day == 0:
+		if _, found := locateElementRef(itinerary, ref); !found {
+			return []ValidationIssue{issue(field("activity_id"), CodeCommentTargetNotFound,
+				"the itinerary has no activity %s", ref.ActivityID)}
+		}
+	case ref.HotelID != "" && ref.Hotel == nil:
+		if _, found := locateElementRef(itinerary, ref); !found {
+			return []ValidationIssue{issue(field("hotel_id"), CodeCommentTargetNotFound,
+				"the itinerary has no hotel %s", ref.HotelID)}
+		}
+	case ref.Hotel != nil:
+		if *ref.Hotel < 0 || *ref.Hotel >= len(itinerary.Hotels) {
+			return []ValidationIssue{issue(field("hotel"), CodeCommentTargetNotFound,
+				"the itinerary has no hotel %d", *ref.Hotel)}
+		}
+	case ref.Flight != "":
+		if !containsFlightNumber(itinerary.Flights, ref.Flight) {
+			return []ValidationIssue{issue(field("flight"), CodeCommentTargetNotFound,
+				"the itinerary has no flight %s", ref.Flight)}
+		}
+	case ref.Installment != 0:
+		for _, installment := range itinerary.PaymentPlan.Installments {
+			if installment.InstallmentNumber == ref.Installment {
+				return nil
+			}
+		}
+		return []ValidationIssue{issue(field("installment"), CodeCommentTargetNotFound,
+			"the payment plan has no installment %d", ref.Installment)}
+	case ref.Day != 0:
+		return checkActivityRef(itinerary, field, ref)
+	case ref.Slot != "" || ref.Activity != nil:
+		return []ValidationIssue{issue(field("day"), CodeCommentTargetNotFound, "an activity comment needs a day")}
+	}
+	return nil
+}
+
+// anchorElementRef adds the ID of the activity or hotel a checked reference
+// points at by position, so the comment follows it when it moves
+func anchorElementRef(itinerary *models.Itinerary, ref models.ElementRef) models.ElementRef {
+	switch {
+	case ref.Hotel != nil && ref.HotelID == "":
+		ref.HotelID = itinerary.Hotels[*ref.Hotel].ID
+	case ref.Activity != nil && ref.ActivityID == "":
+		for _, day := range itinerary.Days {
+			if day.DayNumber == ref.Day {
+				ref.ActivityID = slotActivities(day.Activities, ref.Slot)[*ref.Activity].ID
+			}
+		}
+	}
+	if located, found := locateElementRef(itinerary, ref); found {
+		return located
+	}
+	return ref
+}
+
+// locateElementRef finds where the element of a reference is now: activities
+// and hotels are looked up by ID and get their current day, slot and index.
+// found is false when the element is no longer in the itinerary.
+func locateElementRef(itinerary *models.Itinerary, ref models.ElementRef) (models.ElementRef, bool) {
+	switch {
+	case ref.ActivityID != "":
+		for _, day := range itinerary.Days {
+			for _, slot := range timeSlots {
+				for j, activity := range slotActivities(day.Activities, slot.name) {
+					if activity.ID == ref.ActivityID {
+						index := j
+						ref.Day, ref.Slot, ref.Activity = day.DayNumber, slot.name, &index
+						return ref, true
+					}
+				}
+			}
+		}
+		return ref, false
+	case ref.HotelID != "":
+		for i, hotel := range itinerary.Hotels {
+			if hotel.ID == ref.HotelID {
+				index := i
+				ref.Hotel = &index
+				return ref, true
+			}
+		}
+		return ref, false
+	}
+	return ref, len(checkElementRef(itinerary, "", ref)) == 0
+}
+
+// checkActivityRef reports a reference to a day or activity the itinerary
+// doesn't have
+func checkActivityRef(itinerary *models.Itinerary, field func(string) string, ref models.ElementRef) []ValidationIssue {
+	var day *models.Day
+	for d := range itinerary.Days {
+		if itinerary.Days[d].DayNumber == ref.Day {
+			day = &itinerary.Days[d]
+		}
+	}
+	if day == nil {
+		return []ValidationIssue{issue(field("day"), CodeCommentTargetNotFound, "the itinerary has no day %d", ref.Day)}
+	}
+
+	if ref.Activity == nil {
+		if ref.Slot != "" {
+			return []ValidationIssue{issue(field("activity"), CodeCommentTargetNotFound,
+				"an activity comment needs an activity index")}
+		}
+		return nil
+	}
+	if ref.Slot != "morning" && ref.Slot != "afternoon" && ref.Slot != "evening" {
+		return []ValidationIssue{issue(field("slot"), CodeCommentTargetNotFound,
+			"unknown slot %q, expected morning, afternoon or evening", ref.Slot)}
+	}
+	if activities := slotActivities(day.Activities, ref.Slot); *ref.Activity < 0 || *ref.Activity >= len(activities) {
+		return []ValidationIssue{issue(field("activity"), CodeCommentTargetNotFound,
+			"day %d has no %s activity %d", ref.Day, ref.Slot, *ref.Activity)}
+	}
+	return nil
+}
+
+// containsFlightNumber tells whether a flight list has the given flight number
+func containsFlightNumber(flights []models.Flight, number string) bool {
+	for _, flight := range flights {
+		if flight.FlightNumber == number {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/repository"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// kerala returns a valid two day houseboat trip in Kerala whose activities
+// and hotel already have their IDs
+func kerala() *models.Itinerary {
+	date := func(d int) time.Time { return time.Date(2025, time.December, d, 0, 0, 0, 0, time.UTC) }
+	due := models.NewMoney(decimal.NewFromInt(42000), "INR")
+	return &models.Itinerary{
+		ID:        "kerala-1",
+		UserID:    "agent-kochi",
+		Title:     "Backwaters of Kerala",
+		StartDate: date(5),
+		EndDate:   date(6),
+		Days: []models.Day{
+			{DayNumber: 1, Date: date(5), Title: "Alleppey", Activities: models.Activities{
+				Morning: []models.Activity{
+					{ID: "act-boat", Name: "Houseboat cruise", Location: "Alleppey"},
+					{ID: "act-toddy", Name: "Toddy shop lunch", Location: "Alleppey"},
+				},
+			}},
+			{DayNumber: 2, Date: date(6), Title: "Kumarakom"},
+		},
+		Hotels: []models.Hotel{
+			{ID: "hotel-lake", Name: "Lake Palace", City: "Alleppey", CheckInDate: date(5), CheckOutDate: date(6), Nights: 1},
+		},
+		Flights: []models.Flight{{FlightNumber: "6E 6011", From: "DEL", To: "COK",
+			Departure: date(5).Add(2 * time.Hour), Arrival: date(5).Add(5 * time.Hour)}},
+		PaymentPlan: models.PaymentPlan{
+			AmountDue: due,
+			DueDate:   date(1),
+			Installments: []models.Installment{
+				{InstallmentNumber: 1, Amount: due, DueDate: date(1), Status: "Pending"},
+			},
+		},
+		Status: models.StatusDraft,
+	}
+}
+
+// newCommentFixture returns a comment service over the Kerala trip, deleting
+// the comments with their itinerary the way the routes do
+func newCommentFixture(t *testing.T) (*ItineraryService, *CommentService) {
+	t.Helper()
+	repo := repository.NewInMemoryRepo()
+	if err := repo.Create(kerala()); err != nil {
+		t.Fatal(err)
+	}
+	itineraries := NewItineraryService(repo, nil)
+	comments := NewCommentService(repository.NewInMemoryCommentRepo(), itineraries)
+	itineraries.OnDelete(comments.DeleteItineraryComments)
+	return itineraries, comments
+}
+
+// threadTexts flattens comment threads into their texts, replies indented
+// under the comment they answer
+func threadTexts(threads []models.CommentThread, indent string) []string {
+	var texts []string
+	for _, thread := range threads {
+		texts = append(texts, indent+thread.Text)
+		texts = append(texts, threadTexts(thread.Replies, indent+"  ")...)
+	}
+	return texts
+}
+
+func TestAddCommentTargets(t *testing.T) {
+	second, first := 1, 0
+	missing := 2
+
+	tests := []struct {
+		name    string
+		ref     models.ElementRef
+		want    models.ElementRef
+		wantErr bool
+	}{
+		{"whole itinerary", models.ElementRef{}, models.ElementRef{}, false},
+		{"day", models.ElementRef{Day: 2}, models.ElementRef{Day: 2}, false},
+		{"activity by position", models.ElementRef{Day: 1, Slot: "morning", Activity: &second},
+			models.ElementRef{Day: 1, Slot: "morning", Activity: &second, ActivityID: "act-toddy"}, false},
+		{"activity by id", models.ElementRef{ActivityID: "act-boat"},
+			models.ElementRef{Day: 1, Slot: "morning", Activity: &first, ActivityID: "act-boat"}, false},
+		{"hotel by position", models.ElementRef{Hotel: &first}, models.ElementRef{Hotel: &first, HotelID: "hotel-lake"}, false},
+		{"flight", models.ElementRef{Flight: "6E 6011"}, models.ElementRef{Flight: "6E 6011"}, false},
+		{"installment", models.ElementRef{Installment: 1}, models.ElementRef{Installment: 1}, false},
+		{"missing day", models.ElementRef{Day: 3}, models.ElementRef{}, true},
+		{"missing activity", models.ElementRef{Day: 1, Slot: "morning", Activity: &missing}, models.ElementRef{}, true},
+		{"unknown slot", models.ElementRef{Day: 1, Slot: "night", Activity: &first}, models.ElementRef{}, true},
+		{"missing hotel", models.ElementRef{HotelID: "hotel-beach"}, models.ElementRef{}, true},
+		{"missing flight", models.ElementRef{Flight: "AI 501"}, models.ElementRef{}, true},
+		{"missing installment", models.ElementRef{Installment: 2}, models.ElementRef{}, true},
+		{"two targets", models.ElementRef{Day: 1, Installment: 1}, models.ElementRef{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, comments := newCommentFixture(t)
+			comment, err := comments.AddComment("kerala-1", &models.CreateCommentReq{
+				ElementRef: test.ref, Author: "agent-kochi", Text: "Check this",
+			})
+			if test.wantErr {
+				var validation *ValidationError
+				if !errors.As(err, &validation) || validation.Issues[0].Code != CodeCommentTargetNotFound {
+					t.Errorf("got error %v, want %s", err, CodeCommentTargetNotFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprintf("%+v", refValues(comment.ElementRef)) != fmt.Sprintf("%+v", refValues(test.want)) {
+				t.Errorf("got %+v, want %+v", refValues(comment.ElementRef), refValues(test.want))
+			}
+			if comment.Visibility != models.VisibilityInternal {
+				t.Errorf("got visibility %s, want %s", comment.Visibility, models.VisibilityInternal)
+			}
+		})
+	}
+}
+
+// refValues replaces the index pointers of a reference with their values so
+// references can be compared
+func refValues(ref models.ElementRef) []interface{} {
+	deref := func(index *int) interface{} {
+		if index == nil {
+			return nil
+		}
+		return *index
+	}
+	return []interface{}{ref.Day, ref.Slot, deref(ref.Activity), ref.ActivityID, deref(ref.Hotel), ref.HotelID, ref.Flight, ref.Installment}
+}
+
+func TestCommentThreadsAndVisibility(t *testing.T) {
+	_, comments := newCommentFixture(t)
+	add := func(parent, visibility, text string) *models.Comment {
+		t.Helper()
+		comment, err := comments.AddComment("kerala-1", &models.CreateCommentReq{
+			ParentID: parent, Author: "agent-kochi", Text: text, Visibility: visibility,
+		})
+		if err != nil {
+			t.Fatalf("adding %q: %v", text, err)
+		}
+		return comment
+	}
+
+	welcome := add("", models.VisibilityCustomer, "Welcome to Kerala")
+	add(welcome.ID, models.VisibilityCustomer, "Pack light")
+	supplier := add("", "", "Supplier owes us a refund")
+	add(supplier.ID, "", "Chased by email")
+
+	if _, err := comments.AddComment("kerala-1", &models.CreateCommentReq{
+		ParentID: supplier.ID, Author: "agent-kochi", Text: "Leaked", Visibility: models.VisibilityCustomer,
+	}); !errors.Is(err, ErrInvalidVisibility) {
+		t.Errorf("customer reply to an internal note: got error %v", err)
+	}
+	if _, err := comments.AddComment("kerala-1", &models.CreateCommentReq{
+		Author: "agent-kochi", Text: "Hello", Visibility: "public",
+	}); !errors.Is(err, ErrInvalidVisibility) {
+		t.Errorf("unknown visibility: got error %v", err)
+	}
+
+	tests := []struct {
+		visibility string
+		want       []string
+	}{
+		{"", []string{"Welcome to Kerala", "  Pack light", "Supplier owes us a refund", "  Chased by email"}},
+		{models.VisibilityCustomer, []string{"Welcome to Kerala", "  Pack light"}},
+		{models.VisibilityInternal, []string{"Supplier owes us a refund", "  Chased by email"}},
+	}
+	for _, test := range tests {
+		t.Run("visibility "+test.visibility, func(t *testing.T) {
+			threads, err := comments.Threads("kerala-1", test.visibility)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := threadTexts(threads, ""); fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	// hiding a comment hides its replies from the customer too
+	internal := models.VisibilityInternal
+	if _, err := comments.UpdateComment("kerala-1", welcome.ID, &models.UpdateCommentReq{Visibility: &internal}); err != nil {
+		t.Fatal(err)
+	}
+	if notes, _ := comments.CustomerNotes("kerala-1"); len(notes) != 0 {
+		t.Errorf("got customer notes %q", threadTexts(notes, ""))
+	}
+}
+
+func TestCommentsFollowTheirElement(t *testing.T) {
+	itineraries, comments := newCommentFixture(t)
+	second := 1
+	comment, err := comments.AddComment("kerala-1", &models.CreateCommentReq{
+		ElementRef: models.ElementRef{Day: 1, Slot: "morning", Activity: &second}, Author: "agent-kochi", Text: "Vegetarian options?",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the lunch moves to the afternoon of day 2, then is removed
+	days := kerala().Days
+	lunch := days[0].Activities.Morning[1]
+	days[0].Activities.Morning = days[0].Activities.Morning[:1]
+	days[1].Activities.Afternoon = []models.Activity{lunch}
+	if _, err := itineraries.UpdateItinerary("kerala-1", &models.UpdateItineraryReq{Days: days}); err != nil {
+		t.Fatal(err)
+	}
+	threads, _ := comments.Threads("kerala-1", "")
+	if ref := threads[0].ElementRef; ref.Day != 2 || ref.Slot != "afternoon" || *ref.Activity != 0 || threads[0].Detached {
+		t.Errorf("got %+v, detached %v", refValues(ref), threads[0].Detached)
+	}
+
+	days[1].Activities.Afternoon = nil
+	if _, err := itineraries.UpdateItinerary("kerala-1", &models.UpdateItineraryReq{Days: days}); err != nil {
+		t.Fatal(err)
+	}
+	threads, _ = comments.Threads("kerala-1", "")
+	if threads[0].ID != comment.ID || !threads[0].Detached {
+		t.Errorf("got a comment still attached to %+v", refValues(threads[0].ElementRef))
+	}
+}
+
+func TestDeleteComments(t *testing.T) {
+	itineraries, comments := newCommentFixture(t)
+	first, err := comments.AddComment("kerala-1", &models.CreateCommentReq{Author: "agent-kochi", Text: "Confirm the boat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := comments.AddComment("kerala-1", &models.CreateCommentReq{ParentID: first.ID, Author: "agent-kochi", Text: "Confirmed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comments.AddComment("kerala-1", &models.CreateCommentReq{Author: "agent-kochi", Text: "Ask about the monsoon"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := comments.DeleteComment("other-trip", first.ID); !errors.Is(err, ErrCommentNotFound) {
+		t.Errorf("comment of another itinerary: got error %v", err)
+	}
+	if err := comments.DeleteComment("kerala-1", first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := comments.UpdateComment("kerala-1", reply.ID, &models.UpdateCommentReq{}); !errors.Is(err, ErrCommentNotFound) {
+		t.Errorf("the reply outlived its comment: %v", err)
+	}
+	threads, _ := comments.Threads("kerala-1", "")
+	if got := threadTexts(threads, ""); fmt.Sprint(got) != "[Ask about the monsoon]" {
+		t.Errorf("got %q", got)
+	}
+
+	if err := itineraries.DeleteItinerary("kerala-1"); err != nil {
+		t.Fatal(err)
+	}
+	if left, _ := comments.repo.GetByItineraryID("kerala-1"); len(left) != 0 {
+		t.Errorf("got %d comments of a deleted itinerary", len(left))
+	}
+	if _, err := comments.Threads("kerala-1", ""); !errors.Is(err, ErrItineraryNotFound) {
+		t.Errorf("got error %v, want %v", err, ErrItineraryNotFound)
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// coverage issue codes
+const (
+	CodeHotelCityMismatch = "hotel_city_mismatch"
+)
+
+// UncoveredNight is a night of the trip without any hotel booked
+type UncoveredNight struct {
+	Date      time.Time `json:"date"`
+	DayNumber int       `json:"day_number"`
+}
+
+// DoubleBookedNight is a night covered by more than one hotel stay
+type DoubleBookedNight struct {
+	Date      time.Time `json:"date"`
+	DayNumber int       `json:"day_number"`
+	Hotels    []string  `json:"hotels"`
+}
+
+// CityMismatch is a day whose activities take place in another city than
+// the hotel booked for that night
+type CityMismatch struct {
+	Date      time.Time `json:"date"`
+	DayNumber int       `json:"day_number"`
+	Hotel     string    `json:"hotel"`
+	HotelCity string    `json:"hotel_city"`
+	Locations []string  `json:"locations"`
+
+	dayIndex int
+}
+
+// CoverageReport lists the accommodation problems of an itinerary
+type CoverageReport struct {
+	Nights             int                 `json:"nights"`
+	UncoveredNights    []UncoveredNight    `json:"uncovered_nights"`
+	DoubleBookedNights []DoubleBookedNight `json:"double_booked_nights"`
+	CityMismatches     []CityMismatch      `json:"city_mismatches"`
+}
+
+// HasIssues reports whether anything was found
+func (r *CoverageReport) HasIssues() bool {
+	return len(r.UncoveredNights)+len(r.DoubleBookedNights)+len(r.CityMismatches) > 0
+}
+
+// CoverageService checks every night of a trip against the hotel bookings
+type CoverageService struct{}
+
+// NewCoverageService creates a new coverage service
+func NewCoverageService() *CoverageService {
+	return &CoverageService{}
+}
+
+// Analyze checks each night between StartDate and EndDate against the hotel
+// check-in/check-out ranges and the day's activity locations
+func (s *CoverageService) Analyze(itinerary *models.Itinerary) *CoverageReport {
+	report := &CoverageReport{
+		UncoveredNights:    []UncoveredNight{},
+		DoubleBookedNights: []DoubleBookedNight{},
+		CityMismatches:     []CityMismatch{},
+	}
+
+	nights := daysBetween(itinerary.StartDate, itinerary.EndDate)
+	if nights < 0 {
+		nights = 0
+	}
+	report.Nights = nights
+
+	cities := hotelCities(itinerary.Hotels)
+
+	for n := 0; n < nights; n++ {
+		night := dateOf(itinerary.StartDate).AddDate(0, 0, n)
+		dayNumber := n + 1
+
+		var booked []int
+		for i, hotel := range itinerary.Hotels {
+			if !night.Before(dateOf(hotel.CheckInDate)) && night.Before(dateOf(hotel.CheckOutDate)) {
+				booked = append(booked, i)
+			}
+		}
+
+		switch {
+		case len(booked) == 0:
+			report.UncoveredNights = append(report.UncoveredNights, UncoveredNight{Date: night, DayNumber: dayNumber})
+			continue
+		case len(booked) > 1:
+			double := DoubleBookedNight{Date: night, DayNumber: dayNumber}
+			for _, i := range booked {
+				double.Hotels = append(double.Hotels, itinerary.Hotels[i].Name)
+			}
+			report.DoubleBookedNights = append(report.DoubleBookedNights, double)
+		}
+
+		// compare the day's activities with the city of the hotel for that night
+		dayIndex := dayIndexForDate(itinerary.Days, night)
+		if dayIndex < 0 {
+			continue
+		}
+		hotel := itinerary.Hotels[booked[0]]
+		if locations := foreignLocations(itinerary.Days[dayIndex], hotel.City, cities); len(locations) > 0 {
+			report.CityMismatches = append(report.CityMismatches, CityMismatch{
+				Date:      night,
+				DayNumber: itinerary.Days[dayIndex].DayNumber,
+				Hotel:     hotel.Name,
+				HotelCity: hotel.City,
+				Locations: locations,
+				dayIndex:  dayIndex,
+			})
+		}
+	}
+
+	return report
+}
+
+// ValidationRule reports gaps and city mismatches as validation warnings.
+// Double-booked nights are left to the hotel_overlap rule, which already
+// reports each overlapping stay once.
+func (s *CoverageService) ValidationRule() ValidationRule {
+	return func(itinerary *models.Itinerary) []ValidationIssue {
+		report := s.Analyze(itinerary)
+
+		var issues []ValidationIssue
+		for _, night := range report.UncoveredNights {
+			issues = append(issues, warning(fmt.Sprintf("days[%d]", night.DayNumber-1), CodeNightWithoutHotel,
+				"no hotel booked for the night of %s", night.Date.Format("2006-01-02")))
+		}
+		for _, mismatch := range report.CityMismatches {
+			issues = append(issues, warning(fmt.Sprintf("days[%d].activities", mismatch.dayIndex), CodeHotelCityMismatch,
+				"day %d activities at %s are not in %s where %s is booked", mismatch.DayNumber,
+				strings.Join(mismatch.Locations, ", "), mismatch.HotelCity, mismatch.Hotel))
+		}
+		return issues
+	}
+}
+
+// dayIndexForDate finds the day planned for a calendar date
+func dayIndexForDate(days []models.Day, date time.Time) int {
+	for i, day := range days {
+		if dateOf(day.Date).Equal(date) {
+			return i
+		}
+	}
+	return -1
+}
+
+// hotelCities returns the distinct hotel cities of the trip
+func hotelCities(hotels []models.Hotel) []string {
+	seen := make(map[string]bool)
+	var cities []string
+	for _, hotel := range hotels {
+		city := strings.TrimSpace(hotel.City)
+		if city == "" || seen[strings.ToLower(city)] {
+			continue
+		}
+		seen[strings.ToLower(city)] = true
+		cities = append(cities, city)
+	}
+	return cities
+}
+
+// foreignLocations returns the activity locations of a day that name another
+// city of the trip and not the hotel's own city. Locations that name no known
+// city (e.g. "Eiffel Tower") are given the benefit of the doubt.
+func foreignLocations(day models.Day, hotelCity string, cities []string) []string {
+	var foreign []string
+	for _, activity := range allActivities(day.Activities) {
+		location := strings.ToLower(activity.Location)
+		if location == "" || strings.Contains(location, strings.ToLower(hotelCity)) {
+			continue
+		}
+		for _, city := range cities {
+			if !strings.EqualFold(city, hotelCity) && strings.Contains(location, strings.ToLower(city)) {
+				foreign = append(foreign, activity.Location)
+				break
+			}
+		}
+	}
+	return foreign
+}
+
+// allActivities returns the activities of a day in time slot order
+func allActivities(activities models.Activities) []models.Activity {
+	all := make([]models.Activity, 0, len(activities.Morning)+len(activities.Afternoon)+len(activities.Evening))
+	all = append(all, activities.Morning...)
+	all = append(all, activities.Afternoon...)
+	return append(all, activities.Evening...)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// portugalTrip returns a four night trip, two nights in Lisbon and two in
+// Porto, with a sightseeing activity in each city
+func portugalTrip() *models.Itinerary {
+	start := time.Date(2025, time.October, 6, 0, 0, 0, 0, time.UTC)
+	days := make([]models.Day, 5)
+	for i := range days {
+		days[i] = models.Day{DayNumber: i + 1, Date: start.AddDate(0, 0, i)}
+	}
+	days[1].Activities.Morning = []models.Activity{{Name: "Tram 28", Location: "Alfama, Lisbon"}}
+	days[3].Activities.Afternoon = []models.Activity{{Name: "Port cellars", Location: "Vila Nova de Gaia, Porto"}}
+
+	return &models.Itinerary{
+		StartDate: start,
+		EndDate:   start.AddDate(0, 0, 4),
+		Days:      days,
+		Hotels: []models.Hotel{
+			{Name: "Lisboa Plaza", City: "Lisbon", CheckInDate: start, CheckOutDate: start.AddDate(0, 0, 2), Nights: 2},
+			{Name: "Porto Ribeira", City: "Porto", CheckInDate: start.AddDate(0, 0, 2), CheckOutDate: start.AddDate(0, 0, 4), Nights: 2},
+		},
+	}
+}
+
+func TestCoverageAnalyze(t *testing.T) {
+	tests := []struct {
+		name           string
+		change         func(itinerary *models.Itinerary)
+		wantUncovered  []int
+		wantDouble     []int
+		wantMismatches []int
+	}{
+		{"every night covered", nil, nil, nil, nil},
+		{"late check-in leaves a gap", func(it *models.Itinerary) {
+			it.Hotels[1].CheckInDate = it.Hotels[1].CheckInDate.AddDate(0, 0, 1)
+		}, []int{3}, nil, nil},
+		{"no hotels at all", func(it *models.Itinerary) {
+			it.Hotels = nil
+		}, []int{1, 2, 3, 4}, nil, nil},
+		{"late check-out overlaps the next stay", func(it *models.Itinerary) {
+			it.Hotels[0].CheckOutDate = it.Hotels[0].CheckOutDate.AddDate(0, 0, 1)
+		}, nil, []int{3}, nil},
+		{"activity in the other city", func(it *models.Itinerary) {
+			it.Days[1].Activities.Evening = []models.Activity{{Name: "Fado night", Location: "Porto"}}
+		}, nil, nil, []int{2}},
+		{"activity naming no city of the trip", func(it *models.Itinerary) {
+			it.Days[3].Activities.Evening = []models.Activity{{Name: "Day trip", Location: "Sintra"}}
+		}, nil, nil, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itinerary := portugalTrip()
+			if test.change != nil {
+				test.change(itinerary)
+			}
+			report := NewCoverageService().Analyze(itinerary)
+
+			if report.Nights != 4 {
+				t.Errorf("got %d nights, want 4", report.Nights)
+			}
+			var uncovered, double, mismatches []int
+			for _, night := range report.UncoveredNights {
+				uncovered = append(uncovered, night.DayNumber)
+			}
+			for _, night := range report.DoubleBookedNights {
+				double = append(double, night.DayNumber)
+			}
+			for _, mismatch := range report.CityMismatches {
+				mismatches = append(mismatches, mismatch.DayNumber)
+			}
+			if fmt.Sprint(uncovered) != fmt.Sprint(test.wantUncovered) {
+				t.Errorf("got uncovered nights %v, want %v", uncovered, test.wantUncovered)
+			}
+			if fmt.Sprint(double) != fmt.Sprint(test.wantDouble) {
+				t.Errorf("got double-booked nights %v, want %v", double, test.wantDouble)
+			}
+			if fmt.Sprint(mismatches) != fmt.Sprint(test.wantMismatches) {
+				t.Errorf("got city mismatches on days %v, want %v", mismatches, test.wantMismatches)
+			}
+			if report.HasIssues() != (len(uncovered)+len(double)+len(mismatches) > 0) {
+				t.Errorf("got HasIssues %v", report.HasIssues())
+			}
+		})
+	}
+}
+
+func TestCoverageValidationRule(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(itinerary *models.Itinerary)
+		want   []string
+	}{
+		{"nothing to report", nil, nil},
+		{"gap is a warning", func(it *models.Itinerary) {
+			it.Hotels = it.Hotels[:1]
+		}, []string{"warning " + CodeNightWithoutHotel, "warning " + CodeNightWithoutHotel}},
+		{"city mismatch is a warning", func(it *models.Itinerary) {
+			it.Days[1].Activities.Evening = []models.Activity{{Name: "Fado night", Location: "Porto"}}
+		}, []string{"warning " + CodeHotelCityMismatch}},
+		// the overlap is left to hotel_overlap, once per stay
+		{"double-booked nights are not repeated", func(it *models.Itinerary) {
+			it.Hotels[0].CheckOutDate = it.Hotels[0].CheckOutDate.AddDate(0, 0, 1)
+		}, nil},
+	}
+
+	rule := NewCoverageService().ValidationRule()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itinerary := portugalTrip()
+			if test.change != nil {
+				test.change(itinerary)
+			}
+			var got []string
+			for _, found := range rule(itinerary) {
+				got = append(got, found.Severity+" "+found.Code)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestOverlappingStaysReportedOnce(t *testing.T) {
+	itinerary := portugalTrip()
+	itinerary.Hotels[0].CheckOutDate = itinerary.Hotels[0].CheckOutDate.AddDate(0, 0, 1)
+
+	validator := NewValidator(validateHotelOverlaps, NewCoverageService().ValidationRule())
+	report := validator.Report(itinerary)
+	if len(report.Errors) != 1 || report.Errors[0].Code != CodeHotelOverlap {
+		t.Errorf("got errors %v, want a single %s", report.Errors, CodeHotelOverlap)
+	}
+}
@@ -0,0 +1,378 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/reference"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrUnknownCurrency      = errors.New("unknown currency")
+	ErrNoExchangeRate       = errors.New("no exchange rate")
+	ErrInvalidExchangeRates = errors.New("invalid exchange rates")
+)
+
+// currency issue codes
+const (
+	CodeUnknownCurrency     = "unknown_currency"
+	CodeMissingExchangeRate = "missing_exchange_rate"
+	CodeInvalidPrecision    = "invalid_precision"
+	CodeInvalidExchangeRate = "invalid_exchange_rate"
+)
+
+// DefaultCurrency is the currency of amounts given without one when no
+// default currency is configured
+const DefaultCurrency = "INR"
+
+// CurrencyService rounds amounts to the minor units of their ISO 4217
+// currency and converts them with a local table of exchange rates
+type CurrencyService struct {
+	catalog         *reference.Catalog
+	defaultCurrency string
+
+	// value of one unit of a common base currency in every currency
+	rates map[string]decimal.Decimal
+}
+
+// NewCurrencyService creates a currency service. Rates give the value of one
+// unit of a common base in every currency, e.g. EUR=1, USD=1.08, INR=90.12;
+// without rates only amounts in the same currency can be combined.
+func NewCurrencyService(defaultCurrency string, rates map[string]decimal.Decimal) *CurrencyService {
+	if defaultCurrency == "" {
+		defaultCurrency = DefaultCurrency
+	}
+	if rates == nil {
+		rates = make(map[string]decimal.Decimal)
+	}
+	return &CurrencyService{
+		catalog:         reference.Default(),
+		defaultCurrency: strings.ToUpper(defaultCurrency),
+		rates:           rates,
+	}
+}
+
+// ParseExchangeRates reads an exchange rate table written as
+// "EUR=1,USD=1.08,INR=90.12". Every code must be an ISO 4217 currency and
+// every rate positive.
+func ParseExchangeRates(table string) (map[string]decimal.Decimal, error) {
+	rates := make(map[string]decimal.Decimal)
+	for _, entry := range strings.Split(table, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		code, value, ok := strings.Cut(entry, "=")
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not CODE=rate", ErrInvalidExchangeRates, entry)
+		}
+		if _, known := reference.Default().Currency(code); !known {
+			return nil, fmt.Errorf("%w: %w %q", ErrInvalidExchangeRates, ErrUnknownCurrency, code)
+		}
+		rate, err := decimal.NewFromString(strings.TrimSpace(value))
+		if err != nil || !rate.IsPositive() {
+			return nil, fmt.Errorf("%w: rate of %s must be a positive number", ErrInvalidExchangeRates, code)
+		}
+		rates[code] = rate
+	}
+	return rates, nil
+}
+
+// DefaultCurrency returns the currency of amounts given without one
+func (s *CurrencyService) DefaultCurrency() string {
+	return s.defaultCurrency
+}
+
+// Currency resolves an ISO 4217 currency code
+func (s *CurrencyService) Currency(code string) (reference.Currency, error) {
+	currency, ok := s.catalog.Currency(code)
+	if !ok {
+		return reference.Currency{}, fmt.Errorf("%w %q", ErrUnknownCurrency, code)
+	}
+	return currency, nil
+}
+
+// minorUnits returns the number of decimals of a currency, 2 for unknown ones
+func (s *CurrencyService) minorUnits(code string) int32 {
+	if currency, ok := s.catalog.Currency(code); ok {
+		return int32(currency.MinorUnits)
+	}
+	return 2
+}
+
+// Round rounds an amount half away from zero to the minor units of its
+// currency
+func (s *CurrencyService) Round(amount models.Money) models.Money {
+	return models.NewMoney(amount.Amount.Round(s.minorUnits(amount.Currency)), amount.Currency)
+}
+
+// IsRounded tells whether an amount has no more decimals than its currency
+func (s *CurrencyService) IsRounded(amount models.Money) bool {
+	return amount.Amount.Equal(s.Round(amount).Amount)
+}
+
+// Convert converts an amount into another currency through the exchange
+// rate table and rounds it to the minor units of that currency
+func (s *CurrencyService) Convert(amount models.Money, to string) (models.Money, error) {
+	if amount.Currency == to {
+		return s.Round(amount), nil
+	}
+	from, ok := s.rates[amount.Currency]
+	if !ok {
+		return models.Money{}, fmt.Errorf("%w from %s to %s", ErrNoExchangeRate, amount.Currency, to)
+	}
+	into, ok := s.rates[to]
+	if !ok {
+		return models.Money{}, fmt.Errorf("%w from %s to %s", ErrNoExchangeRate, amount.Currency, to)
+	}
+	return s.Round(models.NewMoney(amount.Amount.Mul(into).Div(from), to)), nil
+}
+
+// Sum adds up amounts converted into a currency. Every amount is rounded
+// after conversion, so the sum is exact in that currency.
+func (s *CurrencyService) Sum(currency string, amounts ...models.Money) (models.Money, error) {
+	sum := models.NewMoney(decimal.Zero, currency)
+	for _, amount := range amounts {
+		converted, err := s.Convert(amount, currency)
+		if err != nil {
+			return models.Money{}, err
+		}
+		sum.Amount = sum.Amount.Add(converted.Amount)
+	}
+	return sum, nil
+}
+
+// Format writes an amount for display, e.g. "EUR 1,234.50"
+func (s *CurrencyService) Format(amount models.Money) string {
+	text := amount.Amount.StringFixed(s.minorUnits(amount.Currency))
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign, text = "-", text[1:]
+	}
+	whole, fraction, _ := strings.Cut(text, ".")
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	if fraction != "" {
+		whole += "." + fraction
+	}
+	return strings.TrimSpace(amount.Currency + " " + sign + whole)
+}
+
+// Rates returns a copy of the exchange rate table
+func (s *CurrencyService) Rates() map[string]decimal.Decimal {
+	rates := make(map[string]decimal.Decimal, len(s.rates))
+	for code, rate := range s.rates {
+		rates[code] = rate
+	}
+	return rates
+}
+
+// forPlan returns a currency service that converts with the exchange rates
+// stored with a payment plan. Currencies the plan has no rate for take the
+// configured rate, rebased so it agrees with the stored ones.
+func (s *CurrencyService) forPlan(plan models.PaymentPlan) *CurrencyService {
+	stored := make(map[string]decimal.Decimal, len(plan.ExchangeRates))
+	for code, rate := range plan.ExchangeRates {
+		// invalid rates are reported by validate
+		if rate.IsPositive() {
+			stored[code] = rate
+		}
+	}
+	if len(stored) == 0 {
+		return s
+	}
+	return &CurrencyService{
+		catalog:         s.catalog,
+		defaultCurrency: s.defaultCurrency,
+		rates:           addRates(stored, s.rates),
+	}
+}
+
+// addRates returns a copy of rates completed with the currencies only other
+// has a rate for. Both tables may use a different base, so the added rates
+// are rebased through the first currency, in code order, found in both.
+func addRates(rates, other map[string]decimal.Decimal) map[string]decimal.Decimal {
+	combined := make(map[string]decimal.Decimal, len(rates)+len(other))
+	codes := make([]string, 0, len(rates))
+	for code, rate := range rates {
+		combined[code] = rate
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	factor := decimal.NewFromInt(1)
+	for _, code := range codes {
+		if base, ok := other[code]; ok {
+			factor = rates[code].Div(base)
+			break
+		}
+	}
+	for code, rate := range other {
+		if _, ok := combined[code]; !ok {
+			combined[code] = rate.Mul(factor)
+		}
+	}
+	return combined
+}
+
+// snapshotRates stores the exchange rates of the currencies an itinerary
+// uses with its payment plan, so its amounts keep converting the same way
+// when the configured rates change. Rates already stored are kept; a
+// currency without one takes the configured rate. Plans in a single
+// currency need no rates.
+func (s *CurrencyService) snapshotRates(itinerary *models.Itinerary) {
+	plan := &itinerary.PaymentPlan
+	codes := usedCurrencies(itinerary)
+	if len(codes) < 2 {
+		return
+	}
+	rates := s.forPlan(*plan).rates
+	snapshot := make(map[string]decimal.Decimal, len(codes))
+	for code, rate := range plan.ExchangeRates {
+		snapshot[code] = rate
+	}
+	for _, code := range codes {
+		if _, ok := snapshot[code]; ok {
+			continue
+		}
+		if rate, ok := rates[code]; ok {
+			snapshot[code] = rate
+		}
+	}
+	if len(snapshot) > 0 {
+		plan.ExchangeRates = snapshot
+	}
+}
+
+// usedCurrencies lists the currencies of the amount due, the installments
+// and the prices of an itinerary, in code order
+func usedCurrencies(itinerary *models.Itinerary) []string {
+	seen := map[string]bool{itinerary.PaymentPlan.AmountDue.Currency: true}
+	for _, installment := range itinerary.PaymentPlan.Installments {
+		seen[installment.Amount.Currency] = true
+	}
+	for _, element := range pricedElements(itinerary) {
+		seen[element.price.Currency] = true
+	}
+	codes := make([]string, 0, len(seen))
+	for code := range seen {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// normalizeMoney upper-cases currency codes and gives amounts without a
+// currency the one of their payment plan, or the default currency
+func (s *CurrencyService) normalizeMoney(itinerary *models.Itinerary) {
+	plan := &itinerary.PaymentPlan
+	plan.AmountDue.Currency = strings.ToUpper(strings.TrimSpace(plan.AmountDue.Currency))
+	if plan.AmountDue.Currency == "" {
+		plan.AmountDue.Currency = s.defaultCurrency
+	}
+	for i := range plan.Installments {
+		amount := &plan.Installments[i].Amount
+		amount.Currency = strings.ToUpper(strings.TrimSpace(amount.Currency))
+		if amount.Currency == "" {
+			amount.Currency = plan.AmountDue.Currency
+		}
+	}
+	if plan.ExchangeRates != nil {
+		rates := make(map[string]decimal.Decimal, len(plan.ExchangeRates))
+		for code, rate := range plan.ExchangeRates {
+			rates[strings.ToUpper(strings.TrimSpace(code))] = rate
+		}
+		plan.ExchangeRates = rates
+	}
+	for _, element := range pricedElements(itinerary) {
+		element.price.Currency = strings.ToUpper(strings.TrimSpace(element.price.Currency))
+		if element.price.Currency == "" {
+			element.price.Currency = plan.AmountDue.Currency
+		}
+	}
+}
+
+// validate checks that every currency is an ISO 4217 currency, that amounts
+// have no more decimals than their currency, that the stored exchange rates
+// are positive, that every foreign currency can be converted into the one of
+// the payment plan and that the installments converted into it add up
+// exactly to the amount due
+func (s *CurrencyService) validate(itinerary *models.Itinerary) []ValidationIssue {
+	var issues []ValidationIssue
+	plan := &itinerary.PaymentPlan
+	currency := plan.AmountDue.Currency
+
+	codes := make([]string, 0, len(plan.ExchangeRates))
+	for code := range plan.ExchangeRates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		path := "payment_plan.exchange_rates." + code
+		if _, err := s.Currency(code); err != nil {
+			issues = append(issues, issue(path, CodeUnknownCurrency, "exchange rates: %s", err))
+		} else if !plan.ExchangeRates[code].IsPositive() {
+			issues = append(issues, issue(path, CodeInvalidExchangeRate, "exchange rate of %s must be positive", code))
+		}
+	}
+
+	if _, err := s.Currency(currency); err != nil {
+		return []ValidationIssue{issue("payment_plan.amount_due.currency", CodeUnknownCurrency,
+			"payment plan: %s", err)}
+	}
+	if !s.IsRounded(plan.AmountDue) {
+		issues = append(issues, issue("payment_plan.amount_due", CodeInvalidPrecision,
+			"total amount %s has more decimals than %s allows", plan.AmountDue.Amount, currency))
+	}
+
+	convertible := true
+	amounts := make([]models.Money, 0, len(plan.Installments))
+	for i, installment := range plan.Installments {
+		path := fmt.Sprintf("payment_plan.installments[%d].amount", i)
+		amount := installment.Amount
+		if _, err := s.Currency(amount.Currency); err != nil {
+			issues = append(issues, issue(path+".currency", CodeUnknownCurrency,
+				"installment %d: %s", installment.InstallmentNumber, err))
+			convertible = false
+			continue
+		}
+		if !s.IsRounded(amount) {
+			issues = append(issues, issue(path, CodeInvalidPrecision,
+				"installment %d: %s has more decimals than %s allows", installment.InstallmentNumber, amount.Amount, amount.Currency))
+		}
+		if _, err := s.Convert(amount, currency); err != nil {
+			issues = append(issues, issue(path+".currency", CodeMissingExchangeRate,
+				"installment %d: %s", installment.InstallmentNumber, err))
+			convertible = false
+			continue
+		}
+		amounts = append(amounts, amount)
+	}
+
+	if convertible && len(amounts) > 0 {
+		sum, _ := s.Sum(currency, amounts...)
+		if !sum.Amount.Equal(plan.AmountDue.Amount) {
+			issues = append(issues, issue("payment_plan.installments", CodeInstallmentSumMismatch,
+				"installment amounts (%s) don't match total amount (%s)", s.Format(sum), s.Format(plan.AmountDue)))
+		}
+	}
+
+	for _, element := range pricedElements(itinerary) {
+		path := element.path + ".price.currency"
+		if _, err := s.Currency(element.price.Currency); err != nil {
+			issues = append(issues, issue(path, CodeUnknownCurrency, "%s: %s", element.description, err))
+			continue
+		}
+		if _, err := s.Convert(models.NewMoney(decimal.Zero, element.price.Currency), currency); err != nil {
+			issues = append(issues, issue(path, CodeMissingExchangeRate, "%s: %s", element.description, err))
+		}
+	}
+
+	return issues
+}
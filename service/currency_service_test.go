@@ -0,0 +1,279 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// amountIn returns an amount in a currency
+func amountIn(amount, currency string) models.Money {
+	return models.NewMoney(decimal.RequireFromString(amount), currency)
+}
+
+// readmeCurrencies returns a currency service with the exchange rates of the
+// readme, converting into rupees by default
+func readmeCurrencies() *CurrencyService {
+	rates, err := ParseExchangeRates("EUR=1,USD=1.08,INR=90.12,JPY=160")
+	if err != nil {
+		panic(err)
+	}
+	return NewCurrencyService("INR", rates)
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		amount models.Money
+		want   string
+	}{
+		{amountIn("10.005", "EUR"), "10.01"},
+		{amountIn("10.004", "EUR"), "10"},
+		{amountIn("-10.005", "EUR"), "-10.01"},
+		{amountIn("1234.5", "JPY"), "1235"},
+		{amountIn("1.0005", "KWD"), "1.001"},
+		{amountIn("1.005", "XXX"), "1.01"},
+	}
+
+	currencies := readmeCurrencies()
+	for _, test := range tests {
+		t.Run(test.amount.Amount.String()+" "+test.amount.Currency, func(t *testing.T) {
+			got := currencies.Round(test.amount)
+			if !got.Equal(amountIn(test.want, test.amount.Currency)) {
+				t.Errorf("got %s, want %s", got.Amount, test.want)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  models.Money
+		to      string
+		want    models.Money
+		wantErr error
+	}{
+		{"same currency is only rounded", amountIn("12.345", "EUR"), "EUR", amountIn("12.35", "EUR"), nil},
+		{"from the base", amountIn("500", "EUR"), "INR", amountIn("45060", "INR"), nil},
+		{"into the base", amountIn("108", "USD"), "EUR", amountIn("100", "EUR"), nil},
+		{"between two foreign currencies", amountIn("100", "USD"), "INR", amountIn("8344.44", "INR"), nil},
+		{"to a currency without decimals", amountIn("10.01", "EUR"), "JPY", amountIn("1602", "JPY"), nil},
+		{"no rate for the source", amountIn("10", "GBP"), "EUR", models.Money{}, ErrNoExchangeRate},
+		{"no rate for the target", amountIn("10", "EUR"), "GBP", models.Money{}, ErrNoExchangeRate},
+	}
+
+	currencies := readmeCurrencies()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := currencies.Convert(test.amount, test.to)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("got %s %s, want %s %s", got.Amount, got.Currency, test.want.Amount, test.want.Currency)
+			}
+		})
+	}
+}
+
+func TestSum(t *testing.T) {
+	tests := []struct {
+		name     string
+		currency string
+		amounts  []models.Money
+		want     models.Money
+		wantErr  error
+	}{
+		{"nothing", "EUR", nil, amountIn("0", "EUR"), nil},
+		{"one currency", "INR", []models.Money{amountIn("100.10", "INR"), amountIn("200.20", "INR")}, amountIn("300.30", "INR"), nil},
+		// every amount is rounded after conversion: 1.6 JPY is 2 JPY twice, not 3.2 JPY
+		{"rounded one by one", "JPY", []models.Money{amountIn("0.01", "EUR"), amountIn("0.01", "EUR")}, amountIn("4", "JPY"), nil},
+		{"mixed currencies", "INR", []models.Money{amountIn("500", "EUR"), amountIn("1000", "INR")}, amountIn("46060", "INR"), nil},
+		{"missing rate", "INR", []models.Money{amountIn("1", "GBP")}, models.Money{}, ErrNoExchangeRate},
+	}
+
+	currencies := readmeCurrencies()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := currencies.Sum(test.currency, test.amounts...)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("got %s %s, want %s %s", got.Amount, got.Currency, test.want.Amount, test.want.Currency)
+			}
+		})
+	}
+}
+
+func TestForPlan(t *testing.T) {
+	tests := []struct {
+		name   string
+		rates  map[string]decimal.Decimal
+		amount models.Money
+		want   models.Money
+	}{
+		{"configured rates without stored ones", nil, amountIn("500", "EUR"), amountIn("45060", "INR")},
+		{"stored rates first", map[string]decimal.Decimal{"EUR": decimal.NewFromInt(1), "INR": decimal.NewFromInt(100)},
+			amountIn("500", "EUR"), amountIn("50000", "INR")},
+		// USD is rebased through EUR, which is 2 in the stored table
+		{"missing rates rebased", map[string]decimal.Decimal{"EUR": decimal.NewFromInt(2), "INR": decimal.NewFromInt(200)},
+			amountIn("108", "USD"), amountIn("10000", "INR")},
+		{"invalid stored rates ignored", map[string]decimal.Decimal{"EUR": decimal.Zero},
+			amountIn("500", "EUR"), amountIn("45060", "INR")},
+	}
+
+	currencies := readmeCurrencies()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := currencies.forPlan(models.PaymentPlan{ExchangeRates: test.rates}).Convert(test.amount, "INR")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(test.want) {
+				t.Errorf("got %s, want %s", got.Amount, test.want.Amount)
+			}
+		})
+	}
+}
+
+func TestParseExchangeRates(t *testing.T) {
+	tests := []struct {
+		table   string
+		want    map[string]string
+		wantErr error
+	}{
+		{"", map[string]string{}, nil},
+		{" eur = 1 , usd=1.08,", map[string]string{"EUR": "1", "USD": "1.08"}, nil},
+		{"EUR", nil, ErrInvalidExchangeRates},
+		{"EUR=1,ABC=2", nil, ErrUnknownCurrency},
+		{"EUR=0", nil, ErrInvalidExchangeRates},
+		{"EUR=one", nil, ErrInvalidExchangeRates},
+	}
+
+	for _, test := range tests {
+		t.Run(test.table, func(t *testing.T) {
+			rates, err := ParseExchangeRates(test.table)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got := make(map[string]string, len(rates))
+			for code, rate := range rates {
+				got[code] = rate.String()
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		amount models.Money
+		want   string
+	}{
+		{amountIn("1234.5", "EUR"), "EUR 1,234.50"},
+		{amountIn("-1234567", "JPY"), "JPY -1,234,567"},
+		{amountIn("0.5", "KWD"), "KWD 0.500"},
+		{amountIn("12", ""), "12.00"},
+	}
+
+	currencies := readmeCurrencies()
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			if got := currencies.Format(test.amount); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+// lisbonPlan returns an itinerary whose payment plan owes 46,060 rupees in
+// one installment of 500 euros and one of 1,000 rupees
+func lisbonPlan() *models.Itinerary {
+	due := time.Date(2025, time.May, 1, 0, 0, 0, 0, time.UTC)
+	return &models.Itinerary{
+		PaymentPlan: models.PaymentPlan{
+			AmountDue: amountIn("46060", "INR"),
+			DueDate:   due,
+			Installments: []models.Installment{
+				{InstallmentNumber: 1, Amount: amountIn("500", "EUR"), DueDate: due, Status: "Pending"},
+				{InstallmentNumber: 2, Amount: amountIn("1000", "INR"), DueDate: due, Status: "Pending"},
+			},
+		},
+	}
+}
+
+func TestCurrencyValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(itinerary *models.Itinerary)
+		want   []string
+	}{
+		{"valid", nil, nil},
+		{"unknown currency of the plan", func(it *models.Itinerary) {
+			it.PaymentPlan.AmountDue.Currency = "ABC"
+		}, []string{CodeUnknownCurrency}},
+		{"too many decimals", func(it *models.Itinerary) {
+			it.PaymentPlan.Installments[0].Amount = amountIn("499.995", "EUR")
+		}, []string{CodeInvalidPrecision, CodeInstallmentSumMismatch}},
+		{"installments don't add up", func(it *models.Itinerary) {
+			it.PaymentPlan.AmountDue = amountIn("46000", "INR")
+		}, []string{CodeInstallmentSumMismatch}},
+		{"installment without a rate", func(it *models.Itinerary) {
+			it.PaymentPlan.Installments[0].Amount = amountIn("400", "GBP")
+		}, []string{CodeMissingExchangeRate}},
+		{"stored rate not positive", func(it *models.Itinerary) {
+			it.PaymentPlan.ExchangeRates = map[string]decimal.Decimal{"EUR": decimal.NewFromInt(1), "INR": decimal.NewFromFloat(90.12), "USD": decimal.Zero}
+		}, []string{CodeInvalidExchangeRate}},
+		{"price in an unknown currency", func(it *models.Itinerary) {
+			it.Hotels = []models.Hotel{{Name: "Pestana Palace", Price: &models.Price{Currency: "XYZ", NetCost: decimal.NewFromInt(1)}}}
+		}, []string{CodeUnknownCurrency}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itinerary := lisbonPlan()
+			if test.change != nil {
+				test.change(itinerary)
+			}
+			if got := issueCodes(readmeCurrencies().validate(itinerary)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInstallmentsMustBePositive(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount models.Money
+		want   []string
+	}{
+		{"positive", amountIn("1000", "INR"), nil},
+		{"zero", amountIn("0", "INR"), []string{CodeInvalidAmount}},
+		{"negative", amountIn("-1000", "INR"), []string{CodeInvalidAmount}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itinerary := lisbonPlan()
+			itinerary.PaymentPlan.Installments[1].Amount = test.amount
+			issues := validatePaymentPlan(itinerary)
+			if got := issueCodes(issues); !reflect.DeepEqual(got, test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+			if len(issues) > 0 && issues[0].Path != "payment_plan.installments[1].amount" {
+				t.Errorf("got path %s", issues[0].Path)
+			}
+		})
+	}
+}
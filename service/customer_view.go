@@ -0,0 +1,82 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+)
+
+// CustomerView returns a copy of an itinerary fit for the public share links.
+// The agent's user ID and the status history, with the agents who changed
+// the status, are left out. Travellers keep their id, name and dietary needs
+// and a masked passport number; dates of birth, nationalities, passport
+// expiries and emergency contacts are dropped. The elements lose their
+// price, and the view lists the customer total of each priced element
+// instead, so net costs and markups stay with the agency.
+func (s *ItineraryService) CustomerView(itinerary *models.Itinerary) *models.CustomerItinerary {
+	view := &models.CustomerItinerary{Itinerary: *copyItinerary(itinerary)}
+	view.UserID = ""
+	view.StatusHistory = nil
+
+	elements := pricedElements(&view.Itinerary)
+	totals := customerTotals(&view.Itinerary, s.currencies.forPlan(view.PaymentPlan))
+	for i, element := range elements {
+		view.Prices = append(view.Prices, models.CustomerPrice{
+			Element:       element.path,
+			Description:   element.description,
+			CustomerTotal: totals[i],
+		})
+	}
+	clearPrices(&view.Itinerary)
+
+	for i, traveller := range view.Travellers {
+		view.Travellers[i] = models.Traveller{
+			ID:             traveller.ID,
+			Name:           traveller.Name,
+			PassportNumber: maskPassport(traveller.PassportNumber),
+			DietaryNeeds:   traveller.DietaryNeeds,
+		}
+	}
+	return view
+}
+
+// clearPrices removes the price of every element of an itinerary
+func clearPrices(itinerary *models.Itinerary) {
+	for i := range itinerary.Days {
+		activities := &itinerary.Days[i].Activities
+		for _, slot := range [][]models.Activity{activities.Morning, activities.Afternoon, activities.Evening} {
+			for j := range slot {
+				slot[j].Price = nil
+			}
+		}
+	}
+	for i := range itinerary.Hotels {
+		itinerary.Hotels[i].Price = nil
+	}
+	for i := range itinerary.Flights {
+		itinerary.Flights[i].Price = nil
+	}
+	for i := range itinerary.Transfers {
+		itinerary.Transfers[i].Price = nil
+	}
+}
+
+// customerTotals returns the customer total of every priced element, in the
+// order of pricedElements. The totals are those of the price breakdown; when
+// a price can't be converted into the currency of the payment plan, every
+// total is given in the currency of its own price.
+func customerTotals(itinerary *models.Itinerary, currencies *CurrencyService) []models.Money {
+	if breakdown, err := priceBreakdown(itinerary, currencies); err == nil {
+		totals := make([]models.Money, len(breakdown.Lines))
+		for i, line := range breakdown.Lines {
+			totals[i] = line.CustomerTotal
+		}
+		return totals
+	}
+
+	adults, children := countTravellers(itinerary)
+	elements := pricedElements(itinerary)
+	totals := make([]models.Money, len(elements))
+	for i, element := range elements {
+		totals[i] = priceLine(element.price, adults, children, currencies).Total
+	}
+	return totals
+}
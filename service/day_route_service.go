@@ -0,0 +1,390 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"math"
+	"math/bits"
+	"time"
+)
+
+var (
+	ErrDayNotFound       = errors.New("day not found")
+	ErrTooManyActivities = errors.New("too many movable activities to optimise")
+)
+
+// maxMovableActivities bounds the exact search, it grows as 2^n * n^2
+const maxMovableActivities = 16
+
+// RouteStop is an activity in a day's visiting order. Pinned stops keep their
+// place: they have a fixed time or no coordinates.
+type RouteStop struct {
+	Slot     string `json:"slot"`
+	Name     string `json:"name"`
+	Location string `json:"location"`
+	Pinned   bool   `json:"pinned"`
+}
+
+// RouteProposal compares a day's current visiting order with the shortest one
+type RouteProposal struct {
+	ItineraryID        string      `json:"itinerary_id"`
+	DayNumber          int         `json:"day_number"`
+	Hotel              string      `json:"hotel,omitempty"`
+	CurrentOrder       []RouteStop `json:"current_order"`
+	ProposedOrder      []RouteStop `json:"proposed_order"`
+	CurrentDistanceKm  float64     `json:"current_distance_km"`
+	ProposedDistanceKm float64     `json:"proposed_distance_km"`
+	DistanceSavedKm    float64     `json:"distance_saved_km"`
+	Changed            bool        `json:"changed"`
+
+	order []int
+}
+
+// routeItem is an activity of the day being ordered
+type routeItem struct {
+	slot     string
+	activity models.Activity
+	pinned   bool
+}
+
+// DayRouteService reorders a day's activities to shorten the distance
+// travelled from and back to the hotel
+type DayRouteService struct {
+	itineraries *ItineraryService
+}
+
+// NewDayRouteService creates a new day route service
+func NewDayRouteService(itineraries *ItineraryService) *DayRouteService {
+	return &DayRouteService{
+		itineraries: itineraries,
+	}
+}
+
+// Propose computes the shortest order of a stored itinerary's day without
+// changing anything
+func (s *DayRouteService) Propose(id string, dayNumber int) (*RouteProposal, error) {
+	itinerary, err := s.itineraries.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Optimize(itinerary, dayNumber)
+}
+
+// Apply saves the proposed order of a day. The update goes through the
+// regular validation.
+func (s *DayRouteService) Apply(id string, dayNumber int) (*models.Itinerary, *RouteProposal, error) {
+	itinerary, err := s.itineraries.GetItinerary(id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proposal, err := s.Optimize(itinerary, dayNumber)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !proposal.Changed {
+		return itinerary, proposal, nil
+	}
+
+	days := append([]models.Day{}, itinerary.Days...)
+	for i := range days {
+		if days[i].DayNumber != dayNumber {
+			continue
+		}
+		items := routeItems(days[i])
+		var activities models.Activities
+		for _, n := range proposal.order {
+			item := items[n]
+			switch item.slot {
+			case "morning":
+				activities.Morning = append(activities.Morning, item.activity)
+			case "afternoon":
+				activities.Afternoon = append(activities.Afternoon, item.activity)
+			default:
+				activities.Evening = append(activities.Evening, item.activity)
+			}
+		}
+		days[i].Activities = activities
+	}
+
+	updated, err := s.itineraries.UpdateItinerary(id, &models.UpdateItineraryReq{Days: days})
+	if err != nil {
+		return nil, nil, err
+	}
+	return updated, proposal, nil
+}
+
+// Optimize finds the order of a day's activities with the shortest distance
+// from the hotel, through every activity and back. Activities stay in their
+// time slot, and activities with a fixed time or without coordinates keep
+// their position; the others are permuted with an exact dynamic program over
+// subsets.
+func (s *DayRouteService) Optimize(itinerary *models.Itinerary, dayNumber int) (*RouteProposal, error) {
+	dayIndex := -1
+	for i, day := range itinerary.Days {
+		if day.DayNumber == dayNumber {
+			dayIndex = i
+			break
+		}
+	}
+	if dayIndex < 0 {
+		return nil, fmt.Errorf("%w: day %d", ErrDayNotFound, dayNumber)
+	}
+	day := itinerary.Days[dayIndex]
+
+	items := routeItems(day)
+	var hotel *models.Hotel
+	if h, ok := nightHotel(itinerary, day.Date); ok {
+		hotel = &h
+	}
+
+	var free []int
+	for i, item := range items {
+		if !item.pinned {
+			free = append(free, i)
+		}
+	}
+	if len(free) > maxMovableActivities {
+		return nil, fmt.Errorf("%w: day %d has %d, at most %d are supported", ErrTooManyActivities,
+			dayNumber, len(free), maxMovableActivities)
+	}
+
+	current := make([]int, len(items))
+	for i := range current {
+		current[i] = i
+	}
+	proposed := bestOrder(items, free, hotel)
+
+	proposal := &RouteProposal{
+		ItineraryID:        itinerary.ID,
+		DayNumber:          day.DayNumber,
+		CurrentOrder:       routeStops(items, current),
+		ProposedOrder:      routeStops(items, proposed),
+		CurrentDistanceKm:  roundKm(routeDistance(items, current, hotel)),
+		ProposedDistanceKm: roundKm(routeDistance(items, proposed, hotel)),
+		order:              proposed,
+	}
+	if hotel != nil {
+		proposal.Hotel = hotel.Name
+	}
+	proposal.DistanceSavedKm = roundKm(proposal.CurrentDistanceKm - proposal.ProposedDistanceKm)
+	for i, n := range proposed {
+		if n != current[i] {
+			proposal.Changed = true
+			break
+		}
+	}
+	if proposal.DistanceSavedKm <= 0 {
+		// never propose a different order that saves nothing
+		proposal.ProposedOrder = proposal.CurrentOrder
+		proposal.ProposedDistanceKm = proposal.CurrentDistanceKm
+		proposal.DistanceSavedKm = 0
+		proposal.Changed = false
+		proposal.order = current
+	}
+
+	return proposal, nil
+}
+
+// bestOrder returns the visiting order of the items with the shortest route.
+// Positions of pinned items are kept; the free items fill the other positions
+// of their own slot.
+func bestOrder(items []routeItem, free []int, hotel *models.Hotel) []int {
+	n := len(free)
+	order := make([]int, len(items))
+	for i := range order {
+		order[i] = i
+	}
+	if n < 2 {
+		return order
+	}
+
+	// the free items fill the positions they hold now, in visiting order
+	positions := free
+
+	// chain returns the pinned stops with coordinates between two positions
+	chain := func(afterPos, beforePos int) []*models.Coordinates {
+		var stops []*models.Coordinates
+		for p := afterPos + 1; p < beforePos; p++ {
+			if items[p].pinned && items[p].activity.Coordinates != nil {
+				stops = append(stops, items[p].activity.Coordinates)
+			}
+		}
+		return stops
+	}
+	var hotelAt *models.Coordinates
+	if hotel != nil {
+		hotelAt = hotel.Coordinates
+	}
+
+	// segment is the distance from a point through a chain of pinned stops to
+	// another point; nil points are skipped
+	segment := func(from *models.Coordinates, stops []*models.Coordinates, to *models.Coordinates) float64 {
+		points := append(append([]*models.Coordinates{from}, stops...), to)
+		total := 0.0
+		var last *models.Coordinates
+		for _, p := range points {
+			if p == nil {
+				continue
+			}
+			if last != nil {
+				total += haversineKm(*last, *p)
+			}
+			last = p
+		}
+		return total
+	}
+
+	allowed := func(item, k int) bool {
+		return items[free[item]].slot == items[positions[k]].slot
+	}
+	coords := func(item int) *models.Coordinates {
+		return items[free[item]].activity.Coordinates
+	}
+
+	size := 1 << uint(n)
+	inf := math.Inf(1)
+	cost := make([][]float64, size)
+	prev := make([][]int, size)
+	for mask := range cost {
+		cost[mask] = make([]float64, n)
+		prev[mask] = make([]int, n)
+		for j := range cost[mask] {
+			cost[mask][j] = inf
+			prev[mask][j] = -1
+		}
+	}
+
+	// first free position, coming from the hotel through the pinned stops before it
+	for i := 0; i < n; i++ {
+		if allowed(i, 0) {
+			cost[1<<uint(i)][i] = segment(hotelAt, chain(-1, positions[0]), coords(i))
+		}
+	}
+
+	for mask := 1; mask < size; mask++ {
+		k := bits.OnesCount(uint(mask))
+		if k >= n {
+			continue
+		}
+		stops := chain(positions[k-1], positions[k])
+		for last := 0; last < n; last++ {
+			if cost[mask][last] == inf {
+				continue
+			}
+			for next := 0; next < n; next++ {
+				if mask&(1<<uint(next)) != 0 || !allowed(next, k) {
+					continue
+				}
+				c := cost[mask][last] + segment(coords(last), stops, coords(next))
+				if c < cost[mask|1<<uint(next)][next] {
+					cost[mask|1<<uint(next)][next] = c
+					prev[mask|1<<uint(next)][next] = last
+				}
+			}
+		}
+	}
+
+	// close the loop back to the hotel through the pinned stops after the last free position
+	full := size - 1
+	best, bestCost := -1, inf
+	tail := chain(positions[n-1], len(items))
+	for last := 0; last < n; last++ {
+		if cost[full][last] == inf {
+			continue
+		}
+		if c := cost[full][last] + segment(coords(last), tail, hotelAt); c < bestCost {
+			best, bestCost = last, c
+		}
+	}
+	if best < 0 {
+		return order
+	}
+
+	// walk back through the table to recover the order of the free items
+	mask := full
+	for k := n - 1; k >= 0; k-- {
+		order[positions[k]] = free[best]
+		previous := prev[mask][best]
+		mask &^= 1 << uint(best)
+		best = previous
+	}
+	return order
+}
+
+// routeItems lists the activities of a day in slot order. Activities with a
+// fixed time or without coordinates are pinned.
+func routeItems(day models.Day) []routeItem {
+	var items []routeItem
+	for _, slot := range timeSlots {
+		for _, activity := range slotActivities(day.Activities, slot.name) {
+			items = append(items, routeItem{
+				slot:     slot.name,
+				activity: activity,
+				pinned:   activity.StartTime != nil || activity.EndTime != nil || activity.Coordinates == nil,
+			})
+		}
+	}
+	return items
+}
+
+// routeDistance returns the length of the route from the hotel through the
+// items in the given order and back
+func routeDistance(items []routeItem, order []int, hotel *models.Hotel) float64 {
+	var points []models.Coordinates
+	if hotel != nil && hotel.Coordinates != nil {
+		points = append(points, *hotel.Coordinates)
+	}
+	for _, n := range order {
+		if c := items[n].activity.Coordinates; c != nil {
+			points = append(points, *c)
+		}
+	}
+	if hotel != nil && hotel.Coordinates != nil {
+		points = append(points, *hotel.Coordinates)
+	}
+
+	total := 0.0
+	for i := 1; i < len(points); i++ {
+		total += haversineKm(points[i-1], points[i])
+	}
+	return total
+}
+
+// routeStops describes the items in the given order
+func routeStops(items []routeItem, order []int) []RouteStop {
+	stops := make([]RouteStop, 0, len(order))
+	for _, n := range order {
+		stops = append(stops, RouteStop{
+			Slot:     items[n].slot,
+			Name:     items[n].activity.Name,
+			Location: items[n].activity.Location,
+			Pinned:   items[n].pinned,
+		})
+	}
+	return stops
+}
+
+// nightHotel finds the hotel booked for the night of a date, or the one
+// checked out of that morning on the last day of a stay
+func nightHotel(itinerary *models.Itinerary, date time.Time) (models.Hotel, bool) {
+	date = dateOf(date)
+	for _, hotel := range itinerary.Hotels {
+		if !date.Before(dateOf(hotel.CheckInDate)) && date.Before(dateOf(hotel.CheckOutDate)) {
+			return hotel, true
+		}
+	}
+	for _, hotel := range itinerary.Hotels {
+		if date.Equal(dateOf(hotel.CheckOutDate)) {
+			return hotel, true
+		}
+	}
+	return models.Hotel{}, false
+}
+
+// roundKm rounds a distance to 100 m
+func roundKm(km float64) float64 {
+	return math.Round(km*10) / 10
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// stop returns a route item on the equator at a longitude; pinned items have
+// a fixed start time
+func stop(slot string, longitude float64, pinned bool) routeItem {
+	return routeItem{
+		slot:     slot,
+		activity: models.Activity{Coordinates: &models.Coordinates{Longitude: longitude}},
+		pinned:   pinned,
+	}
+}
+
+// shortestDistance tries every order that keeps pinned items in place and
+// free items in their slot and returns the length of the shortest route
+func shortestDistance(items []routeItem, hotel *models.Hotel) float64 {
+	order := make([]int, len(items))
+	used := make([]bool, len(items))
+	best := math.Inf(1)
+	var try func(position int)
+	try = func(position int) {
+		if position == len(items) {
+			best = math.Min(best, routeDistance(items, order, hotel))
+			return
+		}
+		if items[position].pinned {
+			order[position] = position
+			try(position + 1)
+			return
+		}
+		for i, item := range items {
+			if !used[i] && !item.pinned && item.slot == items[position].slot {
+				used[i], order[position] = true, i
+				try(position + 1)
+				used[i] = false
+			}
+		}
+	}
+	try(0)
+	return best
+}
+
+func TestBestOrder(t *testing.T) {
+	hotel := &models.Hotel{Name: "Hotel One", Coordinates: &models.Coordinates{}}
+	tests := []struct {
+		name  string
+		items []routeItem
+		hotel *models.Hotel
+		want  []int
+	}{
+		{"nothing to move", []routeItem{stop("morning", 3, false), stop("morning", 1, true)}, hotel, []int{0, 1}},
+		{"from a pinned start", []routeItem{
+			stop("morning", 0, true), stop("morning", 3, false), stop("morning", 1, false), stop("morning", 2, false),
+		}, nil, []int{0, 2, 3, 1}},
+		{"pinned stop keeps its position", []routeItem{
+			stop("morning", 4, false), stop("morning", 2, true), stop("morning", 1, false), stop("morning", 3, false),
+		}, hotel, nil},
+		{"activities stay in their slot", []routeItem{
+			stop("morning", 5, false), stop("morning", 1, false),
+			stop("afternoon", 6, false), stop("afternoon", 2, false),
+			stop("evening", 0.5, false),
+		}, hotel, nil},
+		{"pinned stop without coordinates", []routeItem{
+			stop("morning", 2, false), {slot: "morning", pinned: true}, stop("morning", 1, false), stop("morning", 3, false),
+		}, hotel, nil},
+		{"round trip from the hotel", []routeItem{
+			stop("morning", 1, false), stop("morning", -2, false), stop("morning", 3, false),
+			stop("afternoon", -1, false), stop("afternoon", 2, false), stop("afternoon", 4, false), stop("afternoon", -3, false),
+		}, hotel, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var free []int
+			for i, item := range test.items {
+				if !item.pinned {
+					free = append(free, i)
+				}
+			}
+
+			order := bestOrder(test.items, free, test.hotel)
+			if test.want != nil && !reflect.DeepEqual(order, test.want) {
+				t.Errorf("got order %v, want %v", order, test.want)
+			}
+			visited := make([]bool, len(order))
+			for _, n := range order {
+				if visited[n] {
+					t.Fatalf("item %d is visited twice in %v", n, order)
+				}
+				visited[n] = true
+			}
+			for position, n := range order {
+				if test.items[position].pinned && n != position {
+					t.Errorf("pinned item %d moved to position %d", position, n)
+				}
+				if test.items[n].slot != test.items[position].slot {
+					t.Errorf("item %d moved from the %s to the %s", n, test.items[n].slot, test.items[position].slot)
+				}
+			}
+			if got, want := routeDistance(test.items, order, test.hotel), shortestDistance(test.items, test.hotel); math.Abs(got-want) > 1e-9 {
+				t.Errorf("got a route of %.3f km, the shortest is %.3f km", got, want)
+			}
+		})
+	}
+}
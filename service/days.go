@@ -0,0 +1,49 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"time"
+)
+
+// reconcileDays fits a list of days to the trip's date range. Every day is
+// kept under its day number and moved to StartDate plus DayNumber-1, missing
+// day numbers are filled with empty days and days past the end of the trip
+// are dropped.
+func reconcileDays(days []models.Day, startDate, endDate time.Time) []models.Day {
+	if endDate.Before(startDate) {
+		return days
+	}
+
+	byNumber := make(map[int]models.Day, len(days))
+	for _, day := range days {
+		if _, ok := byNumber[day.DayNumber]; !ok {
+			byNumber[day.DayNumber] = day
+		}
+	}
+
+	length := tripLength(startDate, endDate)
+	reconciled := make([]models.Day, 0, length)
+	for n := 1; n <= length; n++ {
+		day, ok := byNumber[n]
+		if !ok {
+			day = emptyDay(n)
+		}
+		day.Date = dateOf(startDate).AddDate(0, 0, n-1)
+		reconciled = append(reconciled, day)
+	}
+	return reconciled
+}
+
+// emptyDay returns a numbered day with nothing planned yet
+func emptyDay(dayNumber int) models.Day {
+	return models.Day{
+		DayNumber: dayNumber,
+		Title:     fmt.Sprintf("Day %d", dayNumber),
+		Activities: models.Activities{
+			Morning:   []models.Activity{},
+			Afternoon: []models.Activity{},
+			Evening:   []models.Activity{},
+		},
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/repository"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// baliDate returns a date in August 2025
+func baliDate(day int) time.Time {
+	return time.Date(2025, time.August, day, 0, 0, 0, 0, time.UTC)
+}
+
+// plannedDay returns a numbered day with a title and a single activity
+func plannedDay(number int, title string) models.Day {
+	return models.Day{
+		DayNumber:  number,
+		Title:      title,
+		Date:       baliDate(number),
+		Activities: models.Activities{Morning: []models.Activity{{Name: title}}},
+	}
+}
+
+func TestReconcileDays(t *testing.T) {
+	tests := []struct {
+		name       string
+		days       []models.Day
+		start, end time.Time
+		want       []string
+	}{
+		{"nothing planned", nil, baliDate(1), baliDate(3),
+			[]string{"1 Day 1 Aug 1", "2 Day 2 Aug 2", "3 Day 3 Aug 3"}},
+		{"gap filled", []models.Day{plannedDay(1, "Ubud"), plannedDay(3, "Uluwatu")}, baliDate(1), baliDate(3),
+			[]string{"1 Ubud Aug 1", "2 Day 2 Aug 2", "3 Uluwatu Aug 3"}},
+		{"days listed out of order", []models.Day{plannedDay(2, "Tegallalang"), plannedDay(1, "Ubud")}, baliDate(1), baliDate(2),
+			[]string{"1 Ubud Aug 1", "2 Tegallalang Aug 2"}},
+		{"later start moves every day", []models.Day{plannedDay(1, "Ubud"), plannedDay(2, "Tegallalang")}, baliDate(10), baliDate(11),
+			[]string{"1 Ubud Aug 10", "2 Tegallalang Aug 11"}},
+		{"shorter trip drops the last days", []models.Day{plannedDay(1, "Ubud"), plannedDay(2, "Tegallalang"), plannedDay(3, "Uluwatu")},
+			baliDate(1), baliDate(2), []string{"1 Ubud Aug 1", "2 Tegallalang Aug 2"}},
+		{"first of two days with the same number wins", []models.Day{plannedDay(1, "Ubud"), plannedDay(1, "Seminyak")}, baliDate(1), baliDate(1),
+			[]string{"1 Ubud Aug 1"}},
+		{"start time ignored", []models.Day{plannedDay(1, "Ubud")}, baliDate(1).Add(15 * time.Hour), baliDate(1),
+			[]string{"1 Ubud Aug 1"}},
+		{"end before start is left to validation", []models.Day{plannedDay(1, "Ubud")}, baliDate(3), baliDate(1),
+			[]string{"1 Ubud Aug 1"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, day := range reconcileDays(test.days, test.start, test.end) {
+				got = append(got, fmt.Sprintf("%d %s %s", day.DayNumber, day.Title, day.Date.Format("Jan 2")))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReconcileDaysKeepsPlans(t *testing.T) {
+	days := []models.Day{plannedDay(1, "Ubud")}
+	reconciled := reconcileDays(days, baliDate(5), baliDate(6))
+
+	if got := reconciled[0].Activities.Morning; len(got) != 1 || got[0].Name != "Ubud" {
+		t.Errorf("got activities %v", got)
+	}
+	if empty := reconciled[1].Activities; empty.Morning == nil || empty.Afternoon == nil || empty.Evening == nil {
+		t.Error("generated day has nil activity slots, they are written as null")
+	}
+	if !days[0].Date.Equal(baliDate(1)) {
+		t.Errorf("the original day was moved to %s", days[0].Date)
+	}
+}
+
+func TestGeneratedDaysThroughTheService(t *testing.T) {
+	itineraries := NewItineraryService(repository.NewInMemoryRepo(), nil)
+	deposit := models.NewMoney(decimal.NewFromInt(800), "USD")
+	created, err := itineraries.CreateItinerary(&models.CreateItineraryReq{
+		UserID:       "agent-bali",
+		Title:        "Bali retreat",
+		Destination:  "Bali",
+		StartDate:    baliDate(1),
+		EndDate:      baliDate(4),
+		Days:         []models.Day{plannedDay(2, "Tegallalang")},
+		GenerateDays: true,
+		PaymentPlan: models.PaymentPlan{
+			AmountDue:    deposit,
+			DueDate:      baliDate(1),
+			Installments: []models.Installment{{InstallmentNumber: 1, Amount: deposit, DueDate: baliDate(1)}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Days) != 4 || created.Days[1].Title != "Tegallalang" {
+		t.Fatalf("got days %+v", created.Days)
+	}
+
+	// new dates without new days shift the planned days
+	start, end := baliDate(20), baliDate(22)
+	updated, err := itineraries.UpdateItinerary(created.ID, &models.UpdateItineraryReq{StartDate: &start, EndDate: &end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated.Days) != 3 || updated.Days[1].Title != "Tegallalang" || !updated.Days[1].Date.Equal(baliDate(21)) {
+		t.Errorf("got days %+v", updated.Days)
+	}
+}
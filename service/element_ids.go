@@ -0,0 +1,98 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+
+	"github.com/google/uuid"
+)
+
+// normalizeElementIDs gives every activity and hotel without an ID a new one,
+// and a new one to any repeating an ID used earlier in the itinerary.
+// Comments point at activities and hotels by ID, so they follow them when
+// they are reordered.
+func normalizeElementIDs(itinerary *models.Itinerary) {
+	seen := make(map[string]bool)
+	assign := func(id *string) {
+		if *id == "" || seen[*id] {
+			*id = uuid.New().String()
+		}
+		seen[*id] = true
+	}
+
+	for i := range itinerary.Days {
+		for _, slot := range timeSlots {
+			activities := slotActivities(itinerary.Days[i].Activities, slot.name)
+			for j := range activities {
+				assign(&activities[j].ID)
+			}
+		}
+	}
+	for i := range itinerary.Hotels {
+		assign(&itinerary.Hotels[i].ID)
+	}
+}
+
+// keepElementIDs gives the activities and hotels of an update sent without
+// an ID the ID of the stored element they match: an activity of the same day
+// with the same name and location, or a hotel with the same name and city.
+// Comments left on them stay attached when a client sends them back without
+// their IDs.
+func keepElementIDs(stored, updated *models.Itinerary) {
+	used := make(map[string]bool)
+	for _, day := range updated.Days {
+		for _, activity := range dayActivities(day) {
+			used[activity.ID] = true
+		}
+	}
+	for _, hotel := range updated.Hotels {
+		used[hotel.ID] = true
+	}
+
+	for i := range updated.Days {
+		var candidates []models.Activity
+		for _, day := range stored.Days {
+			if day.DayNumber == updated.Days[i].DayNumber {
+				candidates = append(candidates, dayActivities(day)...)
+			}
+		}
+		for _, slot := range timeSlots {
+			activities := slotActivities(updated.Days[i].Activities, slot.name)
+			for j := range activities {
+				activity := &activities[j]
+				if activity.ID != "" {
+					continue
+				}
+				for _, candidate := range candidates {
+					if candidate.ID != "" && !used[candidate.ID] && candidate.Name == activity.Name && candidate.Location == activity.Location {
+						activity.ID = candidate.ID
+						used[candidate.ID] = true
+						break
+					}
+				}
+			}
+		}
+	}
+
+	for i := range updated.Hotels {
+		hotel := &updated.Hotels[i]
+		if hotel.ID != "" {
+			continue
+		}
+		for _, candidate := range stored.Hotels {
+			if candidate.ID != "" && !used[candidate.ID] && candidate.Name == hotel.Name && candidate.City == hotel.City {
+				hotel.ID = candidate.ID
+				used[candidate.ID] = true
+				break
+			}
+		}
+	}
+}
+
+// dayActivities returns the activities of a day, slot by slot
+func dayActivities(day models.Day) []models.Activity {
+	var activities []models.Activity
+	for _, slot := range timeSlots {
+		activities = append(activities, slotActivities(day.Activities, slot.name)...)
+	}
+	return activities
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// feasibility issue codes
+const (
+	CodeInvalidTravelMode   = "invalid_travel_mode"
+	CodeActivityUnreachable = "activity_unreachable"
+)
+
+// travel modes between activities
+const (
+	TravelWalk    = "walk"
+	TravelBike    = "bike"
+	TravelTransit = "transit"
+	TravelCar     = "car"
+	TravelTaxi    = "taxi"
+	TravelTrain   = "train"
+	TravelFerry   = "ferry"
+)
+
+// TravelSpeed models a transport mode as an average door to door speed plus
+// a fixed overhead for waiting, parking or boarding
+type TravelSpeed struct {
+	KmPerHour float64
+	Overhead  time.Duration
+}
+
+// travelSpeeds is the speed model per transport mode
+var travelSpeeds = map[string]TravelSpeed{
+	TravelWalk:    {4.5, 0},
+	TravelBike:    {14, 2 * time.Minute},
+	TravelTransit: {20, 10 * time.Minute},
+	TravelCar:     {30, 5 * time.Minute},
+	TravelTaxi:    {30, 5 * time.Minute},
+	TravelTrain:   {80, 15 * time.Minute},
+	TravelFerry:   {25, 20 * time.Minute},
+}
+
+const (
+	// walkingDistanceKm is the longest leg assumed to be walked when no
+	// travel mode is given, longer ones are assumed to be driven
+	walkingDistanceKm = 1.5
+
+	// detourFactor converts the straight line distance into a road distance
+	detourFactor = 1.3
+
+	// earthRadiusKm is the mean radius of the Earth
+	earthRadiusKm = 6371.0
+)
+
+// TravelLeg is the trip between two consecutive activities of a day
+type TravelLeg struct {
+	From             string  `json:"from"`
+	To               string  `json:"to"`
+	DistanceKm       float64 `json:"distance_km"`
+	Mode             string  `json:"mode"`
+	TravelMinutes    int     `json:"travel_minutes"`
+	AvailableMinutes int     `json:"available_minutes"`
+	Feasible         bool    `json:"feasible"`
+
+	// one of the activities has no time of its own, the time available is a
+	// guess and the leg is not checked
+	Estimated bool `json:"estimated"`
+}
+
+// FeasibilityDay lists the legs of a single day
+type FeasibilityDay struct {
+	DayNumber int         `json:"day_number"`
+	Date      time.Time   `json:"date"`
+	Legs      []TravelLeg `json:"legs"`
+	Feasible  bool        `json:"feasible"`
+}
+
+// FeasibilityReport tells whether every activity can be reached in time
+type FeasibilityReport struct {
+	Days   []FeasibilityDay  `json:"days"`
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// FeasibilityService checks that consecutive activities can physically be
+// reached in the time between them
+type FeasibilityService struct {
+	schedule *ScheduleService
+}
+
+// NewFeasibilityService creates a new feasibility service
+func NewFeasibilityService() *FeasibilityService {
+	return &FeasibilityService{
+		schedule: NewScheduleService(),
+	}
+}
+
+// Analyze walks every day in time order and estimates the travel time between
+// consecutive activities that both have coordinates. Only legs between two
+// activities with a start or end time are checked; activities placed back to
+// back from the usual start of their slot would give false alarms.
+func (s *FeasibilityService) Analyze(itinerary *models.Itinerary) *FeasibilityReport {
+	report := &FeasibilityReport{
+		Days:   []FeasibilityDay{},
+		Issues: []ValidationIssue{},
+	}
+
+	for i, day := range itinerary.Days {
+		feasibility := FeasibilityDay{DayNumber: day.DayNumber, Date: day.Date, Legs: []TravelLeg{}, Feasible: true}
+
+		entries := s.schedule.schedule(i, day)
+		sort.SliceStable(entries, func(a, b int) bool { return entries[a].Start < entries[b].Start })
+
+		for n := 1; n < len(entries); n++ {
+			from, to := entries[n-1], entries[n]
+			if from.activity.Coordinates == nil || to.activity.Coordinates == nil {
+				continue
+			}
+
+			distance := haversineKm(*from.activity.Coordinates, *to.activity.Coordinates)
+			mode := travelMode(to.activity.TravelMode, distance)
+			leg := TravelLeg{
+				From:             from.Name,
+				To:               to.Name,
+				DistanceKm:       math.Round(distance*10) / 10,
+				Mode:             mode,
+				TravelMinutes:    minutes(travelTime(distance, mode)),
+				AvailableMinutes: int(to.Start - from.End),
+				Estimated:        !from.fixed || !to.fixed,
+			}
+			leg.Feasible = leg.Estimated || leg.TravelMinutes <= leg.AvailableMinutes
+
+			if !leg.Feasible {
+				feasibility.Feasible = false
+				report.Issues = append(report.Issues, warning(to.path, CodeActivityUnreachable,
+					"day %d: %s is %.1f km from %s, about %s by %s, but only %s is available", day.DayNumber,
+					to.Name, leg.DistanceKm, from.Name, formatDuration(time.Duration(leg.TravelMinutes)*time.Minute),
+					mode, formatDuration(time.Duration(leg.AvailableMinutes)*time.Minute)))
+			}
+			feasibility.Legs = append(feasibility.Legs, leg)
+		}
+
+		report.Days = append(report.Days, feasibility)
+	}
+
+	return report
+}
+
+// ValidationRule rejects unknown travel modes and warns about activities that
+// can't be reached in time
+func (s *FeasibilityService) ValidationRule() ValidationRule {
+	return func(itinerary *models.Itinerary) []ValidationIssue {
+		var issues []ValidationIssue
+		for i, day := range itinerary.Days {
+			for _, slot := range timeSlots {
+				for j, activity := range slotActivities(day.Activities, slot.name) {
+					if _, ok := travelSpeeds[strings.ToLower(activity.TravelMode)]; activity.TravelMode != "" && !ok {
+						issues = append(issues, issue(fmt.Sprintf("days[%d].activities.%s[%d].travel_mode", i, slot.name, j),
+							CodeInvalidTravelMode, "unknown travel mode %q, expected one of %s", activity.TravelMode, travelModeNames()))
+					}
+				}
+			}
+		}
+		return append(issues, s.Analyze(itinerary).Issues...)
+	}
+}
+
+// travelMode returns the requested mode, or walking for short legs and
+// driving for longer ones
+func travelMode(requested string, distanceKm float64) string {
+	if _, ok := travelSpeeds[strings.ToLower(requested)]; ok {
+		return strings.ToLower(requested)
+	}
+	if distanceKm <= walkingDistanceKm {
+		return TravelWalk
+	}
+	return TravelCar
+}
+
+// travelTime estimates the door to door time of a leg
+func travelTime(distanceKm float64, mode string) time.Duration {
+	speed := travelSpeeds[mode]
+	hours := distanceKm * detourFactor / speed.KmPerHour
+	return speed.Overhead + time.Duration(hours*float64(time.Hour))
+}
+
+// travelModeNames lists the known travel modes for error messages
+func travelModeNames() string {
+	names := make([]string, 0, len(travelSpeeds))
+	for name := range travelSpeeds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// haversineKm returns the great circle distance between two points
+func haversineKm(a, b models.Coordinates) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
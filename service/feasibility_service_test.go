@@ -0,0 +1,210 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// landmarks used by the feasibility tests, in Rome
+var (
+	colosseum   = &models.Coordinates{Latitude: 41.8902, Longitude: 12.4922}
+	forum       = &models.Coordinates{Latitude: 41.8925, Longitude: 12.4853}
+	vatican     = &models.Coordinates{Latitude: 41.9065, Longitude: 12.4536}
+	tivoliVilla = &models.Coordinates{Latitude: 41.9632, Longitude: 12.7961}
+)
+
+// romeVisit returns a timed activity at a landmark
+func romeVisit(name string, at *models.Coordinates, start, end *models.TimeOfDay) models.Activity {
+	return models.Activity{Name: name, Location: name + ", Rome", Coordinates: at, StartTime: start, EndTime: end}
+}
+
+func TestHaversineKm(t *testing.T) {
+	paris := models.Coordinates{Latitude: 48.8566, Longitude: 2.3522}
+	london := models.Coordinates{Latitude: 51.5074, Longitude: -0.1278}
+
+	if got := haversineKm(paris, london); math.Abs(got-343.5) > 1 {
+		t.Errorf("got %.1f km from Paris to London, want about 343.5", got)
+	}
+	if got := haversineKm(paris, paris); got != 0 {
+		t.Errorf("got %.1f km from Paris to itself", got)
+	}
+}
+
+func TestTravelMode(t *testing.T) {
+	tests := []struct {
+		requested string
+		distance  float64
+		want      string
+	}{
+		{"", 0.8, TravelWalk},
+		{"", 1.5, TravelWalk},
+		{"", 1.6, TravelCar},
+		{"Train", 0.5, TravelTrain},
+		{"hovercraft", 10, TravelCar},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%q %.1f km", test.requested, test.distance), func(t *testing.T) {
+			if got := travelMode(test.requested, test.distance); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFeasibilityAnalyze(t *testing.T) {
+	tests := []struct {
+		name         string
+		activities   models.Activities
+		wantLegs     []string
+		wantFeasible bool
+	}{
+		{"short walk with time to spare", models.Activities{Morning: []models.Activity{
+			romeVisit("Colosseum", colosseum, clock(9, 0), clock(10, 30)),
+			romeVisit("Roman Forum", forum, clock(10, 45), clock(11, 45)),
+		}}, []string{"Colosseum-Roman Forum walk feasible"}, true},
+		{"across town with no time", models.Activities{Morning: []models.Activity{
+			romeVisit("Colosseum", colosseum, clock(9, 0), clock(10, 30)),
+			romeVisit("Vatican Museums", vatican, clock(10, 35), clock(11, 55)),
+		}}, []string{"Colosseum-Vatican Museums car unreachable"}, false},
+		{"by train as requested", models.Activities{Afternoon: []models.Activity{
+			romeVisit("Colosseum", colosseum, clock(12, 0), clock(13, 0)),
+			func() models.Activity {
+				visit := romeVisit("Villa d'Este", tivoliVilla, clock(14, 0), clock(16, 0))
+				visit.TravelMode = "train"
+				return visit
+			}(),
+		}}, []string{"Colosseum-Villa d'Este train feasible"}, true},
+		// the second visit has no time of its own, so the gap is a guess
+		{"estimated times are not checked", models.Activities{Morning: []models.Activity{
+			romeVisit("Colosseum", colosseum, clock(9, 0), clock(10, 30)),
+			romeVisit("Vatican Museums", vatican, nil, nil),
+		}}, []string{"Colosseum-Vatican Museums car estimated"}, true},
+		{"activity without coordinates is skipped", models.Activities{Morning: []models.Activity{
+			romeVisit("Colosseum", colosseum, clock(9, 0), clock(10, 30)),
+			romeVisit("Gelato stop", nil, clock(10, 35), clock(10, 50)),
+		}}, nil, true},
+	}
+
+	feasibility := NewFeasibilityService()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := feasibility.Analyze(&models.Itinerary{Days: []models.Day{{DayNumber: 1, Activities: test.activities}}})
+
+			day := report.Days[0]
+			var legs []string
+			for _, leg := range day.Legs {
+				state := "feasible"
+				switch {
+				case leg.Estimated:
+					state = "estimated"
+				case !leg.Feasible:
+					state = "unreachable"
+				}
+				legs = append(legs, fmt.Sprintf("%s-%s %s %s", leg.From, leg.To, leg.Mode, state))
+			}
+			if fmt.Sprint(legs) != fmt.Sprint(test.wantLegs) {
+				t.Errorf("got legs %v, want %v", legs, test.wantLegs)
+			}
+			if day.Feasible != test.wantFeasible || (len(report.Issues) == 0) != test.wantFeasible {
+				t.Errorf("got feasible %v with issues %v, want feasible %v", day.Feasible, report.Issues, test.wantFeasible)
+			}
+		})
+	}
+}
+
+func TestFeasibilityRejectsUnknownTravelMode(t *testing.T) {
+	visit := romeVisit("Colosseum", colosseum, nil, nil)
+	visit.TravelMode = "hovercraft"
+
+	issues := NewFeasibilityService().ValidationRule()(&models.Itinerary{Days: []models.Day{{
+		DayNumber:  1,
+		Activities: models.Activities{Evening: []models.Activity{visit}},
+	}}})
+	if len(issues) != 1 || issues[0].Code != CodeInvalidTravelMode || issues[0].Path != "days[0].activities.evening[0].travel_mode" {
+		t.Errorf("got %v", issues)
+	}
+}
+
+// countingGeocoder answers from a fixed table and counts the questions
+type countingGeocoder struct {
+	places map[string]models.Coordinates
+	err    error
+	asked  int
+}
+
+func (g *countingGeocoder) Geocode(query string) (models.Coordinates, error) {
+	g.asked++
+	if g.err != nil {
+		return models.Coordinates{}, g.err
+	}
+	if coordinates, ok := g.places[query]; ok {
+		return coordinates, nil
+	}
+	return models.Coordinates{}, ErrLocationNotFound
+}
+
+func TestCachingGeocoder(t *testing.T) {
+	remote := &countingGeocoder{places: map[string]models.Coordinates{"Trevi Fountain": {Latitude: 41.9009, Longitude: 12.4833}}}
+	geocoder := NewCachingGeocoder(remote)
+
+	for _, query := range []string{"Trevi Fountain", "trevi fountain ", "Atlantis", "Atlantis"} {
+		geocoder.Geocode(query)
+	}
+	if got, err := geocoder.Geocode("TREVI FOUNTAIN"); err != nil || got.Latitude != 41.9009 {
+		t.Errorf("got %v, %v from the cache", got, err)
+	}
+	if _, err := geocoder.Geocode("Atlantis"); !errors.Is(err, ErrLocationNotFound) {
+		t.Errorf("got error %v, want %v", err, ErrLocationNotFound)
+	}
+	if remote.asked != 2 {
+		t.Errorf("asked the remote geocoder %d times, want once per place", remote.asked)
+	}
+
+	// other failures are remembered for a while too, not forever
+	failing := &countingGeocoder{err: errors.New("503 Service Unavailable")}
+	geocoder = NewCachingGeocoder(failing)
+	geocoder.Geocode("Pantheon")
+	geocoder.Geocode("Pantheon")
+	if failing.asked != 1 {
+		t.Errorf("asked the failing geocoder %d times, want once", failing.asked)
+	}
+	cached, _ := geocoder.cache.Load("pantheon")
+	if cached.(cachedLocation).expires.IsZero() {
+		t.Error("the failure never expires")
+	}
+}
+
+func TestGeocodeLocations(t *testing.T) {
+	geocoder := GeocoderChain{
+		&countingGeocoder{places: map[string]models.Coordinates{"Colosseum, Rome": *colosseum}},
+		NewGazetteerGeocoder(),
+	}
+	itinerary := &models.Itinerary{
+		Days: []models.Day{{DayNumber: 1, Activities: models.Activities{Morning: []models.Activity{
+			{Name: "Colosseum", Location: "Colosseum, Rome"},
+			{Name: "Vatican", Location: "Vatican Museums", Coordinates: vatican},
+			{Name: "Somewhere", Location: "Nowhere in particular"},
+		}}}},
+		Hotels: []models.Hotel{{Name: "Hotel Artemide", City: "Rome", Address: "Via Nazionale 22"}},
+	}
+	geocodeLocations(geocoder, itinerary)
+
+	morning := itinerary.Days[0].Activities.Morning
+	if morning[0].Coordinates == nil || *morning[0].Coordinates != *colosseum {
+		t.Errorf("got %v for the Colosseum", morning[0].Coordinates)
+	}
+	if morning[1].Coordinates != vatican {
+		t.Error("replaced the coordinates that were given")
+	}
+	if morning[2].Coordinates != nil {
+		t.Errorf("got %v for an unknown place", morning[2].Coordinates)
+	}
+	// the address is unknown, the hotel is found by its city
+	if itinerary.Hotels[0].Coordinates == nil {
+		t.Error("hotel left without coordinates")
+	}
+}
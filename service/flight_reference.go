@@ -0,0 +1,91 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/reference"
+	"fmt"
+)
+
+// flight reference issue codes
+const (
+	CodeInvalidFlightNumber   = "invalid_flight_number"
+	CodeFlightAirlineMismatch = "flight_airline_mismatch"
+	CodeUnknownAirport        = "unknown_airport"
+	CodeUnknownAirline        = "unknown_airline"
+)
+
+// validateFlightReferences checks flights against the airport and airline
+// reference data. A malformed flight number or one that does not carry the
+// airline's designator blocks a save; codes missing from the dataset are only
+// warnings since it does not list every airport in the world.
+func validateFlightReferences(itinerary *models.Itinerary) []ValidationIssue {
+	catalog := reference.Default()
+	var issues []ValidationIssue
+
+	for i, flight := range itinerary.Flights {
+		path := fmt.Sprintf("flights[%d]", i)
+
+		designator, _, ok := reference.ParseFlightNumber(flight.FlightNumber)
+		if !ok {
+			issues = append(issues, issue(path+".flight_number", CodeInvalidFlightNumber,
+				"%q is not a valid flight number, expected an airline designator followed by up to 4 digits", flight.FlightNumber))
+		}
+
+		airline, known := catalog.Airline(flight.Airline)
+		switch {
+		case !known:
+			issues = append(issues, warning(path+".airline", CodeUnknownAirline,
+				"airline %q is not in the reference data", flight.Airline))
+		case ok && designator != airline.IATA:
+			issues = append(issues, issue(path+".flight_number", CodeFlightAirlineMismatch,
+				"flight %s does not match %s whose designator is %s", flight.FlightNumber, airline.Name, airline.IATA))
+		}
+
+		if _, ok := catalog.Airport(flight.From); !ok {
+			issues = append(issues, warning(path+".from", CodeUnknownAirport,
+				"airport %q is not in the reference data", flight.From))
+		}
+		if _, ok := catalog.Airport(flight.To); !ok {
+			issues = append(issues, warning(path+".to", CodeUnknownAirport,
+				"airport %q is not in the reference data", flight.To))
+		}
+	}
+
+	return issues
+}
+
+// applyAirportTimeZones fills in missing flight time zones from the airports
+func applyAirportTimeZones(itinerary *models.Itinerary) {
+	catalog := reference.Default()
+	for i := range itinerary.Flights {
+		flight := &itinerary.Flights[i]
+		if flight.DepartureTimeZone == "" {
+			if airport, ok := catalog.Airport(flight.From); ok {
+				flight.DepartureTimeZone = airport.TimeZone
+			}
+		}
+		if flight.ArrivalTimeZone == "" {
+			if airport, ok := catalog.Airport(flight.To); ok {
+				flight.ArrivalTimeZone = airport.TimeZone
+			}
+		}
+	}
+}
+
+// airportName resolves an airport code to its display name, unknown values
+// are shown as entered
+func airportName(text string) string {
+	if airport, ok := reference.Default().Airport(text); ok {
+		return airport.DisplayName()
+	}
+	return text
+}
+
+// airlineName resolves an airline code to its name, unknown values are shown
+// as entered
+func airlineName(text string) string {
+	if airline, ok := reference.Default().Airline(text); ok {
+		return airline.Name
+	}
+	return text
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/reference"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLocationNotFound = errors.New("location not found")
+	ErrNoRemoteGeocoder = errors.New("no online geocoder is configured")
+)
+
+const (
+	// minRequestInterval spaces out the requests to an online geocoder, public
+	// Nominatim allows one request per second
+	minRequestInterval = time.Second
+
+	// failureRetryAfter is how long an online geocoder failure is remembered
+	// before the location is asked again
+	failureRetryAfter = 10 * time.Minute
+)
+
+// Geocoder resolves a free text location into coordinates. It returns
+// ErrLocationNotFound when the location is unknown.
+type Geocoder interface {
+	Geocode(query string) (models.Coordinates, error)
+}
+
+// GazetteerGeocoder resolves locations offline from the landmarks and city
+// centres of the reference data
+type GazetteerGeocoder struct {
+	catalog *reference.Catalog
+}
+
+// NewGazetteerGeocoder creates a geocoder backed by the embedded gazetteer
+func NewGazetteerGeocoder() *GazetteerGeocoder {
+	return &GazetteerGeocoder{
+		catalog: reference.Default(),
+	}
+}
+
+// Geocode finds the landmark or city named in the query
+func (g *GazetteerGeocoder) Geocode(query string) (models.Coordinates, error) {
+	place, ok := g.catalog.Place(query)
+	if !ok {
+		return models.Coordinates{}, fmt.Errorf("%w: %q", ErrLocationNotFound, query)
+	}
+	return models.Coordinates{Latitude: place.Latitude, Longitude: place.Longitude}, nil
+}
+
+// HTTPGeocoder queries a Nominatim compatible search API, at most one
+// request per minRequestInterval
+type HTTPGeocoder struct {
+	baseURL   string
+	userAgent string
+	client    *http.Client
+
+	mu          sync.Mutex
+	lastRequest time.Time
+}
+
+// NewHTTPGeocoder creates a geocoder calling baseURL/search
+func NewHTTPGeocoder(baseURL, userAgent string) *HTTPGeocoder {
+	return &HTTPGeocoder{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		userAgent: userAgent,
+		client:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Geocode returns the first search result for the query
+func (g *HTTPGeocoder) Geocode(query string) (models.Coordinates, error) {
+	g.mu.Lock()
+	if wait := minRequestInterval - time.Since(g.lastRequest); wait > 0 {
+		time.Sleep(wait)
+	}
+	g.lastRequest = time.Now()
+	g.mu.Unlock()
+
+	req, err := http.NewRequest(http.MethodGet, g.baseURL+"/search?format=json&limit=1&q="+url.QueryEscape(query), nil)
+	if err != nil {
+		return models.Coordinates{}, err
+	}
+	req.Header.Set("User-Agent", g.userAgent)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return models.Coordinates{}, fmt.Errorf("geocoding %q: %w", query, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return models.Coordinates{}, fmt.Errorf("geocoding %q: unexpected status %s", query, resp.Status)
+	}
+
+	var results []struct {
+		Lat string `json:"lat"`
+		Lon string `json:"lon"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return models.Coordinates{}, fmt.Errorf("geocoding %q: %w", query, err)
+	}
+	if len(results) == 0 {
+		return models.Coordinates{}, fmt.Errorf("%w: %q", ErrLocationNotFound, query)
+	}
+
+	lat, err := strconv.ParseFloat(results[0].Lat, 64)
+	if err != nil {
+		return models.Coordinates{}, fmt.Errorf("geocoding %q: %w", query, err)
+	}
+	lon, err := strconv.ParseFloat(results[0].Lon, 64)
+	if err != nil {
+		return models.Coordinates{}, fmt.Errorf("geocoding %q: %w", query, err)
+	}
+	return models.Coordinates{Latitude: lat, Longitude: lon}, nil
+}
+
+// CachingGeocoder remembers the answers of another geocoder, including the
+// locations it could not find
+type CachingGeocoder struct {
+	next  Geocoder
+	cache sync.Map
+}
+
+// cachedLocation is a remembered geocoding answer, failures expire
+type cachedLocation struct {
+	coordinates models.Coordinates
+	err         error
+	expires     time.Time
+}
+
+// NewCachingGeocoder wraps a geocoder with an in-memory cache
+func NewCachingGeocoder(next Geocoder) *CachingGeocoder {
+	return &CachingGeocoder{
+		next: next,
+	}
+}
+
+// Geocode answers from the cache or asks the wrapped geocoder. Failures other
+// than an unknown location are remembered for failureRetryAfter and then
+// retried.
+func (g *CachingGeocoder) Geocode(query string) (models.Coordinates, error) {
+	key := strings.ToLower(strings.TrimSpace(query))
+	if cached, ok := g.cache.Load(key); ok {
+		found := cached.(cachedLocation)
+		if found.expires.IsZero() || time.Now().Before(found.expires) {
+			return found.coordinates, found.err
+		}
+	}
+
+	coordinates, err := g.next.Geocode(query)
+	found := cachedLocation{coordinates: coordinates, err: err}
+	if err != nil && !errors.Is(err, ErrLocationNotFound) {
+		found.expires = time.Now().Add(failureRetryAfter)
+	}
+	g.cache.Store(key, found)
+	return coordinates, err
+}
+
+// GeocoderChain tries each geocoder in turn until one finds the location
+type GeocoderChain []Geocoder
+
+// Geocode returns the first answer found, or the last error
+func (chain GeocoderChain) Geocode(query string) (models.Coordinates, error) {
+	err := fmt.Errorf("%w: %q", ErrLocationNotFound, query)
+	for _, geocoder := range chain {
+		var coordinates models.Coordinates
+		coordinates, err = geocoder.Geocode(query)
+		if err == nil {
+			return coordinates, nil
+		}
+	}
+	return models.Coordinates{}, err
+}
+
+// geocodeLocations fills in the missing coordinates of activities and hotels.
+// Locations that can't be resolved are left without coordinates and are
+// skipped by the checks that need them.
+func geocodeLocations(geocoder Geocoder, itinerary *models.Itinerary) {
+	if geocoder == nil {
+		return
+	}
+	resolve := func(queries ...string) *models.Coordinates {
+		for _, query := range queries {
+			if strings.TrimSpace(query) == "" {
+				continue
+			}
+			coordinates, err := geocoder.Geocode(query)
+			if err == nil {
+				return &coordinates
+			}
+			if !errors.Is(err, ErrLocationNotFound) {
+				log.Printf("geocoding failed: %v", err)
+			}
+		}
+		return nil
+	}
+
+	for i := range itinerary.Hotels {
+		hotel := &itinerary.Hotels[i]
+		if hotel.Coordinates == nil {
+			hotel.Coordinates = resolve(hotel.Address, hotel.Name+", "+hotel.City)
+		}
+	}
+	for i := range itinerary.Days {
+		activities := &itinerary.Days[i].Activities
+		for _, slot := range [][]models.Activity{activities.Morning, activities.Afternoon, activities.Evening} {
+			for j := range slot {
+				if slot[j].Coordinates == nil {
+					slot[j].Coordinates = resolve(slot[j].Location)
+				}
+			}
+		}
+	}
+}
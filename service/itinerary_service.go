@@ -4,70 +4,153 @@ import (
 	"errors"
 	"example/vigovia-itenary-api/models"
 	"example/vigovia-itenary-api/repository"
+	"strings"
 	"time"
 	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 	"fmt"
 )	
 
 var (
-	ErrInvalidDateRange = errors.New("end date must be after start date")
-	ErrInvalidDays      = errors.New("number of days doesn't match date range")
+	ErrInvalidDateRange  = errors.New("end date must be after start date")
+	ErrInvalidDays       = errors.New("number of days doesn't match date range")
+	ErrItineraryNotFound = errors.New("itinerary not found")
 )
 
 // ItineraryService handles business logic for itineraries
 type ItineraryService struct {
-	repo repository.ItineraryRepository
+	repo        repository.ItineraryRepository
+	validator   *Validator
+	coverage    *CoverageService
+	schedule    *ScheduleService
+	feasibility *FeasibilityService
+	geocoder    Geocoder
+	remote      Geocoder
+	currencies  *CurrencyService
+
+	// called with the ID of every deleted itinerary
+	onDelete []func(id string) error
 }
 
-// NewItineraryService creates a new itinerary service
-func NewItineraryService(repo repository.ItineraryRepository) *ItineraryService {
-	return &ItineraryService{
-		repo: repo,
+// NewItineraryService creates a new itinerary service checking connections
+// with the journey service, or with the default minimum connection time
+func NewItineraryService(repo repository.ItineraryRepository, journeys *JourneyService) *ItineraryService {
+	if journeys == nil {
+		journeys = NewJourneyService(DefaultMinConnectionTime)
 	}
+	s := &ItineraryService{
+		repo:        repo,
+		validator:   NewValidator(DefaultValidationRules(journeys)...),
+		coverage:    NewCoverageService(),
+		schedule:    NewScheduleService(),
+		feasibility: NewFeasibilityService(),
+		geocoder:    NewCachingGeocoder(NewGazetteerGeocoder()),
+		currencies:  NewCurrencyService(DefaultCurrency, nil),
+	}
+	s.validator.AddRules(s.validateCurrencies)
+	return s
+}
+
+// AddValidationRules registers extra rules checked on create and update
+func (s *ItineraryService) AddValidationRules(rules ...ValidationRule) {
+	s.validator.AddRules(rules...)
+}
+
+// SetGeocoder replaces the geocoder used to fill in missing coordinates on
+// save. It runs in the request, so it should answer without the network.
+func (s *ItineraryService) SetGeocoder(geocoder Geocoder) {
+	s.geocoder = geocoder
+}
+
+// SetRemoteGeocoder sets the online geocoder used by GeocodeItinerary. It is
+// never used on save, so a slow or failing provider can't hold up edits.
+func (s *ItineraryService) SetRemoteGeocoder(geocoder Geocoder) {
+	s.remote = geocoder
+}
+
+// OnDelete registers a hook removing what belongs to an itinerary when it is
+// deleted
+func (s *ItineraryService) OnDelete(hook func(id string) error) {
+	s.onDelete = append(s.onDelete, hook)
+}
+
+// SetCurrencies replaces the currency service used to round and convert amounts
+func (s *ItineraryService) SetCurrencies(currencies *CurrencyService) {
+	s.currencies = currencies
+}
+
+// Currencies returns the currency service used to round and convert amounts
+func (s *ItineraryService) Currencies() *CurrencyService {
+	return s.currencies
+}
+
+// validateCurrencies checks the amounts with the current currency service
+// and the exchange rates stored with the itinerary
+func (s *ItineraryService) validateCurrencies(itinerary *models.Itinerary) []ValidationIssue {
+	return s.currencies.forPlan(itinerary.PaymentPlan).validate(itinerary)
 }
 
 // CreateItinerary creates a new itinerary
 func (s *ItineraryService) CreateItinerary(req *models.CreateItineraryReq) (*models.Itinerary, error) {
-	// validates date range
-	if req.EndDate.Before(req.StartDate) || req.EndDate.Equal(req.StartDate) {
-		return nil, ErrInvalidDateRange
-	}
+	// Create itinerary
+	itinerary := s.buildItinerary(req)
+	s.normalize(itinerary)
 
-	// Validate days
-	if err := s.validateDays(req.Days, req.StartDate, req.EndDate); err != nil {
+	// Validate every rule at once
+	if err := s.validator.Check(itinerary); err != nil {
 		return nil, err
 	}
 
-	// Validate payment plan
-	if err := s.validatePaymentPlan(&req.PaymentPlan); err != nil {
-		return nil, err
+	if err := s.repo.Create(itinerary); err != nil {
+		return nil, fmt.Errorf("failed to create itinerary: %w", err)
+	}
+
+	return itinerary, nil
+}
+
+// ValidateItinerary runs every rule against a create request without saving it
+func (s *ItineraryService) ValidateItinerary(req *models.CreateItineraryReq) *ValidationReport {
+	itinerary := s.buildItinerary(req)
+	s.normalize(itinerary)
+	return s.validator.Report(itinerary)
+}
+
+// Validate runs every rule against an itinerary and returns the full report
+func (s *ItineraryService) Validate(itinerary *models.Itinerary) *ValidationReport {
+	return s.validator.Report(itinerary)
+}
+
+// buildItinerary creates a new draft itinerary from a create request. With
+// GenerateDays, the days missing from the request are generated empty from
+// the date range.
+func (s *ItineraryService) buildItinerary(req *models.CreateItineraryReq) *models.Itinerary {
+	days := req.Days
+	if req.GenerateDays {
+		days = reconcileDays(req.Days, req.StartDate, req.EndDate)
 	}
 
-	// Create itinerary
 	now := time.Now()
-	itinerary := &models.Itinerary{
+	return &models.Itinerary{
 		ID:          uuid.New().String(),
 		UserID:      req.UserID,
+		CustomerName: req.CustomerName,
 		Title:       req.Title,
 		Destination: req.Destination,
 		StartDate:   req.StartDate,
 		EndDate:     req.EndDate,
-		Days:        req.Days,
+		Days:        days,
 		Hotels:      req.Hotels,
 		Flights:     req.Flights,
 		Transfers:   req.Transfers,
 		PaymentPlan: req.PaymentPlan,
 		Inclusions:  req.Inclusions,
 		Exclusions:  req.Exclusions,
+		Travellers:  req.Travellers,
+		Status:      models.StatusDraft,
+		StatusHistory: []models.StatusChange{{To: models.StatusDraft, ChangedBy: req.UserID, ChangedAt: now}},
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
-
-	if err := s.repo.Create(itinerary); err != nil {
-		return nil, fmt.Errorf("failed to create itinerary: %w", err)
-	}
-
-	return itinerary, nil
 }
 
 // GetItinerary retrieves an itinerary by ID
@@ -75,7 +158,7 @@ func (s *ItineraryService) GetItinerary(id string) (*models.Itinerary, error) {
 	itinerary, err := s.repo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			return nil, fmt.Errorf("itinerary not found")
+			return nil, ErrItineraryNotFound
 		}
 		return nil, fmt.Errorf("failed to get itinerary: %w", err)
 	}
@@ -83,6 +166,55 @@ func (s *ItineraryService) GetItinerary(id string) (*models.Itinerary, error) {
 	return itinerary, nil
 }
 
+// GetCoverage checks the hotel coverage of every night of an itinerary
+func (s *ItineraryService) GetCoverage(id string) (*CoverageReport, error) {
+	itinerary, err := s.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.coverage.Analyze(itinerary), nil
+}
+
+// GetTimeline places the activities of every day of an itinerary on the clock
+func (s *ItineraryService) GetTimeline(id string) (*Timeline, error) {
+	itinerary, err := s.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.schedule.Timeline(itinerary), nil
+}
+
+// GetFeasibility checks that the activities of every day can be reached in time
+func (s *ItineraryService) GetFeasibility(id string) (*FeasibilityReport, error) {
+	itinerary, err := s.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.feasibility.Analyze(itinerary), nil
+}
+
+// GeocodeItinerary looks up the coordinates the offline gazetteer couldn't
+// find with the online geocoder and saves them through the regular update
+func (s *ItineraryService) GeocodeItinerary(id string) (*models.Itinerary, error) {
+	if s.remote == nil {
+		return nil, ErrNoRemoteGeocoder
+	}
+	itinerary, err := s.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+
+	located := copyItinerary(itinerary)
+	geocodeLocations(s.remote, located)
+	return s.UpdateItinerary(id, &models.UpdateItineraryReq{
+		Days:   located.Days,
+		Hotels: located.Hotels,
+	})
+}
+
 // GetUserItineraries retrieves all itineraries for a user
 func (s *ItineraryService) GetUserItineraries(userID string) ([]*models.Itinerary, error) {
 	itineraries, err := s.repo.GetByUserID(userID)
@@ -106,15 +238,29 @@ func (s *ItineraryService) GetAllItineraries() ([]*models.Itinerary, error) {
 // UpdateItinerary updates an existing itinerary
 func (s *ItineraryService) UpdateItinerary(id string, req *models.UpdateItineraryReq) (*models.Itinerary, error) {
 	// Get existing itinerary
-	existing, err := s.repo.GetByID(id)
+	stored, err := s.repo.GetByID(id)
 	if err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			return nil, fmt.Errorf("itinerary not found")
+			return nil, ErrItineraryNotFound
 		}
 		return nil, fmt.Errorf("failed to get itinerary: %w", err)
 	}
 
+	// statuses only move through the workflow, which checks the transition
+	if req.Status != nil && *req.Status != stored.Status {
+		return nil, fmt.Errorf("%w, use POST /api/v1/itineraries/%s/status", ErrStatusNotEditable, id)
+	}
+	if isReadOnly(stored.Status) {
+		return nil, fmt.Errorf("%w: %s itineraries can't be edited", ErrItineraryLocked, stored.Status)
+	}
+
+	// work on a copy so a rejected update leaves the stored itinerary untouched
+	existing := copyItinerary(stored)
+
 	// Update fields
+	if req.CustomerName != nil {
+		existing.CustomerName = *req.CustomerName
+	}
 	if req.Title != nil {
 		existing.Title = *req.Title
 	}
@@ -129,6 +275,10 @@ func (s *ItineraryService) UpdateItinerary(id string, req *models.UpdateItinerar
 	}
 	if req.Days != nil {
 		existing.Days = req.Days
+	} else if !existing.StartDate.Equal(stored.StartDate) || !existing.EndDate.Equal(stored.EndDate) {
+		// new dates without new days: shift the planned days onto the new
+		// range, adding or dropping days at the end
+		existing.Days = reconcileDays(stored.Days, existing.StartDate, existing.EndDate)
 	}
 	if req.Hotels != nil {
 		existing.Hotels = req.Hotels
@@ -140,7 +290,12 @@ func (s *ItineraryService) UpdateItinerary(id string, req *models.UpdateItinerar
 		existing.Transfers = req.Transfers
 	}
 	if req.PaymentPlan != nil {
+		// a plan sent without exchange rates keeps the stored ones
+		rates := existing.PaymentPlan.ExchangeRates
 		existing.PaymentPlan = *req.PaymentPlan
+		if existing.PaymentPlan.ExchangeRates == nil {
+			existing.PaymentPlan.ExchangeRates = rates
+		}
 	}
 	if req.Inclusions != nil {
 		existing.Inclusions = req.Inclusions
@@ -148,15 +303,21 @@ func (s *ItineraryService) UpdateItinerary(id string, req *models.UpdateItinerar
 	if req.Exclusions != nil {
 		existing.Exclusions = req.Exclusions
 	}
+	if req.Travellers != nil {
+		existing.Travellers = req.Travellers
+	}
 
 	existing.UpdatedAt = time.Now()
+	keepElementIDs(stored, existing)
+	s.normalize(existing)
 
-	// Validate updated data
-	if existing.EndDate.Before(existing.StartDate) || existing.EndDate.Equal(existing.StartDate) {
-		return nil, ErrInvalidDateRange
+	// booked itineraries keep their dates, bookings and prices
+	if err := checkLocks(stored, existing); err != nil {
+		return nil, err
 	}
 
-	if err := s.validatePaymentPlan(&existing.PaymentPlan); err != nil {
+	// Validate updated data
+	if err := s.validator.Check(existing); err != nil {
 		return nil, err
 	}
 
@@ -172,43 +333,148 @@ func (s *ItineraryService) UpdateItinerary(id string, req *models.UpdateItinerar
 func (s *ItineraryService) DeleteItinerary(id string) error {
 	if err := s.repo.Delete(id); err != nil {
 		if errors.Is(err, repository.ErrNotFound) {
-			return fmt.Errorf("itinerary not found")
+			return ErrItineraryNotFound
 		}
 		return fmt.Errorf("failed to delete itinerary: %w", err)
 	}
 
+	for _, hook := range s.onDelete {
+		if err := hook(id); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// validateDays validates that days match the date range
-func (s *ItineraryService) validateDays(days []models.Day, startDate, endDate time.Time) error {
-	duration := int(endDate.Sub(startDate).Hours()/24) + 1
-	if len(days) != duration {
-		return fmt.Errorf("%w: expected %d days, got %d", ErrInvalidDays, duration, len(days))
+// CloneItinerary copies an itinerary into a new draft, optionally for another
+// customer or moved to a new start date. Booking references, seats and
+// tickets belong to the original booking and are not copied; installments
+// start over as pending. The travellers and their rooms are only kept for
+// the same customer.
+func (s *ItineraryService) CloneItinerary(id string, req *models.CloneItineraryReq) (*models.Itinerary, error) {
+	source, err := s.GetItinerary(id)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
-}
-
-// validatePaymentPlan validates payment plan consistency
-func (s *ItineraryService) validatePaymentPlan(plan *models.PaymentPlan) error {
-	if plan.AmountDue <= 0 {
-		return errors.New("total amount must be positive")
+	offset := 0
+	if req.StartDate != nil {
+		offset = daysBetween(source.StartDate, *req.StartDate)
+	}
+	shifted := shiftedItinerary(source, offset, false)
+
+	create := &models.CreateItineraryReq{
+		UserID:       source.UserID,
+		CustomerName: source.CustomerName,
+		Title:        source.Title,
+		Destination:  source.Destination,
+		StartDate:    *shifted.StartDate,
+		EndDate:      *shifted.EndDate,
+		Days:         shifted.Days,
+		Hotels:       shifted.Hotels,
+		Flights:      shifted.Flights,
+		Transfers:    shifted.Transfers,
+		PaymentPlan:  *shifted.PaymentPlan,
+		Inclusions:   append([]string{}, source.Inclusions...),
+		Exclusions:   append([]string{}, source.Exclusions...),
+		Travellers:   copyTravellers(source.Travellers),
+	}
+	if req.UserID != "" {
+		create.UserID = req.UserID
+	}
+	if req.CustomerName != nil {
+		create.CustomerName = *req.CustomerName
+	}
+	sameCustomer := create.UserID == source.UserID && create.CustomerName == source.CustomerName
+	if !sameCustomer {
+		create.Travellers = nil
 	}
 
-	if len(plan.Installments) == 0 {
-		return errors.New("at least one installment is required")
+	for i := range create.Days {
+		create.Days[i].Activities = copyActivities(create.Days[i].Activities)
+	}
+	for i := range create.Hotels {
+		create.Hotels[i].BookingReference = ""
+		create.Hotels[i].Coordinates = copyCoordinates(create.Hotels[i].Coordinates)
+		create.Hotels[i].Rooms = copyRooms(create.Hotels[i].Rooms)
+		if !sameCustomer {
+			create.Hotels[i].Rooms = nil
+		}
+	}
+	for i := range create.Flights {
+		create.Flights[i].BookingReference = ""
+		create.Flights[i].Passengers = nil
+	}
+	for i := range create.Transfers {
+		create.Transfers[i].BookingReference = ""
 	}
+	for i := range create.PaymentPlan.Installments {
+		create.PaymentPlan.Installments[i].Status = "Pending"
+	}
+
+	return s.CreateItinerary(create)
+}
+
+// normalize derives the stored form of an itinerary before it is validated:
+// airport and hotel time zones, instants in local time, typed activity
+// durations, traveller, activity and hotel IDs, currencies and exchange
+// rates, the amount due of priced itineraries and coordinates
+func (s *ItineraryService) normalize(itinerary *models.Itinerary) {
+	applyAirportTimeZones(itinerary)
+	applyHotelTimeZones(itinerary)
+	normalizeInstants(itinerary)
+	normalizeActivities(itinerary)
+	normalizeTravellers(itinerary)
+	normalizeElementIDs(itinerary)
+	s.currencies.normalizeMoney(itinerary)
+	s.currencies.snapshotRates(itinerary)
+	applyPricing(itinerary, s.currencies.forPlan(itinerary.PaymentPlan))
+	geocodeLocations(s.geocoder, itinerary)
+}
+
+// copyItinerary returns a deep copy of an itinerary, so the copy can be
+// changed or read without touching the stored record
+func copyItinerary(itinerary *models.Itinerary) *models.Itinerary {
+	copied := *itinerary
 
-	var sum float64
-	for _, inst := range plan.Installments {
-		sum += inst.Amount
+	copied.Days = make([]models.Day, len(itinerary.Days))
+	for i, day := range itinerary.Days {
+		day.Activities = copyActivities(day.Activities)
+		copied.Days[i] = day
+	}
+	copied.Hotels = copyHotels(itinerary.Hotels)
+	copied.Flights = make([]models.Flight, len(itinerary.Flights))
+	for i, flight := range itinerary.Flights {
+		flight.Passengers = append([]models.FlightPassenger(nil), flight.Passengers...)
+		flight.Price = copyPrice(flight.Price)
+		copied.Flights[i] = flight
+	}
+	copied.Transfers = make([]models.Transfer, len(itinerary.Transfers))
+	for i, transfer := range itinerary.Transfers {
+		transfer.Price = copyPrice(transfer.Price)
+		copied.Transfers[i] = transfer
 	}
 
-	// Allow small floating point difference
-	if sum < plan.AmountDue-0.01 || sum > plan.AmountDue+0.01 {
-		return fmt.Errorf("installment amounts (%.2f) don't match total amount (%.2f)", sum, plan.AmountDue)
+	copied.PaymentPlan.Installments = append([]models.Installment(nil), itinerary.PaymentPlan.Installments...)
+	if itinerary.PaymentPlan.ExchangeRates != nil {
+		copied.PaymentPlan.ExchangeRates = make(map[string]decimal.Decimal, len(itinerary.PaymentPlan.ExchangeRates))
+		for code, rate := range itinerary.PaymentPlan.ExchangeRates {
+			copied.PaymentPlan.ExchangeRates[code] = rate
+		}
 	}
+	copied.Inclusions = append([]string(nil), itinerary.Inclusions...)
+	copied.Exclusions = append([]string(nil), itinerary.Exclusions...)
+	copied.Travellers = copyTravellers(itinerary.Travellers)
+	copied.StatusHistory = append([]models.StatusChange(nil), itinerary.StatusHistory...)
+	if itinerary.ConfirmedAt != nil {
+		confirmedAt := *itinerary.ConfirmedAt
+		copied.ConfirmedAt = &confirmedAt
+	}
+	return &copied
+}
 
-	return nil
+// newConfirmationNumber generates a short human readable confirmation number
+func newConfirmationNumber() string {
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	return "VGV-" + strings.ToUpper(id[:8])
 }
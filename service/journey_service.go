@@ -0,0 +1,243 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/reference"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// journey issue codes
+const (
+	CodeOverlappingFlights      = "overlapping_flights"
+	CodeShortConnection         = "short_connection"
+	CodeBrokenFlightChain       = "broken_flight_chain"
+	CodeOvernightLayoverNoHotel = "overnight_layover_without_hotel"
+)
+
+const (
+	// DefaultMinConnectionTime is used when no minimum connection time is configured
+	DefaultMinConnectionTime = 60 * time.Minute
+
+	// maxConnectionTime separates a connection from a stopover; a longer stay
+	// at an airport city is part of the trip rather than a layover
+	maxConnectionTime = 24 * time.Hour
+
+	// minOvernightLayover is the shortest layover past midnight that needs a
+	// bed, a late connection just after midnight doesn't
+	minOvernightLayover = 6 * time.Hour
+)
+
+// JourneyLeg is a single flight of a journey
+type JourneyLeg struct {
+	FlightIndex       int       `json:"flight_index"`
+	FlightNumber      string    `json:"flight_number"`
+	Airline           string    `json:"airline"`
+	From              string    `json:"from"`
+	To                string    `json:"to"`
+	Departure         time.Time `json:"departure"`
+	Arrival           time.Time `json:"arrival"`
+	DepartureTimeZone string    `json:"departure_time_zone,omitempty"`
+	ArrivalTimeZone   string    `json:"arrival_time_zone,omitempty"`
+	DurationMinutes   int       `json:"duration_minutes"`
+}
+
+// Connection is the layover between two legs of a journey
+type Connection struct {
+	Airport         string `json:"airport"`
+	ArrivingFlight  string `json:"arriving_flight"`
+	DepartingFlight string `json:"departing_flight"`
+	LayoverMinutes  int    `json:"layover_minutes"`
+	Overnight       bool   `json:"overnight"`
+	HotelBooked     bool   `json:"hotel_booked"`
+}
+
+// Journey is an origin to destination trip made of connecting flights
+type Journey struct {
+	From          string       `json:"from"`
+	To            string       `json:"to"`
+	Departure     time.Time    `json:"departure"`
+	Arrival       time.Time    `json:"arrival"`
+	Legs          []JourneyLeg `json:"legs"`
+	Connections   []Connection `json:"connections"`
+	TravelMinutes int          `json:"travel_minutes"`
+}
+
+// JourneyReport chains the flights of an itinerary into journeys
+type JourneyReport struct {
+	Journeys            []Journey         `json:"journeys"`
+	TotalFlightMinutes  int               `json:"total_flight_minutes"`
+	TotalLayoverMinutes int               `json:"total_layover_minutes"`
+	TotalTravelMinutes  int               `json:"total_travel_minutes"`
+	Issues              []ValidationIssue `json:"issues"`
+}
+
+// JourneyService chains flights by airport and checks the connections
+type JourneyService struct {
+	minConnection time.Duration
+}
+
+// NewJourneyService creates a new journey service flagging connections
+// shorter than minConnection
+func NewJourneyService(minConnection time.Duration) *JourneyService {
+	if minConnection <= 0 {
+		minConnection = DefaultMinConnectionTime
+	}
+
+	return &JourneyService{
+		minConnection: minConnection,
+	}
+}
+
+// Analyze orders the flights by departure and groups consecutive legs that
+// connect within maxConnectionTime at the same airport into journeys
+func (s *JourneyService) Analyze(itinerary *models.Itinerary) *JourneyReport {
+	report := &JourneyReport{
+		Journeys: []Journey{},
+		Issues:   []ValidationIssue{},
+	}
+
+	order := make([]int, len(itinerary.Flights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return itinerary.Flights[order[a]].Departure.Before(itinerary.Flights[order[b]].Departure)
+	})
+
+	var current *Journey
+	for n, i := range order {
+		flight := itinerary.Flights[i]
+		leg := JourneyLeg{
+			FlightIndex:       i,
+			FlightNumber:      flight.FlightNumber,
+			Airline:           flight.Airline,
+			From:              flight.From,
+			To:                flight.To,
+			Departure:         flight.Departure,
+			Arrival:           flight.Arrival,
+			DepartureTimeZone: flight.DepartureTimeZone,
+			ArrivalTimeZone:   flight.ArrivalTimeZone,
+			DurationMinutes:   minutes(FlightDuration(flight)),
+		}
+		report.TotalFlightMinutes += leg.DurationMinutes
+
+		if n > 0 {
+			prev := itinerary.Flights[order[n-1]]
+			path := fmt.Sprintf("flights[%d]", i)
+			layover := flight.Departure.Sub(prev.Arrival)
+
+			if !sameAirport(prev.To, flight.From) {
+				report.Issues = append(report.Issues, warning(path+".from", CodeBrokenFlightChain,
+					"flight %s departs from %s but the previous flight %s arrived at %s",
+					flight.FlightNumber, flight.From, prev.FlightNumber, prev.To))
+				if layover < 0 {
+					report.Issues = append(report.Issues, issue(path+".departure", CodeOverlappingFlights,
+						"flight %s departs before %s has landed", flight.FlightNumber, prev.FlightNumber))
+				}
+			} else if layover <= maxConnectionTime {
+				connection := s.connect(itinerary, prev, flight, layover)
+				current.Connections = append(current.Connections, connection)
+				current.Legs = append(current.Legs, leg)
+				current.To = leg.To
+				current.Arrival = leg.Arrival
+				report.TotalLayoverMinutes += connection.LayoverMinutes
+				report.Issues = append(report.Issues, s.connectionIssues(path, prev, flight, connection)...)
+				continue
+			}
+		}
+
+		report.Journeys = append(report.Journeys, Journey{
+			From:        leg.From,
+			To:          leg.To,
+			Departure:   leg.Departure,
+			Arrival:     leg.Arrival,
+			Legs:        []JourneyLeg{leg},
+			Connections: []Connection{},
+		})
+		current = &report.Journeys[len(report.Journeys)-1]
+	}
+
+	for i := range report.Journeys {
+		journey := &report.Journeys[i]
+		journey.TravelMinutes = minutes(journey.Arrival.Sub(journey.Departure))
+	}
+	report.TotalTravelMinutes = report.TotalFlightMinutes + report.TotalLayoverMinutes
+
+	return report
+}
+
+// ValidationRule reports the journey problems as validation issues
+func (s *JourneyService) ValidationRule() ValidationRule {
+	return func(itinerary *models.Itinerary) []ValidationIssue {
+		return s.Analyze(itinerary).Issues
+	}
+}
+
+// connect describes the layover between two legs at the same airport. A
+// layover is overnight when it lasts at least minOvernightLayover and the
+// local dates of landing and take-off differ.
+func (s *JourneyService) connect(itinerary *models.Itinerary, arriving, departing models.Flight, layover time.Duration) Connection {
+	connection := Connection{
+		Airport:         departing.From,
+		ArrivingFlight:  arriving.FlightNumber,
+		DepartingFlight: departing.FlightNumber,
+		LayoverMinutes:  minutes(layover),
+	}
+
+	landed := localDateOf(arriving.Arrival, arriving.ArrivalTimeZone)
+	if layover >= minOvernightLayover && localDateOf(departing.Departure, arriving.ArrivalTimeZone).After(landed) {
+		connection.Overnight = true
+		for _, hotel := range itinerary.Hotels {
+			if !landed.Before(dateOf(hotel.CheckInDate)) && landed.Before(dateOf(hotel.CheckOutDate)) {
+				connection.HotelBooked = true
+				break
+			}
+		}
+	}
+
+	return connection
+}
+
+// connectionIssues flags overlapping legs, connections shorter than the
+// minimum connection time and overnight layovers without a hotel
+func (s *JourneyService) connectionIssues(path string, arriving, departing models.Flight, connection Connection) []ValidationIssue {
+	var issues []ValidationIssue
+	switch {
+	case connection.LayoverMinutes < 0:
+		issues = append(issues, issue(path+".departure", CodeOverlappingFlights,
+			"flight %s departs before %s has landed", departing.FlightNumber, arriving.FlightNumber))
+	case connection.LayoverMinutes < minutes(s.minConnection):
+		issues = append(issues, warning(path+".departure", CodeShortConnection,
+			"only %s to connect from %s to %s at %s, the minimum connection time is %s",
+			formatDuration(time.Duration(connection.LayoverMinutes)*time.Minute), arriving.FlightNumber,
+			departing.FlightNumber, connection.Airport, formatDuration(s.minConnection)))
+	}
+
+	if connection.Overnight && !connection.HotelBooked {
+		issues = append(issues, warning(path, CodeOvernightLayoverNoHotel,
+			"overnight layover at %s between %s and %s has no hotel booked",
+			connection.Airport, arriving.FlightNumber, departing.FlightNumber))
+	}
+
+	return issues
+}
+
+// sameAirport compares two airports by their reference code when both are
+// known and by their text otherwise
+func sameAirport(a, b string) bool {
+	catalog := reference.Default()
+	airportA, okA := catalog.Airport(a)
+	airportB, okB := catalog.Airport(b)
+	if okA && okB {
+		return airportA.IATA == airportB.IATA
+	}
+	return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+}
+
+// minutes rounds a duration to whole minutes
+func minutes(d time.Duration) int {
+	return int(d.Round(time.Minute) / time.Minute)
+}
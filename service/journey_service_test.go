@@ -0,0 +1,118 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// leg returns a flight between two airports at RFC 3339 times
+func leg(number, from, to, departure, arrival string) models.Flight {
+	parse := func(value string) time.Time {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			panic(err)
+		}
+		return t
+	}
+	return models.Flight{FlightNumber: number, From: from, To: to, Departure: parse(departure), Arrival: parse(arrival)}
+}
+
+func TestJourneyAnalyze(t *testing.T) {
+	tests := []struct {
+		name         string
+		flights      []models.Flight
+		hotels       []models.Hotel
+		wantJourneys []string
+		wantLayover  int
+		wantIssues   []string
+	}{
+		{"single flight", []models.Flight{
+			leg("AI 131", "BOM", "LHR", "2025-05-02T02:00:00+05:30", "2025-05-02T07:40:00+01:00"),
+		}, nil, []string{"BOM-LHR"}, 0, nil},
+		{"connection through Dubai", []models.Flight{
+			leg("EK 501", "BOM", "DXB", "2025-05-02T04:30:00+05:30", "2025-05-02T06:20:00+04:00"),
+			leg("EK 1", "DXB", "LHR", "2025-05-02T07:45:00+04:00", "2025-05-02T12:30:00+01:00"),
+		}, nil, []string{"BOM-DXB-LHR"}, 85, nil},
+		{"flights listed out of order", []models.Flight{
+			leg("EK 1", "DXB", "LHR", "2025-05-02T07:45:00+04:00", "2025-05-02T12:30:00+01:00"),
+			leg("EK 501", "BOM", "DXB", "2025-05-02T04:30:00+05:30", "2025-05-02T06:20:00+04:00"),
+		}, nil, []string{"BOM-DXB-LHR"}, 85, nil},
+		{"airport named with its code in parentheses", []models.Flight{
+			leg("EK 501", "BOM", "Dubai (DXB)", "2025-05-02T04:30:00+05:30", "2025-05-02T06:20:00+04:00"),
+			leg("EK 1", "DXB", "LHR", "2025-05-02T07:45:00+04:00", "2025-05-02T12:30:00+01:00"),
+		}, nil, []string{"BOM-Dubai (DXB)-LHR"}, 85, nil},
+		{"short connection", []models.Flight{
+			leg("EK 501", "BOM", "DXB", "2025-05-02T04:30:00+05:30", "2025-05-02T06:20:00+04:00"),
+			leg("EK 1", "DXB", "LHR", "2025-05-02T06:50:00+04:00", "2025-05-02T11:35:00+01:00"),
+		}, nil, []string{"BOM-DXB-LHR"}, 30, []string{"warning " + CodeShortConnection}},
+		{"connecting flight leaves before landing", []models.Flight{
+			leg("EK 501", "BOM", "DXB", "2025-05-02T04:30:00+05:30", "2025-05-02T06:20:00+04:00"),
+			leg("EK 1", "DXB", "LHR", "2025-05-02T06:00:00+04:00", "2025-05-02T10:45:00+01:00"),
+		}, nil, []string{"BOM-DXB-LHR"}, -20, []string{"error " + CodeOverlappingFlights}},
+		{"next flight from another airport", []models.Flight{
+			leg("EK 501", "BOM", "DXB", "2025-05-02T04:30:00+05:30", "2025-05-02T06:20:00+04:00"),
+			leg("EY 19", "AUH", "LHR", "2025-05-02T09:00:00+04:00", "2025-05-02T13:45:00+01:00"),
+		}, nil, []string{"BOM-DXB", "AUH-LHR"}, 0, []string{"warning " + CodeBrokenFlightChain}},
+		{"next flight from another airport before landing", []models.Flight{
+			leg("EK 501", "BOM", "DXB", "2025-05-02T04:30:00+05:30", "2025-05-02T06:20:00+04:00"),
+			leg("EY 19", "AUH", "LHR", "2025-05-02T06:00:00+04:00", "2025-05-02T10:45:00+01:00"),
+		}, nil, []string{"BOM-DXB", "AUH-LHR"}, 0, []string{"warning " + CodeBrokenFlightChain, "error " + CodeOverlappingFlights}},
+		{"overnight layover without a hotel", []models.Flight{
+			leg("LH 757", "BLR", "FRA", "2025-05-02T03:00:00+05:30", "2025-05-02T09:00:00+02:00"),
+			leg("LH 400", "FRA", "JFK", "2025-05-03T08:00:00+02:00", "2025-05-03T10:30:00-04:00"),
+		}, nil, []string{"BLR-FRA-JFK"}, 23 * 60, []string{"warning " + CodeOvernightLayoverNoHotel}},
+		// landing at 23:00 and leaving at 01:30 crosses midnight but needs no bed
+		{"late connection after midnight", []models.Flight{
+			leg("LH 763", "DEL", "FRA", "2025-05-02T16:00:00+05:30", "2025-05-02T23:00:00+02:00"),
+			leg("LH 400", "FRA", "JFK", "2025-05-03T01:30:00+02:00", "2025-05-03T04:00:00-04:00"),
+		}, nil, []string{"DEL-FRA-JFK"}, 150, nil},
+		{"overnight layover with a hotel", []models.Flight{
+			leg("LH 757", "BLR", "FRA", "2025-05-02T03:00:00+05:30", "2025-05-02T09:00:00+02:00"),
+			leg("LH 400", "FRA", "JFK", "2025-05-03T08:00:00+02:00", "2025-05-03T10:30:00-04:00"),
+		}, []models.Hotel{{Name: "Hilton Frankfurt Airport", CheckInDate: time.Date(2025, time.May, 2, 0, 0, 0, 0, time.UTC),
+			CheckOutDate: time.Date(2025, time.May, 3, 0, 0, 0, 0, time.UTC), Nights: 1}},
+			[]string{"BLR-FRA-JFK"}, 23 * 60, nil},
+		{"stay of more than a day is a new journey", []models.Flight{
+			leg("AI 131", "BOM", "LHR", "2025-05-02T02:00:00+05:30", "2025-05-02T07:40:00+01:00"),
+			leg("AI 130", "LHR", "BOM", "2025-05-09T21:00:00+01:00", "2025-05-10T11:00:00+05:30"),
+		}, nil, []string{"BOM-LHR", "LHR-BOM"}, 0, nil},
+	}
+
+	journeys := NewJourneyService(time.Hour)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itinerary := &models.Itinerary{Flights: test.flights, Hotels: test.hotels}
+			applyAirportTimeZones(itinerary)
+			report := journeys.Analyze(itinerary)
+
+			var routes []string
+			for _, journey := range report.Journeys {
+				route := journey.From
+				for _, leg := range journey.Legs {
+					route += "-" + leg.To
+				}
+				routes = append(routes, route)
+			}
+			var issues []string
+			for _, found := range report.Issues {
+				issues = append(issues, found.Severity+" "+found.Code)
+			}
+
+			if fmt.Sprint(routes) != fmt.Sprint(test.wantJourneys) {
+				t.Errorf("got journeys %v, want %v", routes, test.wantJourneys)
+			}
+			if report.TotalLayoverMinutes != test.wantLayover {
+				t.Errorf("got %d minutes of layover, want %d", report.TotalLayoverMinutes, test.wantLayover)
+			}
+			if report.TotalTravelMinutes != report.TotalFlightMinutes+report.TotalLayoverMinutes {
+				t.Errorf("got %d minutes of travel for %d in the air and %d on the ground",
+					report.TotalTravelMinutes, report.TotalFlightMinutes, report.TotalLayoverMinutes)
+			}
+			if fmt.Sprint(issues) != fmt.Sprint(test.wantIssues) {
+				t.Errorf("got issues %v, want %v", issues, test.wantIssues)
+			}
+		})
+	}
+}
@@ -0,0 +1,559 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidDayConflict = errors.New("day_conflict must be one of combine, keep_first or keep_second")
+	ErrMergeSelf          = errors.New("an itinerary can't be merged with itself")
+	ErrInvalidSplitDay    = errors.New("split day must be after the first day and not after the last one")
+	ErrMergeOwners        = errors.New("the itineraries belong to different users, give user_id to choose the owner")
+	ErrInvalidMergeOwner  = errors.New("user_id must be the owner of one of the merged itineraries")
+	ErrInvalidSplitAmount = errors.New("first_amount must be more than zero and less than the amount due")
+	ErrSplitAmountPriced  = errors.New("first_amount can't be given for an itinerary with prices")
+)
+
+// MergeItineraries combines two itineraries into a new draft spanning both
+// date ranges. Days on the same date are resolved by req.DayConflict: combine
+// joins their activities, keep_first and keep_second keep one of them. Gaps
+// between the trips get empty days. Stays at the same hotel are joined into
+// one; with keep_first or keep_second the other itinerary's hotels lose the
+// nights already booked. Flights, transfers and travellers are joined without
+// duplicates and the payment plans are added up in the currency of the
+// first one; when any element has a price the installments are scaled to the
+// derived amount due instead. Itineraries of two users can only be merged
+// when req.UserID names which of them owns the result. The two originals are
+// left untouched.
+func (s *ItineraryService) MergeItineraries(id string, req *models.MergeItinerariesReq) (*models.Itinerary, error) {
+	conflict := req.DayConflict
+	if conflict == "" {
+		conflict = models.MergeCombine
+	}
+	switch conflict {
+	case models.MergeCombine, models.MergeKeepFirst, models.MergeKeepSecond:
+	default:
+		return nil, ErrInvalidDayConflict
+	}
+	if id == req.OtherID {
+		return nil, ErrMergeSelf
+	}
+
+	first, err := s.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+	second, err := s.GetItinerary(req.OtherID)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := first.UserID
+	switch {
+	case req.UserID == "" && second.UserID != first.UserID:
+		return nil, ErrMergeOwners
+	case req.UserID != "" && req.UserID != first.UserID && req.UserID != second.UserID:
+		return nil, ErrInvalidMergeOwner
+	case req.UserID != "":
+		owner = req.UserID
+	}
+
+	merged := newDraftFrom(first)
+	merged.UserID = owner
+	merged.StatusHistory[0].ChangedBy = owner
+	if req.Title != nil {
+		merged.Title = *req.Title
+	}
+	if second.CustomerName != "" && !strings.EqualFold(second.CustomerName, first.CustomerName) {
+		if merged.CustomerName == "" {
+			merged.CustomerName = second.CustomerName
+		} else {
+			merged.CustomerName += " & " + second.CustomerName
+		}
+	}
+	if !strings.EqualFold(second.Destination, first.Destination) {
+		merged.Destination += " & " + second.Destination
+	}
+
+	merged.StartDate = dateOf(first.StartDate)
+	if second.StartDate.Before(first.StartDate) {
+		merged.StartDate = dateOf(second.StartDate)
+	}
+	merged.EndDate = dateOf(first.EndDate)
+	if second.EndDate.After(first.EndDate) {
+		merged.EndDate = dateOf(second.EndDate)
+	}
+	merged.Days = mergeDays(first, second, merged.StartDate, merged.EndDate, conflict)
+
+	merged.Hotels = mergeHotels(first.Hotels, second.Hotels, conflict)
+
+	merged.Flights = append([]models.Flight{}, first.Flights...)
+	for _, flight := range second.Flights {
+		if !containsFlight(merged.Flights, flight) {
+			merged.Flights = append(merged.Flights, flight)
+		}
+	}
+	sort.SliceStable(merged.Flights, func(i, j int) bool { return merged.Flights[i].Departure.Before(merged.Flights[j].Departure) })
+
+	merged.Transfers = append([]models.Transfer{}, first.Transfers...)
+	for _, transfer := range second.Transfers {
+		if !containsTransfer(merged.Transfers, transfer) {
+			merged.Transfers = append(merged.Transfers, transfer)
+		}
+	}
+	sort.SliceStable(merged.Transfers, func(i, j int) bool { return merged.Transfers[i].Timing.Before(merged.Transfers[j].Timing) })
+
+	merged.PaymentPlan, err = mergePaymentPlans(first.PaymentPlan, second.PaymentPlan, s.currencies)
+	if err != nil {
+		return nil, err
+	}
+	if hasPricing(merged) {
+		currencies := s.currencies.forPlan(merged.PaymentPlan)
+		breakdown, err := priceBreakdown(merged, currencies)
+		if err != nil {
+			return nil, err
+		}
+		merged.PaymentPlan = scalePaymentPlan(merged.PaymentPlan, breakdown.Total, currencies)
+	}
+	merged.Inclusions = unionStrings(first.Inclusions, second.Inclusions)
+	merged.Exclusions = unionStrings(first.Exclusions, second.Exclusions)
+	for _, traveller := range copyTravellers(second.Travellers) {
+		if !containsTraveller(merged.Travellers, traveller.ID) {
+			merged.Travellers = append(merged.Travellers, traveller)
+		}
+	}
+
+	if err := s.createAll(merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// SplitItinerary splits an itinerary at a day into two new drafts. The first
+// one ends on the split day and the second one starts on it, taking that
+// day's activities. Hotel stays are cut at the split date. Flights and
+// transfers before the split date go to the first part and the others to the
+// second. The amount due is shared by number of nights unless FirstAmount is
+// given, and every installment is split in the same proportion, each part
+// owing the sum of its installments. Priced hotel
+// stays are cut in proportion to their nights, and a priced itinerary has the
+// amount due of each part derived from its own prices. The original is left
+// untouched.
+func (s *ItineraryService) SplitItinerary(id string, req *models.SplitItineraryReq) ([]*models.Itinerary, error) {
+	source, err := s.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.DayNumber < 2 || req.DayNumber > len(source.Days) {
+		return nil, fmt.Errorf("%w: got %d for a %d day itinerary", ErrInvalidSplitDay, req.DayNumber, len(source.Days))
+	}
+
+	splitDate := dateOf(source.StartDate).AddDate(0, 0, req.DayNumber-1)
+	first, second := newDraftFrom(source), newDraftFrom(source)
+	first.Title = source.Title + " (part 1)"
+	second.Title = source.Title + " (part 2)"
+	first.StartDate, first.EndDate = dateOf(source.StartDate), splitDate
+	second.StartDate, second.EndDate = splitDate, dateOf(source.EndDate)
+
+	for _, day := range source.Days {
+		switch {
+		case day.DayNumber < req.DayNumber:
+			day.Activities = copyActivities(day.Activities)
+			first.Days = append(first.Days, day)
+		case day.DayNumber == req.DayNumber:
+			// the changeover day ends the first part and starts the second
+			last := emptyDay(day.DayNumber)
+			last.Title, last.TimeZone = day.Title, day.TimeZone
+			first.Days = append(first.Days, last)
+			fallthrough
+		default:
+			day.Activities = copyActivities(day.Activities)
+			second.Days = append(second.Days, day)
+		}
+	}
+	first.Days = reconcileDays(first.Days, first.StartDate, first.EndDate)
+	renumbered := make([]models.Day, len(second.Days))
+	for i, day := range second.Days {
+		day.DayNumber = i + 1
+		renumbered[i] = day
+	}
+	second.Days = reconcileDays(renumbered, second.StartDate, second.EndDate)
+
+	for _, hotel := range copyHotels(source.Hotels) {
+		if dateOf(hotel.CheckInDate).Before(splitDate) {
+			stay := hotel
+			if dateOf(stay.CheckOutDate).After(splitDate) {
+				stay.CheckOutDate = splitDate
+				stay.Nights = daysBetween(stay.CheckInDate, stay.CheckOutDate)
+				stay.Price = scalePrice(hotel.Price, nightShare(stay.Nights, hotel.Nights))
+			}
+			first.Hotels = append(first.Hotels, stay)
+		}
+		if dateOf(hotel.CheckOutDate).After(splitDate) {
+			stay := hotel
+			stay.Coordinates = copyCoordinates(hotel.Coordinates)
+			if dateOf(stay.CheckInDate).Before(splitDate) {
+				stay.CheckInDate = splitDate
+				stay.Nights = daysBetween(stay.CheckInDate, stay.CheckOutDate)
+				stay.Price = scalePrice(hotel.Price, nightShare(stay.Nights, hotel.Nights))
+			}
+			second.Hotels = append(second.Hotels, stay)
+		}
+	}
+
+	for _, flight := range source.Flights {
+		if localDateOf(flight.Departure, flight.DepartureTimeZone).Before(splitDate) {
+			first.Flights = append(first.Flights, flight)
+		} else {
+			second.Flights = append(second.Flights, flight)
+		}
+	}
+	for _, transfer := range source.Transfers {
+		if localDateOf(transfer.Timing, transferZone(source, transfer)).Before(splitDate) {
+			first.Transfers = append(first.Transfers, transfer)
+		} else {
+			second.Transfers = append(second.Transfers, transfer)
+		}
+	}
+
+	share := nightShare(req.DayNumber-1, len(source.Days)-1)
+	if req.FirstAmount != nil {
+		if hasPricing(source) {
+			return nil, ErrSplitAmountPriced
+		}
+		amount := *req.FirstAmount
+		if amount.Currency == "" {
+			amount.Currency = source.PaymentPlan.AmountDue.Currency
+		}
+		amount, err = s.currencies.forPlan(source.PaymentPlan).Convert(amount, source.PaymentPlan.AmountDue.Currency)
+		if err != nil {
+			return nil, err
+		}
+		// each part has to owe something
+		if !amount.Amount.IsPositive() || !amount.Amount.LessThan(source.PaymentPlan.AmountDue.Amount) {
+			return nil, ErrInvalidSplitAmount
+		}
+		share = amount.Amount.Div(source.PaymentPlan.AmountDue.Amount)
+	}
+	currencies := s.currencies.forPlan(source.PaymentPlan)
+	first.PaymentPlan, second.PaymentPlan, err = splitPaymentPlan(source.PaymentPlan, share, currencies)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range []*models.Itinerary{first, second} {
+		if hasPricing(part) {
+			breakdown, err := priceBreakdown(part, currencies)
+			if err != nil {
+				return nil, err
+			}
+			part.PaymentPlan = scalePaymentPlan(part.PaymentPlan, breakdown.Total, currencies)
+		}
+	}
+
+	if err := s.createAll(first, second); err != nil {
+		return nil, err
+	}
+	return []*models.Itinerary{first, second}, nil
+}
+
+// createAll normalizes and validates every itinerary before storing any of
+// them, so either all of them are created or none is
+func (s *ItineraryService) createAll(itineraries ...*models.Itinerary) error {
+	for _, itinerary := range itineraries {
+		s.normalize(itinerary)
+		if err := s.validator.Check(itinerary); err != nil {
+			return err
+		}
+	}
+	for _, itinerary := range itineraries {
+		if err := s.repo.Create(itinerary); err != nil {
+			return fmt.Errorf("failed to create itinerary: %w", err)
+		}
+	}
+	return nil
+}
+
+// newDraftFrom starts a new draft itinerary for the customer of another one
+func newDraftFrom(source *models.Itinerary) *models.Itinerary {
+	now := time.Now()
+	return &models.Itinerary{
+		ID:            uuid.New().String(),
+		UserID:        source.UserID,
+		CustomerName:  source.CustomerName,
+		Title:         source.Title,
+		Destination:   source.Destination,
+		Hotels:        []models.Hotel{},
+		Flights:       []models.Flight{},
+		Transfers:     []models.Transfer{},
+		Inclusions:    append([]string{}, source.Inclusions...),
+		Exclusions:    append([]string{}, source.Exclusions...),
+		Travellers:    copyTravellers(source.Travellers),
+		Status:        models.StatusDraft,
+		StatusHistory: []models.StatusChange{{To: models.StatusDraft, ChangedBy: source.UserID, ChangedAt: now}},
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+}
+
+// mergeDays builds one day per date of the merged range. Days of both
+// itineraries on the same date are resolved by the conflict rule.
+func mergeDays(first, second *models.Itinerary, startDate, endDate time.Time, conflict string) []models.Day {
+	byDate := func(itinerary *models.Itinerary) map[time.Time]models.Day {
+		days := make(map[time.Time]models.Day, len(itinerary.Days))
+		for _, day := range itinerary.Days {
+			days[dateOf(day.Date)] = day
+		}
+		return days
+	}
+	firstDays, secondDays := byDate(first), byDate(second)
+
+	var days []models.Day
+	for n := 1; n <= tripLength(startDate, endDate); n++ {
+		date := startDate.AddDate(0, 0, n-1)
+		a, inFirst := firstDays[date]
+		b, inSecond := secondDays[date]
+
+		var day models.Day
+		switch {
+		case inFirst && inSecond && conflict == models.MergeKeepFirst, inFirst && !inSecond:
+			day = a
+			day.Activities = copyActivities(a.Activities)
+		case inFirst && inSecond && conflict == models.MergeKeepSecond, inSecond && !inFirst:
+			day = b
+			day.Activities = copyActivities(b.Activities)
+		case inFirst && inSecond:
+			day = a
+			if !strings.EqualFold(a.Title, b.Title) {
+				day.Title = a.Title + " / " + b.Title
+			}
+			if day.TimeZone == "" {
+				day.TimeZone = b.TimeZone
+			}
+			day.Activities = copyActivities(a.Activities)
+			more := copyActivities(b.Activities)
+			day.Activities.Morning = append(day.Activities.Morning, more.Morning...)
+			day.Activities.Afternoon = append(day.Activities.Afternoon, more.Afternoon...)
+			day.Activities.Evening = append(day.Activities.Evening, more.Evening...)
+		default:
+			day = emptyDay(n)
+		}
+		day.DayNumber = n
+		day.Date = date
+		days = append(days, day)
+	}
+	return days
+}
+
+// mergePaymentPlans adds up two payment plans in the currency of the first
+// one. The merged plan keeps the exchange rates of the first one, completed
+// with those of the second, and owes the sum of all installments converted
+// with them. Installments keep their currency, are ordered by due date and
+// renumbered.
+func mergePaymentPlans(a, b models.PaymentPlan, currencies *CurrencyService) (models.PaymentPlan, error) {
+	plan := models.PaymentPlan{
+		DueDate: a.DueDate,
+	}
+	if len(a.ExchangeRates) > 0 || len(b.ExchangeRates) > 0 {
+		plan.ExchangeRates = addRates(a.ExchangeRates, b.ExchangeRates)
+	}
+	if b.DueDate.Before(a.DueDate) {
+		plan.DueDate = b.DueDate
+	}
+	plan.Installments = append(append([]models.Installment{}, a.Installments...), b.Installments...)
+	sort.SliceStable(plan.Installments, func(i, j int) bool {
+		return plan.Installments[i].DueDate.Before(plan.Installments[j].DueDate)
+	})
+	amounts := []models.Money{a.AmountDue, b.AmountDue}
+	if len(plan.Installments) > 0 {
+		amounts = make([]models.Money, len(plan.Installments))
+	}
+	for i := range plan.Installments {
+		plan.Installments[i].InstallmentNumber = i + 1
+		amounts[i] = plan.Installments[i].Amount
+	}
+
+	var err error
+	if plan.AmountDue, err = currencies.forPlan(plan).Sum(a.AmountDue.Currency, amounts...); err != nil {
+		return models.PaymentPlan{}, err
+	}
+	return plan, nil
+}
+
+// splitPaymentPlan shares a payment plan between two parts. The first part
+// gets share of every installment rounded to the minor units of its currency
+// and the second one the rest, so no installment gains or loses anything.
+// Each part owes the sum of its installments in the currency of the plan
+// and keeps its exchange rates.
+func splitPaymentPlan(plan models.PaymentPlan, share decimal.Decimal, currencies *CurrencyService) (models.PaymentPlan, models.PaymentPlan, error) {
+	first := models.PaymentPlan{DueDate: plan.DueDate, ExchangeRates: plan.ExchangeRates}
+	second := models.PaymentPlan{DueDate: plan.DueDate, ExchangeRates: plan.ExchangeRates}
+	var firstAmounts, secondAmounts []models.Money
+	for _, installment := range plan.Installments {
+		part := installment
+		part.Amount = currencies.Round(models.NewMoney(installment.Amount.Amount.Mul(share), installment.Amount.Currency))
+		rest := installment
+		rest.Amount = models.NewMoney(installment.Amount.Amount.Sub(part.Amount.Amount), installment.Amount.Currency)
+		if part.Amount.Amount.IsPositive() {
+			part.InstallmentNumber = len(first.Installments) + 1
+			first.Installments = append(first.Installments, part)
+			firstAmounts = append(firstAmounts, part.Amount)
+		}
+		if rest.Amount.Amount.IsPositive() {
+			rest.InstallmentNumber = len(second.Installments) + 1
+			second.Installments = append(second.Installments, rest)
+			secondAmounts = append(secondAmounts, rest.Amount)
+		}
+	}
+
+	var err error
+	if first.AmountDue, err = currencies.Sum(plan.AmountDue.Currency, firstAmounts...); err != nil {
+		return first, second, err
+	}
+	if second.AmountDue, err = currencies.Sum(plan.AmountDue.Currency, secondAmounts...); err != nil {
+		return first, second, err
+	}
+	return first, second, nil
+}
+
+// copyHotels returns a copy of a list of hotels
+func copyHotels(hotels []models.Hotel) []models.Hotel {
+	copied := make([]models.Hotel, len(hotels))
+	for i, hotel := range hotels {
+		hotel.Coordinates = copyCoordinates(hotel.Coordinates)
+		hotel.Rooms = copyRooms(hotel.Rooms)
+		hotel.Price = copyPrice(hotel.Price)
+		copied[i] = hotel
+	}
+	return copied
+}
+
+// mergeHotels joins the stays of two itineraries. Overlapping or back to back
+// stays at the same hotel become one stay. Unless the conflict rule is
+// combine, the stays of the itinerary not kept are cut to the nights the kept
+// one has no hotel for. Prices follow the nights they pay for.
+func mergeHotels(first, second []models.Hotel, conflict string) []models.Hotel {
+	kept, other := copyHotels(first), copyHotels(second)
+	if conflict == models.MergeKeepSecond {
+		kept, other = other, kept
+	}
+
+	var rest []models.Hotel
+	for _, hotel := range other {
+		joined := false
+		for i := range kept {
+			k := &kept[i]
+			if strings.EqualFold(k.Name, hotel.Name) && !dateOf(hotel.CheckInDate).After(dateOf(k.CheckOutDate)) &&
+				!dateOf(k.CheckInDate).After(dateOf(hotel.CheckOutDate)) {
+				if hotel.CheckInDate.Before(k.CheckInDate) {
+					k.CheckInDate = hotel.CheckInDate
+				}
+				if hotel.CheckOutDate.After(k.CheckOutDate) {
+					k.CheckOutDate = hotel.CheckOutDate
+				}
+				added := daysBetween(k.CheckInDate, k.CheckOutDate) - k.Nights
+				if added > 0 && hotel.Nights > 0 {
+					k.Price = addPrices(k.Price, scalePrice(hotel.Price, nightShare(added, hotel.Nights)))
+				}
+				k.Nights = daysBetween(k.CheckInDate, k.CheckOutDate)
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			rest = append(rest, hotel)
+		}
+	}
+
+	booked := make(map[time.Time]bool)
+	for _, hotel := range kept {
+		for night := dateOf(hotel.CheckInDate); night.Before(dateOf(hotel.CheckOutDate)); night = night.AddDate(0, 0, 1) {
+			booked[night] = true
+		}
+	}
+
+	hotels := kept
+	for _, hotel := range rest {
+		if conflict == models.MergeCombine {
+			hotels = append(hotels, hotel)
+			continue
+		}
+		// keep every run of nights that isn't booked yet as its own stay
+		var runStart *time.Time
+		end := dateOf(hotel.CheckOutDate)
+		for night := dateOf(hotel.CheckInDate); !night.After(end); night = night.AddDate(0, 0, 1) {
+			free := night.Before(end) && !booked[night]
+			switch {
+			case free && runStart == nil:
+				start := night
+				runStart = &start
+			case !free && runStart != nil:
+				stay := hotel
+				stay.Coordinates = copyCoordinates(hotel.Coordinates)
+				stay.CheckInDate, stay.CheckOutDate = *runStart, night
+				stay.Nights = daysBetween(stay.CheckInDate, stay.CheckOutDate)
+				stay.Price = scalePrice(hotel.Price, nightShare(stay.Nights, hotel.Nights))
+				hotels = append(hotels, stay)
+				runStart = nil
+			}
+		}
+	}
+
+	sort.SliceStable(hotels, func(i, j int) bool { return hotels[i].CheckInDate.Before(hotels[j].CheckInDate) })
+	return hotels
+}
+
+// containsTraveller tells whether a traveller with the given ID is in the list
+func containsTraveller(travellers []models.Traveller, id string) bool {
+	for _, traveller := range travellers {
+		if traveller.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFlight tells whether the same flight is already in the list
+func containsFlight(flights []models.Flight, flight models.Flight) bool {
+	for _, f := range flights {
+		if strings.EqualFold(f.FlightNumber, flight.FlightNumber) && f.Departure.Equal(flight.Departure) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsTransfer tells whether the same transfer is already in the list
+func containsTransfer(transfers []models.Transfer, transfer models.Transfer) bool {
+	for _, t := range transfers {
+		if strings.EqualFold(t.From, transfer.From) && strings.EqualFold(t.To, transfer.To) && t.Timing.Equal(transfer.Timing) {
+			return true
+		}
+	}
+	return false
+}
+
+// unionStrings joins two lists, skipping entries already present
+func unionStrings(a, b []string) []string {
+	union := append([]string{}, a...)
+	for _, s := range b {
+		found := false
+		for _, u := range union {
+			if strings.EqualFold(u, s) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			union = append(union, s)
+		}
+	}
+	return union
+}
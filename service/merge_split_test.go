@@ -0,0 +1,198 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// cash returns an amount in a currency
+func cash(amount, currency string) models.Money {
+	return models.NewMoney(decimal.RequireFromString(amount), currency)
+}
+
+// splitCurrencies returns a currency service converting rupees, euros and
+// pounds; pounds have no rate
+func splitCurrencies() *CurrencyService {
+	rates, err := ParseExchangeRates("EUR=1,INR=90.12")
+	if err != nil {
+		panic(err)
+	}
+	return NewCurrencyService("INR", rates)
+}
+
+// planDue is the due date of every installment of planOf
+var planDue = time.Date(2025, time.September, 1, 0, 0, 0, 0, time.UTC)
+
+// planOf returns a payment plan in INR with one installment per amount
+func planOf(amountDue string, amounts ...models.Money) models.PaymentPlan {
+	plan := models.PaymentPlan{AmountDue: cash(amountDue, "INR"), DueDate: planDue}
+	for i, amount := range amounts {
+		plan.Installments = append(plan.Installments, models.Installment{
+			InstallmentNumber: i + 1,
+			Amount:            amount,
+			DueDate:           planDue,
+			Status:            "Pending",
+		})
+	}
+	return plan
+}
+
+// installmentAmounts returns the amounts of the installments of a plan
+func installmentAmounts(plan models.PaymentPlan) []models.Money {
+	amounts := make([]models.Money, len(plan.Installments))
+	for i, installment := range plan.Installments {
+		amounts[i] = installment.Amount
+	}
+	return amounts
+}
+
+// sameAmounts tells whether two lists hold the same amounts in the same currencies
+func sameAmounts(a, b []models.Money) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSplitPaymentPlan(t *testing.T) {
+	tests := []struct {
+		name        string
+		plan        models.PaymentPlan
+		share       string
+		first       []models.Money
+		second      []models.Money
+		firstTotal  string
+		secondTotal string
+		wantErr     error
+	}{
+		{"halves", planOf("1000", cash("300", "INR"), cash("700", "INR")), "0.5",
+			[]models.Money{cash("150", "INR"), cash("350", "INR")},
+			[]models.Money{cash("150", "INR"), cash("350", "INR")}, "500", "500", nil},
+		{"rounded to the minor units", planOf("100", cash("100", "INR")), "0.333333",
+			[]models.Money{cash("33.33", "INR")},
+			[]models.Money{cash("66.67", "INR")}, "33.33", "66.67", nil},
+		{"installments keep their currency", planOf("46060", cash("500", "EUR"), cash("1000", "INR")), "0.5",
+			[]models.Money{cash("250", "EUR"), cash("500", "INR")},
+			[]models.Money{cash("250", "EUR"), cash("500", "INR")}, "23030", "23030", nil},
+		{"empty parts are dropped", planOf("1000", cash("1000", "INR")), "0",
+			nil, []models.Money{cash("1000", "INR")}, "0", "1000", nil},
+		{"missing rate", planOf("1000", cash("10", "GBP")), "0.5", nil, nil, "", "", ErrNoExchangeRate},
+	}
+
+	currencies := splitCurrencies()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			first, second, err := splitPaymentPlan(test.plan, decimal.RequireFromString(test.share), currencies)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := installmentAmounts(first); !sameAmounts(got, test.first) {
+				t.Errorf("first part: got %v, want %v", got, test.first)
+			}
+			if got := installmentAmounts(second); !sameAmounts(got, test.second) {
+				t.Errorf("second part: got %v, want %v", got, test.second)
+			}
+			if !first.AmountDue.Equal(cash(test.firstTotal, "INR")) || !second.AmountDue.Equal(cash(test.secondTotal, "INR")) {
+				t.Errorf("got amounts due %s and %s, want %s and %s", first.AmountDue.Amount, second.AmountDue.Amount,
+					test.firstTotal, test.secondTotal)
+			}
+			for i, installment := range second.Installments {
+				if installment.InstallmentNumber != i+1 {
+					t.Errorf("second part: installment %d is numbered %d", i+1, installment.InstallmentNumber)
+				}
+			}
+		})
+	}
+}
+
+func TestMergePaymentPlans(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  models.PaymentPlan
+		total string
+	}{
+		{"same currency", planOf("1000", cash("1000", "INR")), planOf("500", cash("500", "INR")), "1500"},
+		{"foreign installments", planOf("1000", cash("1000", "INR")), planOf("45060", cash("500", "EUR")), "46060"},
+		{"rates of the first plan", func() models.PaymentPlan {
+			plan := planOf("50000", cash("500", "EUR"))
+			plan.ExchangeRates = map[string]decimal.Decimal{"EUR": decimal.NewFromInt(1), "INR": decimal.NewFromInt(100)}
+			return plan
+		}(), planOf("45060", cash("500", "EUR")), "100000"},
+	}
+
+	currencies := splitCurrencies()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			merged, err := mergePaymentPlans(test.a, test.b, currencies)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !merged.AmountDue.Equal(cash(test.total, "INR")) {
+				t.Errorf("got %s, want %s", merged.AmountDue.Amount, test.total)
+			}
+			if len(merged.Installments) != len(test.a.Installments)+len(test.b.Installments) {
+				t.Errorf("got %d installments", len(merged.Installments))
+			}
+		})
+	}
+}
+
+func TestMergeDays(t *testing.T) {
+	date := func(d int) time.Time { return time.Date(2025, time.September, d, 0, 0, 0, 0, time.UTC) }
+	first := &models.Itinerary{Days: []models.Day{
+		{DayNumber: 1, Date: date(1), Title: "Lisbon", Activities: models.Activities{Morning: []models.Activity{{Name: "Belém"}}}},
+		{DayNumber: 2, Date: date(2), Title: "Sintra", Activities: models.Activities{Morning: []models.Activity{{Name: "Pena palace"}}}},
+	}}
+	second := &models.Itinerary{Days: []models.Day{
+		{DayNumber: 1, Date: date(2), Title: "Cascais", Activities: models.Activities{Evening: []models.Activity{{Name: "Boca do Inferno"}}}},
+		{DayNumber: 2, Date: date(3), Title: "Porto"},
+	}}
+
+	tests := []struct {
+		conflict string
+		want     []string
+	}{
+		{models.MergeCombine, []string{"1 Lisbon [Belém]", "2 Sintra / Cascais [Pena palace Boca do Inferno]", "3 Porto []"}},
+		{models.MergeKeepFirst, []string{"1 Lisbon [Belém]", "2 Sintra [Pena palace]", "3 Porto []"}},
+		{models.MergeKeepSecond, []string{"1 Lisbon [Belém]", "2 Cascais [Boca do Inferno]", "3 Porto []"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.conflict, func(t *testing.T) {
+			var got []string
+			for i, day := range mergeDays(first, second, date(1), date(3), test.conflict) {
+				if !day.Date.Equal(date(i + 1)) {
+					t.Errorf("day %d is on %s", day.DayNumber, day.Date)
+				}
+				var names []string
+				for _, activity := range append(append(day.Activities.Morning, day.Activities.Afternoon...), day.Activities.Evening...) {
+					names = append(names, activity.Name)
+				}
+				got = append(got, fmt.Sprintf("%d %s %v", day.DayNumber, day.Title, names))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+
+	// combining copies the activities instead of sharing them
+	merged := mergeDays(first, second, date(1), date(3), models.MergeCombine)
+	merged[1].Activities.Morning[0].Name = "Monserrate"
+	if first.Days[1].Activities.Morning[0].Name != "Pena palace" {
+		t.Error("changing the merged day changed the original")
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// addManifest lists the travellers with their personal details, followed by
+// their seats and tickets on every flight and their rooms at every hotel
+func (s *PDFService) addManifest(pdf *gofpdf.Fpdf, itinerary *models.Itinerary) {
+	s.addSectionTitle(pdf, "Passenger Manifest")
+
+	for i, traveller := range itinerary.Travellers {
+		pdf.SetFont("Arial", "B", 12)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(0, 8, fmt.Sprintf("%d. %s", i+1, traveller.Name), "", 1, "L", false, 0, "")
+
+		pdf.SetFont("Arial", "", 10)
+		pdf.SetTextColor(60, 60, 60)
+		if traveller.DateOfBirth != nil {
+			pdf.MultiCell(0, 5, fmt.Sprintf("Date of birth: %s", traveller.DateOfBirth.Format("January 2, 2006")), "", "L", false)
+		}
+		if traveller.Nationality != "" {
+			pdf.MultiCell(0, 5, fmt.Sprintf("Nationality: %s", traveller.Nationality), "", "L", false)
+		}
+		if traveller.PassportNumber != "" || traveller.PassportExpiry != nil {
+			passport := "Passport:"
+			if traveller.PassportNumber != "" {
+				passport += " " + maskPassport(traveller.PassportNumber)
+			}
+			if traveller.PassportExpiry != nil {
+				passport += fmt.Sprintf(" (expires %s)", traveller.PassportExpiry.Format("January 2, 2006"))
+			}
+			pdf.MultiCell(0, 5, passport, "", "L", false)
+		}
+		if len(traveller.DietaryNeeds) > 0 {
+			pdf.MultiCell(0, 5, fmt.Sprintf("Dietary needs: %s", strings.Join(traveller.DietaryNeeds, ", ")), "", "L", false)
+		}
+		if contact := traveller.EmergencyContact; contact != nil {
+			line := fmt.Sprintf("Emergency contact: %s, %s", contact.Name, contact.Phone)
+			if contact.Relationship != "" {
+				line = fmt.Sprintf("Emergency contact: %s (%s), %s", contact.Name, contact.Relationship, contact.Phone)
+			}
+			pdf.MultiCell(0, 5, line, "", "L", false)
+		}
+
+		pdf.Ln(3)
+	}
+
+	// Seats and tickets
+	for _, flight := range itinerary.Flights {
+		if len(flight.Passengers) == 0 {
+			continue
+		}
+		pdf.Ln(2)
+		pdf.SetFont("Arial", "B", 11)
+		pdf.SetTextColor(70, 130, 180)
+		pdf.CellFormat(0, 7, fmt.Sprintf("Flight %s: %s to %s", flight.FlightNumber, flight.From, flight.To), "", 1, "L", false, 0, "")
+
+		pdf.SetFont("Arial", "", 10)
+		pdf.SetTextColor(60, 60, 60)
+		for _, passenger := range flight.Passengers {
+			line := travellerNames(itinerary.Travellers, []string{passenger.TravellerID})[0]
+			if passenger.Seat != "" {
+				line += fmt.Sprintf(" - seat %s", passenger.Seat)
+			}
+			if passenger.TicketNumber != "" {
+				line += fmt.Sprintf(" - ticket %s", passenger.TicketNumber)
+			}
+			pdf.MultiCell(0, 5, line, "", "L", false)
+		}
+	}
+
+	// Rooms
+	for _, hotel := range itinerary.Hotels {
+		if len(hotel.Rooms) == 0 {
+			continue
+		}
+		pdf.Ln(2)
+		pdf.SetFont("Arial", "B", 11)
+		pdf.SetTextColor(70, 130, 180)
+		pdf.CellFormat(0, 7, fmt.Sprintf("%s, %s", hotel.Name, hotel.CheckInDate.Format("January 2")), "", 1, "L", false, 0, "")
+
+		pdf.SetFont("Arial", "", 10)
+		pdf.SetTextColor(60, 60, 60)
+		for n, room := range hotel.Rooms {
+			label := fmt.Sprintf("Room %d", n+1)
+			if room.RoomNumber != "" {
+				label = fmt.Sprintf("Room %s", room.RoomNumber)
+			}
+			if room.RoomType != "" {
+				label += fmt.Sprintf(" (%s)", room.RoomType)
+			}
+			pdf.MultiCell(0, 5, fmt.Sprintf("%s: %s", label, strings.Join(travellerNames(itinerary.Travellers, room.TravellerIDs), ", ")), "", "L", false)
+		}
+	}
+}
+
+// maskPassport hides all but the last four characters of a passport number
+func maskPassport(number string) string {
+	if len(number) <= 4 {
+		return number
+	}
+	return strings.Repeat("*", len(number)-4) + number[len(number)-4:]
+}
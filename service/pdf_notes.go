@@ -0,0 +1,111 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// pdfNotes are the customer comment threads printed next to the elements
+// they were left on
+type pdfNotes []models.CommentThread
+
+// customerNotes loads the customer notes of an itinerary, leaving out the
+// ones on removed elements. Without a comment service, or when they can't be
+// loaded, the PDF is rendered without notes.
+func (s *PDFService) customerNotes(itinerary *models.Itinerary) pdfNotes {
+	if s.comments == nil {
+		return nil
+	}
+	threads, err := s.comments.CustomerNotes(itinerary.ID)
+	if err != nil {
+		return nil
+	}
+
+	var notes pdfNotes
+	for _, thread := range threads {
+		if !thread.Detached {
+			notes = append(notes, thread)
+		}
+	}
+	return notes
+}
+
+// matching returns the threads left on the elements accepted by match
+func (n pdfNotes) matching(match func(ref models.ElementRef) bool) pdfNotes {
+	var found pdfNotes
+	for _, thread := range n {
+		if match(thread.ElementRef) {
+			found = append(found, thread)
+		}
+	}
+	return found
+}
+
+// forItinerary returns the notes on the itinerary as a whole
+func (n pdfNotes) forItinerary() pdfNotes {
+	return n.matching(func(ref models.ElementRef) bool {
+		return ref.Day == 0 && ref.Hotel == nil && ref.Flight == "" && ref.Installment == 0
+	})
+}
+
+// forDay returns the notes on a day, not on its activities
+func (n pdfNotes) forDay(dayNumber int) pdfNotes {
+	return n.matching(func(ref models.ElementRef) bool {
+		return ref.Day == dayNumber && ref.Activity == nil
+	})
+}
+
+// forActivity returns the notes on an activity of a day's slot
+func (n pdfNotes) forActivity(dayNumber int, slot string, index int) pdfNotes {
+	return n.matching(func(ref models.ElementRef) bool {
+		return ref.Day == dayNumber && ref.Slot == slot && ref.Activity != nil && *ref.Activity == index
+	})
+}
+
+// forHotel returns the notes on the hotel at an index
+func (n pdfNotes) forHotel(index int) pdfNotes {
+	return n.matching(func(ref models.ElementRef) bool {
+		return ref.Hotel != nil && *ref.Hotel == index
+	})
+}
+
+// forFlight returns the notes on a flight
+func (n pdfNotes) forFlight(flightNumber string) pdfNotes {
+	return n.matching(func(ref models.ElementRef) bool {
+		return ref.Flight == flightNumber
+	})
+}
+
+// forInstallment returns the notes on an installment
+func (n pdfNotes) forInstallment(number int) pdfNotes {
+	return n.matching(func(ref models.ElementRef) bool {
+		return ref.Installment == number
+	})
+}
+
+// addNotes prints note threads with their replies indented below them
+func (s *PDFService) addNotes(pdf *gofpdf.Fpdf, notes pdfNotes) {
+	if len(notes) == 0 {
+		return
+	}
+
+	left, _, _, _ := pdf.GetMargins()
+	var write func(threads []models.CommentThread, indent float64, label string)
+	write = func(threads []models.CommentThread, indent float64, label string) {
+		for _, thread := range threads {
+			pdf.SetLeftMargin(left + indent)
+			pdf.SetX(left + indent)
+			pdf.SetFont("Arial", "I", 9)
+			pdf.SetTextColor(139, 69, 19)
+			pdf.MultiCell(0, 5, fmt.Sprintf("%s from %s: %s", label, thread.Author, thread.Text), "", "L", false)
+			write(thread.Replies, indent+5, "Reply")
+		}
+	}
+	write(notes, 0, "Note")
+
+	pdf.SetLeftMargin(left)
+	pdf.SetX(left)
+	pdf.Ln(2)
+}
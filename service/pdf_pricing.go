@@ -0,0 +1,60 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// addPriceBreakdown draws the itemised price of the itinerary as a table
+// with one row per priced element and a row with the totals. Elements priced
+// in another currency show their price and taxes in that currency and their
+// total in the currency of the payment plan.
+func (s *PDFService) addPriceBreakdown(pdf *gofpdf.Fpdf, breakdown *models.PriceBreakdown) {
+	pdf.Ln(5)
+	pdf.SetFont("Arial", "B", 11)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.CellFormat(0, 8, "Price Breakdown:", "", 1, "L", false, 0, "")
+
+	widths := []float64{85, 37, 31, 37}
+	row := func(cells []string, style string, fill bool) {
+		pdf.SetFont("Arial", style, 9)
+		for i, cell := range cells {
+			align := "R"
+			if i == 0 {
+				align = "L"
+				cell = fitText(pdf, cell, widths[i]-2)
+			}
+			pdf.CellFormat(widths[i], 7, cell, "1", 0, align, fill, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	pdf.SetFillColor(230, 230, 250)
+	pdf.SetTextColor(25, 25, 112)
+	row([]string{"Item", "Price", "Taxes", "Total"}, "B", true)
+
+	pdf.SetTextColor(60, 60, 60)
+	for _, line := range breakdown.Lines {
+		price := models.NewMoney(line.Net.Amount.Add(line.Markup.Amount), line.Net.Currency)
+		row([]string{line.Description, s.currencies.Format(price),
+			s.currencies.Format(line.Taxes), s.currencies.Format(line.CustomerTotal)}, "", false)
+	}
+
+	pdf.SetTextColor(0, 0, 0)
+	price := models.NewMoney(breakdown.Net.Amount.Add(breakdown.Markup.Amount), breakdown.Net.Currency)
+	row([]string{"Total", s.currencies.Format(price),
+		s.currencies.Format(breakdown.Taxes), s.currencies.Format(breakdown.Total)}, "B", true)
+}
+
+// fitText shortens text with an ellipsis until it fits in width
+func fitText(pdf *gofpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
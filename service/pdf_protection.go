@@ -0,0 +1,127 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jung-kurt/gofpdf"
+)
+
+var (
+	ErrPasswordRequired    = errors.New("a user password is required to protect the PDF")
+	ErrUnknownPasswordRule = errors.New("unknown PDF password rule")
+)
+
+// password rules used to derive the user password of a protected PDF
+const (
+	PasswordRuleNone               = ""
+	PasswordRuleConfirmationNumber = "confirmation_number"
+	PasswordRuleBookingReference   = "booking_reference"
+	PasswordRuleUserID             = "user_id"
+	PasswordRuleItineraryID        = "itinerary_id"
+)
+
+// PDFProtection encrypts a PDF and restricts what readers can do with it
+type PDFProtection struct {
+	UserPassword  string `json:"user_password"`
+	OwnerPassword string `json:"owner_password"`
+	AllowPrint    bool   `json:"allow_print"`
+	AllowCopy     bool   `json:"allow_copy"`
+	AllowModify   bool   `json:"allow_modify"`
+}
+
+// resolveProtection works out the protection for a render. An explicit request
+// wins, otherwise the configured password rule protects every document.
+func (s *PDFService) resolveProtection(itinerary *models.Itinerary, requested *PDFProtection) (*PDFProtection, error) {
+	if requested == nil && s.passwordRule == PasswordRuleNone {
+		return nil, nil
+	}
+
+	protection := PDFProtection{}
+	if requested != nil {
+		protection = *requested
+	}
+
+	if protection.UserPassword == "" {
+		password, err := derivePassword(s.passwordRule, itinerary)
+		if err != nil {
+			return nil, err
+		}
+		protection.UserPassword = password
+	}
+
+	if protection.OwnerPassword == "" {
+		protection.OwnerPassword = s.ownerPassword
+	}
+	if protection.OwnerPassword == "" {
+		// without a known owner password the restrictions can't be lifted
+		protection.OwnerPassword = uuid.New().String()
+	}
+
+	return &protection, nil
+}
+
+// derivePassword derives a user password from the itinerary using a rule.
+// An itinerary without a value for the rule, e.g. no confirmation number
+// before it is confirmed, needs a password in the request: falling back to
+// another field could end on one printed in the document itself.
+func derivePassword(rule string, itinerary *models.Itinerary) (string, error) {
+	var password string
+	switch rule {
+	case PasswordRuleNone:
+		return "", ErrPasswordRequired
+	case PasswordRuleConfirmationNumber:
+		password = itinerary.ConfirmationNumber
+	case PasswordRuleBookingReference:
+		password = bookingReference(itinerary)
+	case PasswordRuleUserID:
+		password = itinerary.UserID
+	case PasswordRuleItineraryID:
+		password = itinerary.ID
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownPasswordRule, rule)
+	}
+	if password == "" {
+		return "", fmt.Errorf("%w: itinerary has no %s, send a user_password", ErrPasswordRequired, rule)
+	}
+	return password, nil
+}
+
+// bookingReference returns the first booking reference of the itinerary,
+// looking at flights, then hotels, then transfers
+func bookingReference(itinerary *models.Itinerary) string {
+	for _, flight := range itinerary.Flights {
+		if flight.BookingReference != "" {
+			return flight.BookingReference
+		}
+	}
+	for _, hotel := range itinerary.Hotels {
+		if hotel.BookingReference != "" {
+			return hotel.BookingReference
+		}
+	}
+	for _, transfer := range itinerary.Transfers {
+		if transfer.BookingReference != "" {
+			return transfer.BookingReference
+		}
+	}
+	return ""
+}
+
+// applyProtection encrypts the document with the given passwords and permissions
+func applyProtection(pdf *gofpdf.Fpdf, protection *PDFProtection) {
+	var permissions byte
+	if protection.AllowPrint {
+		permissions |= gofpdf.CnProtectPrint
+	}
+	if protection.AllowCopy {
+		permissions |= gofpdf.CnProtectCopy
+	}
+	if protection.AllowModify {
+		permissions |= gofpdf.CnProtectModify | gofpdf.CnProtectAnnotForms
+	}
+
+	pdf.SetProtection(permissions, protection.UserPassword, protection.OwnerPassword)
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"testing"
+)
+
+func TestDerivePassword(t *testing.T) {
+	booked := &models.Itinerary{
+		ID:                 "it-42",
+		UserID:             "user-7",
+		ConfirmationNumber: "VGV-1A2B3C4D",
+		Hotels:             []models.Hotel{{Name: "Hotel Roma", BookingReference: "HTL-9"}},
+		Flights:            []models.Flight{{FlightNumber: "AZ101"}, {FlightNumber: "AZ102", BookingReference: "PNR123"}},
+	}
+	draft := &models.Itinerary{ID: "it-43"}
+
+	tests := []struct {
+		name      string
+		rule      string
+		itinerary *models.Itinerary
+		want      string
+		wantErr   error
+	}{
+		{"confirmation number", PasswordRuleConfirmationNumber, booked, "VGV-1A2B3C4D", nil},
+		{"first flight booking reference", PasswordRuleBookingReference, booked, "PNR123", nil},
+		{"user id", PasswordRuleUserID, booked, "user-7", nil},
+		{"itinerary id", PasswordRuleItineraryID, draft, "it-43", nil},
+		{"not confirmed yet", PasswordRuleConfirmationNumber, draft, "", ErrPasswordRequired},
+		{"no booking reference", PasswordRuleBookingReference, draft, "", ErrPasswordRequired},
+		{"no rule", PasswordRuleNone, booked, "", ErrPasswordRequired},
+		{"unknown rule", "passport_number", booked, "", ErrUnknownPasswordRule},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := derivePassword(test.rule, test.itinerary)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
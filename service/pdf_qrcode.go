@@ -0,0 +1,44 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/boombuler/barcode/qr"
+	"github.com/jung-kurt/gofpdf"
+)
+
+// drawQRCode renders content as a vector QR code of the given size at (x, y).
+// The code is generated locally, no external service is involved.
+func drawQRCode(pdf *gofpdf.Fpdf, content string, x, y, size float64) {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		pdf.SetError(fmt.Errorf("failed to encode QR code: %w", err))
+		return
+	}
+
+	bounds := code.Bounds()
+	modules := bounds.Dx()
+	module := size / float64(modules)
+
+	pdf.SetFillColor(0, 0, 0)
+	for row := 0; row < modules; row++ {
+		// draw each horizontal run of dark modules as one rectangle
+		start := -1
+		for col := 0; col <= modules; col++ {
+			dark := false
+			if col < modules {
+				r, _, _, _ := code.At(bounds.Min.X+col, bounds.Min.Y+row).RGBA()
+				dark = r == 0
+			}
+
+			if dark && start < 0 {
+				start = col
+			}
+			if !dark && start >= 0 {
+				pdf.Rect(x+float64(start)*module, y+float64(row)*module,
+					float64(col-start)*module, module, "F")
+				start = -1
+			}
+		}
+	}
+}
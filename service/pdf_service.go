@@ -1,10 +1,12 @@
 package service
 
 import (
+	"bytes"
 	"example/vigovia-itenary-api/models"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/jung-kurt/gofpdf"
@@ -12,20 +14,143 @@ import (
 
 // PDFService handles PDF generation
 type PDFService struct {
-	outputDir string
+	outputDir    string
+	defaultTheme string
+	themes       map[string]PDFTheme
+
+	passwordRule  string
+	ownerPassword string
+
+	shareLinks *ShareLinkService
+	agency     AgencyContact
+	coverage   *CoverageService
+	journeys   *JourneyService
+	comments   *CommentService
+	currencies *CurrencyService
 }
 
-// NewPDFService creates a new PDF service
-func NewPDFService(outputDir string) *PDFService {
-	return &PDFService{
-		outputDir: outputDir,
+// PDFSettings holds the service wide PDF configuration
+type PDFSettings struct {
+	DefaultTheme string
+
+	// Themes are added to the built-in themes, replacing those of the
+	// same name
+	Themes []PDFTheme
+
+	// PasswordRule derives a user password for every document when set
+	PasswordRule  string
+	OwnerPassword string
+
+	// ShareLinks adds QR codes linking to the live itinerary when set
+	ShareLinks *ShareLinkService
+
+	// Agency contact printed on vouchers
+	Agency AgencyContact
+
+	// Journeys chains the flights for the journey overview, a service with
+	// the default minimum connection time is used when nil
+	Journeys *JourneyService
+
+	// Comments prints the customer notes next to the elements they were
+	// left on when set
+	Comments *CommentService
+
+	// Currencies formats and converts amounts, a service with the default
+	// currency and no exchange rates is used when nil
+	Currencies *CurrencyService
+}
+
+// PDFOptions customises a single PDF render
+type PDFOptions struct {
+	Theme      string         `json:"theme"`
+	Protection *PDFProtection `json:"protection"`
+}
+
+// NewPDFService creates a new PDF service. It fails when the default theme
+// is not one of the available themes, so a typo is caught at startup rather
+// than on every render.
+func NewPDFService(outputDir string, settings PDFSettings) (*PDFService, error) {
+	defaultTheme := settings.DefaultTheme
+	if defaultTheme == "" {
+		defaultTheme = DefaultThemeName
+	}
+
+	themes := builtinThemes()
+	for _, theme := range settings.Themes {
+		themes[theme.Name] = theme
+	}
+	if _, ok := themes[defaultTheme]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTheme, defaultTheme)
+	}
+
+	journeys := settings.Journeys
+	if journeys == nil {
+		journeys = NewJourneyService(DefaultMinConnectionTime)
+	}
+
+	currencies := settings.Currencies
+	if currencies == nil {
+		currencies = NewCurrencyService(DefaultCurrency, nil)
 	}
+
+	return &PDFService{
+		outputDir:    outputDir,
+		defaultTheme: defaultTheme,
+		themes:       themes,
+
+		passwordRule:  settings.PasswordRule,
+		ownerPassword: settings.OwnerPassword,
+
+		shareLinks: settings.ShareLinks,
+		agency:     settings.Agency,
+		coverage:   NewCoverageService(),
+		journeys:   journeys,
+		comments:   settings.Comments,
+		currencies: currencies,
+	}, nil
 }
 
-// GeneratePDF creates a PDF from an itinerary
+// GeneratePDF creates a PDF from an itinerary using the default options
 func (s *PDFService) GeneratePDF(itinerary *models.Itinerary) (string, error) {
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.SetAutoPageBreak(true, 15)
+	return s.GeneratePDFWithOptions(itinerary, PDFOptions{})
+}
+
+// GeneratePDFWithOptions creates a PDF from an itinerary
+func (s *PDFService) GeneratePDFWithOptions(itinerary *models.Itinerary, opts PDFOptions) (string, error) {
+	pdf, err := s.renderItinerary(itinerary, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// Generate filename
+	filename := fmt.Sprintf("itinerary_%s_%s.pdf", itinerary.ID, time.Now().Format("20060102_150405"))
+
+	return s.save(pdf, filename)
+}
+
+// RenderPDF renders the itinerary PDF into memory instead of the output directory
+func (s *PDFService) RenderPDF(itinerary *models.Itinerary, opts PDFOptions) ([]byte, error) {
+	pdf, err := s.renderItinerary(itinerary, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render PDF: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// renderItinerary lays out the complete itinerary document
+func (s *PDFService) renderItinerary(itinerary *models.Itinerary, opts PDFOptions) (*gofpdf.Fpdf, error) {
+	pdf, err := s.newDocument(itinerary, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	notes := s.customerNotes(itinerary)
 
 	// Add first page
 	pdf.AddPage()
@@ -36,37 +161,91 @@ func (s *PDFService) GeneratePDF(itinerary *models.Itinerary) (string, error) {
 	// Trip overview
 	pdf.AddPage()
 	s.addTripOverview(pdf, itinerary)
+	s.addNotes(pdf, notes.forItinerary())
+
+	// Accommodation issues
+	if report := s.coverage.Analyze(itinerary); report.HasIssues() {
+		pdf.AddPage()
+		s.addIssues(pdf, report)
+	}
 
 	// Day-wise itinerary
 	for _, day := range itinerary.Days {
 		pdf.AddPage()
-		s.addDayDetails(pdf, &day)
+		s.addDayDetails(pdf, &day, notes)
 	}
 
 	// Hotels
 	pdf.AddPage()
-	s.addHotels(pdf, itinerary.Hotels)
+	s.addHotels(pdf, itinerary.Hotels, notes)
 
 	// Flights
 	pdf.AddPage()
-	s.addFlights(pdf, itinerary.Flights)
+	s.addFlights(pdf, itinerary.ID, itinerary.Flights, notes)
+
+	// Journey overview with connections and travel time
+	if len(itinerary.Flights) > 0 {
+		pdf.AddPage()
+		s.addJourney(pdf, s.journeys.Analyze(itinerary))
+	}
 
 	// Transfers
 	if len(itinerary.Transfers) > 0 {
 		pdf.AddPage()
-		s.addTransfers(pdf, itinerary.Transfers)
+		s.addTransfers(pdf, itinerary)
 	}
 
-	// Payment plan
+	// Passenger manifest
+	if len(itinerary.Travellers) > 0 {
+		pdf.AddPage()
+		s.addManifest(pdf, itinerary)
+	}
+
+	// Payment plan, itemised when the theme shows prices
 	pdf.AddPage()
-	s.addPaymentPlan(pdf, &itinerary.PaymentPlan)
+	s.addPaymentPlan(pdf, &itinerary.PaymentPlan, notes)
+	if theme, err := s.theme(opts.Theme); err == nil && theme.ShowPriceBreakdown && hasPricing(itinerary) {
+		if breakdown, err := priceBreakdown(itinerary, s.currencies.forPlan(itinerary.PaymentPlan)); err == nil {
+			s.addPriceBreakdown(pdf, breakdown)
+		}
+	}
 
 	// Inclusions and Exclusions
 	pdf.AddPage()
 	s.addInclusionsExclusions(pdf, itinerary.Inclusions, itinerary.Exclusions)
 
-	// Generate filename
-	filename := fmt.Sprintf("itinerary_%s_%s.pdf", itinerary.ID, time.Now().Format("20060102_150405"))
+	return pdf, nil
+}
+
+// newDocument creates an empty A4 document with the theme, protection and
+// status markings requested for the itinerary
+func (s *PDFService) newDocument(itinerary *models.Itinerary, opts PDFOptions) (*gofpdf.Fpdf, error) {
+	theme, err := s.theme(opts.Theme)
+	if err != nil {
+		return nil, err
+	}
+
+	protection, err := s.resolveProtection(itinerary, opts.Protection)
+	if err != nil {
+		return nil, err
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetAutoPageBreak(true, 15)
+
+	// Encryption and permissions
+	if protection != nil {
+		applyProtection(pdf, protection)
+	}
+
+	// Status markings on every page
+	s.addStatusMarkings(pdf, itinerary, theme)
+
+	return pdf, nil
+}
+
+// save writes the document into the output directory
+func (s *PDFService) save(pdf *gofpdf.Fpdf, filename string) (string, error) {
 	filepath := filepath.Join(s.outputDir, filename)
 
 	if err:=os.MkdirAll(s.outputDir, 0755);err!=nil{
@@ -81,6 +260,69 @@ func (s *PDFService) GeneratePDF(itinerary *models.Itinerary) (string, error) {
 	return filepath, nil
 }
 
+// addStatusMarkings stamps unconfirmed, cancelled and expired documents with
+// a watermark and bookings with their confirmation number and issue date
+func (s *PDFService) addStatusMarkings(pdf *gofpdf.Fpdf, itinerary *models.Itinerary, theme *PDFTheme) {
+	var watermark string
+	switch itinerary.Status {
+	case models.StatusConfirmed, models.StatusInProgress, models.StatusCompleted:
+	case models.StatusQuoted:
+		watermark = theme.QuoteWatermark
+	case models.StatusCancelled:
+		watermark = theme.CancelledWatermark
+	case models.StatusExpired:
+		watermark = theme.ExpiredWatermark
+	default:
+		watermark = theme.DraftWatermark
+	}
+
+	if watermark != "" {
+		pdf.SetHeaderFunc(func() {
+			s.addWatermark(pdf, watermark, theme)
+		})
+	}
+
+	if itinerary.ConfirmationNumber != "" && watermark == "" {
+		issued := time.Now()
+		if itinerary.ConfirmedAt != nil {
+			issued = *itinerary.ConfirmedAt
+		}
+		footer := fmt.Sprintf("Confirmation No. %s  |  Issued %s",
+			itinerary.ConfirmationNumber, issued.Format("January 2, 2006"))
+
+		pdf.SetFooterFunc(func() {
+			pdf.SetY(-12)
+			pdf.SetFont("Arial", "", 8)
+			pdf.SetTextColor(100, 100, 100)
+			pdf.CellFormat(0, 5, footer, "", 0, "C", false, 0, "")
+		})
+	}
+}
+
+// addWatermark draws the watermark text diagonally across the current page
+func (s *PDFService) addWatermark(pdf *gofpdf.Fpdf, text string, theme *PDFTheme) {
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	text = tr(text)
+	pageW, pageH := pdf.GetPageSize()
+
+	// shrink the font until long texts still fit across the page
+	fontSize := 72.0
+	pdf.SetFont("Arial", "B", fontSize)
+	for fontSize > 20 && pdf.GetStringWidth(text) > pageH {
+		fontSize -= 4
+		pdf.SetFont("Arial", "B", fontSize)
+	}
+
+	c := theme.WatermarkColor
+	pdf.SetTextColor(c.R, c.G, c.B)
+	pdf.SetAlpha(theme.WatermarkAlpha, "Normal")
+	pdf.TransformBegin()
+	pdf.TransformRotate(55, pageW/2, pageH/2)
+	pdf.Text((pageW-pdf.GetStringWidth(text))/2, pageH/2, text)
+	pdf.TransformEnd()
+	pdf.SetAlpha(1, "Normal")
+}
+
 func (s *PDFService) addTitlePage(pdf *gofpdf.Fpdf, itinerary *models.Itinerary) {
 	// Title
 	pdf.SetFont("Arial", "B", 28)
@@ -119,6 +361,20 @@ func (s *PDFService) addTitlePage(pdf *gofpdf.Fpdf, itinerary *models.Itinerary)
 	pdf.SetFont("Arial", "B", 16)
 	pdf.SetTextColor(220, 20, 60)
 	pdf.CellFormat(0, 10, fmt.Sprintf("%d Days / %d Nights", duration, duration-1), "", 1, "C", false, 0, "")
+
+	// QR code linking to the live online itinerary
+	if s.shareLinks != nil {
+		const qrSize = 40.0
+		pageW, _ := pdf.GetPageSize()
+
+		pdf.Ln(15)
+		drawQRCode(pdf, s.shareLinks.ItineraryURL(itinerary.ID), (pageW-qrSize)/2, pdf.GetY(), qrSize)
+		pdf.SetY(pdf.GetY() + qrSize + 3)
+
+		pdf.SetFont("Arial", "I", 10)
+		pdf.SetTextColor(100, 100, 100)
+		pdf.CellFormat(0, 6, "Scan to view the latest version of your itinerary", "", 1, "C", false, 0, "")
+	}
 }
 
 func (s *PDFService) addTripOverview(pdf *gofpdf.Fpdf, itinerary *models.Itinerary) {
@@ -138,10 +394,54 @@ func (s *PDFService) addTripOverview(pdf *gofpdf.Fpdf, itinerary *models.Itinera
 	// Total amount
 	pdf.SetFont("Arial", "B", 12)
 	pdf.SetTextColor(220, 20, 60)
-	pdf.MultiCell(0, 8, fmt.Sprintf("Total Package Cost: %.2f", itinerary.PaymentPlan.AmountDue), "", "L", false)
+	pdf.MultiCell(0, 8, fmt.Sprintf("Total Package Cost: %s", s.currencies.Format(itinerary.PaymentPlan.AmountDue)), "", "L", false)
+}
+
+func (s *PDFService) addIssues(pdf *gofpdf.Fpdf, report *CoverageReport) {
+	s.addSectionTitle(pdf, "Issues")
+
+	pdf.SetFont("Arial", "I", 10)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.MultiCell(0, 5, "The following accommodation issues were found and should be resolved before travel.", "", "L", false)
+	pdf.Ln(4)
+
+	if len(report.UncoveredNights) > 0 {
+		s.addIssueGroup(pdf, "Nights without a hotel")
+		for _, night := range report.UncoveredNights {
+			pdf.MultiCell(0, 5, fmt.Sprintf("- Night of %s (Day %d)", night.Date.Format("Monday, January 2, 2006"), night.DayNumber), "", "L", false)
+		}
+		pdf.Ln(3)
+	}
+
+	if len(report.DoubleBookedNights) > 0 {
+		s.addIssueGroup(pdf, "Double-booked nights")
+		for _, night := range report.DoubleBookedNights {
+			pdf.MultiCell(0, 5, fmt.Sprintf("- Night of %s (Day %d): %s", night.Date.Format("Monday, January 2, 2006"),
+				night.DayNumber, strings.Join(night.Hotels, ", ")), "", "L", false)
+		}
+		pdf.Ln(3)
+	}
+
+	if len(report.CityMismatches) > 0 {
+		s.addIssueGroup(pdf, "Hotel city doesn't match the day's activities")
+		for _, mismatch := range report.CityMismatches {
+			pdf.MultiCell(0, 5, fmt.Sprintf("- Day %d: staying at %s (%s) but visiting %s", mismatch.DayNumber,
+				mismatch.Hotel, mismatch.HotelCity, strings.Join(mismatch.Locations, ", ")), "", "L", false)
+		}
+		pdf.Ln(3)
+	}
+}
+
+func (s *PDFService) addIssueGroup(pdf *gofpdf.Fpdf, title string) {
+	pdf.SetFont("Arial", "B", 12)
+	pdf.SetTextColor(220, 20, 60)
+	pdf.CellFormat(0, 8, title, "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.SetTextColor(60, 60, 60)
 }
 
-func (s *PDFService) addDayDetails(pdf *gofpdf.Fpdf, day *models.Day) {
+func (s *PDFService) addDayDetails(pdf *gofpdf.Fpdf, day *models.Day, notes pdfNotes) {
 	// Day header
 	pdf.SetFont("Arial", "B", 16)
 	pdf.SetTextColor(25, 25, 112)
@@ -150,34 +450,49 @@ func (s *PDFService) addDayDetails(pdf *gofpdf.Fpdf, day *models.Day) {
 	pdf.SetFont("Arial", "I", 10)
 	pdf.SetTextColor(100, 100, 100)
 	pdf.CellFormat(0, 6, day.Date.Format("Monday, January 2, 2006"), "", 1, "L", false, 0, "")
+	if day.TimeZone != "" {
+		pdf.CellFormat(0, 5, fmt.Sprintf("Local time: %s", day.TimeZone), "", 1, "L", false, 0, "")
+	}
 	
 	pdf.Ln(5)
+	s.addNotes(pdf, notes.forDay(day.DayNumber))
 
 	// Morning activities
 	if len(day.Activities.Morning) > 0 {
-		s.addTimeSlot(pdf, "Morning", day.Activities.Morning)
+		s.addTimeSlot(pdf, day, "Morning", day.Activities.Morning, func(i int) pdfNotes { return notes.forActivity(day.DayNumber, "morning", i) })
 	}
 
 	// Afternoon activities
 	if len(day.Activities.Afternoon) > 0 {
-		s.addTimeSlot(pdf, "Afternoon", day.Activities.Afternoon)
+		s.addTimeSlot(pdf, day, "Afternoon", day.Activities.Afternoon, func(i int) pdfNotes { return notes.forActivity(day.DayNumber, "afternoon", i) })
 	}
 
 	// Evening activities
 	if len(day.Activities.Evening) > 0 {
-		s.addTimeSlot(pdf, "Evening", day.Activities.Evening)
+		s.addTimeSlot(pdf, day, "Evening", day.Activities.Evening, func(i int) pdfNotes { return notes.forActivity(day.DayNumber, "evening", i) })
 	}
 }
 
-func (s *PDFService) addTimeSlot(pdf *gofpdf.Fpdf, timeSlot string, activities []models.Activity) {
+func (s *PDFService) addTimeSlot(pdf *gofpdf.Fpdf, day *models.Day, timeSlot string, activities []models.Activity, notes func(i int) pdfNotes) {
 	pdf.SetFont("Arial", "B", 12)
 	pdf.SetTextColor(70, 130, 180)
 	pdf.CellFormat(0, 8, timeSlot, "", 1, "L", false, 0, "")
 
-	for _, activity := range activities {
+	for i, activity := range activities {
 		pdf.SetFont("Arial", "B", 11)
 		pdf.SetTextColor(0, 0, 0)
-		pdf.MultiCell(0, 6, fmt.Sprintf("• %s", activity.Name), "", "L", false)
+		// times are local to the day, its zone abbreviation follows them
+		zone := ""
+		if activity.StartTime != nil && day.TimeZone != "" {
+			zone = " " + atLocalTime(day.Date, *activity.StartTime, day.TimeZone).Format("MST")
+		}
+		if activity.StartTime != nil && activity.EndTime != nil {
+			pdf.MultiCell(0, 6, fmt.Sprintf("• %s - %s%s  %s", activity.StartTime, activity.EndTime, zone, activity.Name), "", "L", false)
+		} else if activity.StartTime != nil {
+			pdf.MultiCell(0, 6, fmt.Sprintf("• %s%s  %s", activity.StartTime, zone, activity.Name), "", "L", false)
+		} else {
+			pdf.MultiCell(0, 6, fmt.Sprintf("• %s", activity.Name), "", "L", false)
+		}
 
 		pdf.SetFont("Arial", "", 10)
 		pdf.SetTextColor(60, 60, 60)
@@ -191,6 +506,7 @@ func (s *PDFService) addTimeSlot(pdf *gofpdf.Fpdf, timeSlot string, activities [
 		if activity.Duration != "" {
 			pdf.MultiCell(0, 5, fmt.Sprintf("Duration: %s", activity.Duration), "", "L", false)
 		}
+		s.addNotes(pdf, notes(i))
 		
 		pdf.SetLeftMargin(10)
 		pdf.Ln(3)
@@ -199,7 +515,7 @@ func (s *PDFService) addTimeSlot(pdf *gofpdf.Fpdf, timeSlot string, activities [
 	pdf.Ln(2)
 }
 
-func (s *PDFService) addHotels(pdf *gofpdf.Fpdf, hotels []models.Hotel) {
+func (s *PDFService) addHotels(pdf *gofpdf.Fpdf, hotels []models.Hotel, notes pdfNotes) {
 	s.addSectionTitle(pdf, "Accommodation Details")
 
 	for i, hotel := range hotels {
@@ -210,8 +526,8 @@ func (s *PDFService) addHotels(pdf *gofpdf.Fpdf, hotels []models.Hotel) {
 		pdf.SetFont("Arial", "", 10)
 		pdf.SetTextColor(60, 60, 60)
 		pdf.MultiCell(0, 5, fmt.Sprintf("City: %s", hotel.City), "", "L", false)
-		pdf.MultiCell(0, 5, fmt.Sprintf("Check-in: %s", hotel.CheckInDate.Format("January 2, 2006")), "", "L", false)
-		pdf.MultiCell(0, 5, fmt.Sprintf("Check-out: %s", hotel.CheckOutDate.Format("January 2, 2006")), "", "L", false)
+		pdf.MultiCell(0, 5, fmt.Sprintf("Check-in: %s", formatLocalDate(hotel.CheckInDate, hotel.TimeZone, "January 2, 2006")), "", "L", false)
+		pdf.MultiCell(0, 5, fmt.Sprintf("Check-out: %s", formatLocalDate(hotel.CheckOutDate, hotel.TimeZone, "January 2, 2006")), "", "L", false)
 		pdf.MultiCell(0, 5, fmt.Sprintf("Nights: %d", hotel.Nights), "", "L", false)
 		
 		if hotel.Address != "" {
@@ -219,34 +535,112 @@ func (s *PDFService) addHotels(pdf *gofpdf.Fpdf, hotels []models.Hotel) {
 			pdf.SetTextColor(100, 100, 100)
 			pdf.MultiCell(0, 5, fmt.Sprintf("Address: %s", hotel.Address), "", "L", false)
 		}
+		s.addNotes(pdf, notes.forHotel(i))
 		
 		pdf.Ln(5)
 	}
 }
 
-func (s *PDFService) addFlights(pdf *gofpdf.Fpdf, flights []models.Flight) {
+func (s *PDFService) addFlights(pdf *gofpdf.Fpdf, itineraryID string, flights []models.Flight, notes pdfNotes) {
 	s.addSectionTitle(pdf, "Flight Details")
 
+	const qrSize = 28.0
+	pageW, pageH := pdf.GetPageSize()
+	_, _, rightMargin, bottomMargin := pdf.GetMargins()
+
 	for i, flight := range flights {
+		// keep each flight and its QR code together on one page
+		if s.shareLinks != nil && pdf.GetY()+qrSize > pageH-bottomMargin {
+			pdf.AddPage()
+		}
+		top := pdf.GetY()
+
 		pdf.SetFont("Arial", "B", 12)
 		pdf.SetTextColor(0, 0, 0)
-		pdf.CellFormat(0, 8, fmt.Sprintf("%d. %s - %s", i+1, flight.FlightNumber, flight.Airline), "", 1, "L", false, 0, "")
+		pdf.CellFormat(0, 8, fmt.Sprintf("%d. %s - %s", i+1, flight.FlightNumber, airlineName(flight.Airline)), "", 1, "L", false, 0, "")
 
 		pdf.SetFont("Arial", "", 10)
 		pdf.SetTextColor(60, 60, 60)
-		pdf.MultiCell(0, 5, fmt.Sprintf("From: %s", flight.From), "", "L", false)
-		pdf.MultiCell(0, 5, fmt.Sprintf("To: %s", flight.To), "", "L", false)
-		pdf.MultiCell(0, 5, fmt.Sprintf("Departure: %s", flight.Departure.Format("January 2, 2006 at 3:04 PM")), "", "L", false)
-		pdf.MultiCell(0, 5, fmt.Sprintf("Arrival: %s", flight.Arrival.Format("January 2, 2006 at 3:04 PM")), "", "L", false)
+		pdf.MultiCell(0, 5, fmt.Sprintf("From: %s", airportName(flight.From)), "", "L", false)
+		pdf.MultiCell(0, 5, fmt.Sprintf("To: %s", airportName(flight.To)), "", "L", false)
+		pdf.MultiCell(0, 5, fmt.Sprintf("Departure: %s", formatLocal(flight.Departure, flight.DepartureTimeZone, "January 2, 2006 at 3:04 PM")), "", "L", false)
+		pdf.MultiCell(0, 5, fmt.Sprintf("Arrival: %s", formatLocal(flight.Arrival, flight.ArrivalTimeZone, "January 2, 2006 at 3:04 PM")), "", "L", false)
+		pdf.MultiCell(0, 5, fmt.Sprintf("Duration: %s", formatDuration(FlightDuration(flight))), "", "L", false)
+		if flight.BookingReference != "" {
+			pdf.MultiCell(0, 5, fmt.Sprintf("Booking Reference: %s", flight.BookingReference), "", "L", false)
+		}
+
+		// QR code with the flight number, the booking reference stays off the printed link
+		if s.shareLinks != nil {
+			url := s.shareLinks.FlightURL(itineraryID, flight.FlightNumber, "")
+			drawQRCode(pdf, url, pageW-rightMargin-qrSize, top, qrSize)
+			if pdf.GetY() < top+qrSize {
+				pdf.SetY(top + qrSize)
+			}
+		}
+		s.addNotes(pdf, notes.forFlight(flight.FlightNumber))
 		
 		pdf.Ln(5)
 	}
 }
 
-func (s *PDFService) addTransfers(pdf *gofpdf.Fpdf, transfers []models.Transfer) {
+func (s *PDFService) addJourney(pdf *gofpdf.Fpdf, report *JourneyReport) {
+	s.addSectionTitle(pdf, "Journey Overview")
+
+	for i, journey := range report.Journeys {
+		first, last := journey.Legs[0], journey.Legs[len(journey.Legs)-1]
+
+		pdf.SetFont("Arial", "B", 12)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(0, 8, fmt.Sprintf("%d. %s to %s", i+1, airportName(journey.From), airportName(journey.To)), "", 1, "L", false, 0, "")
+
+		pdf.SetFont("Arial", "", 10)
+		pdf.SetTextColor(60, 60, 60)
+		pdf.MultiCell(0, 5, fmt.Sprintf("Departs: %s", formatLocal(journey.Departure, first.DepartureTimeZone, "January 2, 2006 at 3:04 PM")), "", "L", false)
+		pdf.MultiCell(0, 5, fmt.Sprintf("Arrives: %s", formatLocal(journey.Arrival, last.ArrivalTimeZone, "January 2, 2006 at 3:04 PM")), "", "L", false)
+
+		for n, leg := range journey.Legs {
+			pdf.MultiCell(0, 5, fmt.Sprintf("- %s %s to %s (%s)", leg.FlightNumber, leg.From, leg.To,
+				formatDuration(time.Duration(leg.DurationMinutes)*time.Minute)), "", "L", false)
+			if n < len(journey.Connections) {
+				connection := journey.Connections[n]
+				line := fmt.Sprintf("   Connection at %s: %s", connection.Airport,
+					formatDuration(time.Duration(connection.LayoverMinutes)*time.Minute))
+				if connection.Overnight {
+					if connection.HotelBooked {
+						line += " (overnight, hotel booked)"
+					} else {
+						line += " (overnight, no hotel booked)"
+					}
+				}
+				pdf.MultiCell(0, 5, line, "", "L", false)
+			}
+		}
+
+		pdf.SetFont("Arial", "B", 10)
+		pdf.MultiCell(0, 5, fmt.Sprintf("Total travel time: %s", formatDuration(time.Duration(journey.TravelMinutes)*time.Minute)), "", "L", false)
+		pdf.Ln(4)
+	}
+
+	pdf.SetFont("Arial", "B", 11)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.MultiCell(0, 6, fmt.Sprintf("Time in the air: %s   Time in transit: %s",
+		formatDuration(time.Duration(report.TotalFlightMinutes)*time.Minute),
+		formatDuration(time.Duration(report.TotalLayoverMinutes)*time.Minute)), "", "L", false)
+
+	if len(report.Issues) > 0 {
+		pdf.Ln(3)
+		s.addIssueGroup(pdf, "Connection issues")
+		for _, found := range report.Issues {
+			pdf.MultiCell(0, 5, fmt.Sprintf("- %s", found.Message), "", "L", false)
+		}
+	}
+}
+
+func (s *PDFService) addTransfers(pdf *gofpdf.Fpdf, itinerary *models.Itinerary) {
 	s.addSectionTitle(pdf, "Transfer Details")
 
-	for i, transfer := range transfers {
+	for i, transfer := range itinerary.Transfers {
 		pdf.SetFont("Arial", "B", 11)
 		pdf.SetTextColor(0, 0, 0)
 		pdf.CellFormat(0, 8, fmt.Sprintf("%d. %s to %s", i+1, transfer.From, transfer.To), "", 1, "L", false, 0, "")
@@ -254,19 +648,23 @@ func (s *PDFService) addTransfers(pdf *gofpdf.Fpdf, transfers []models.Transfer)
 		pdf.SetFont("Arial", "", 10)
 		pdf.SetTextColor(60, 60, 60)
 		pdf.MultiCell(0, 5, fmt.Sprintf("Mode: %s", transfer.Mode), "", "L", false)
-		pdf.MultiCell(0, 5, fmt.Sprintf("Timing: %s", transfer.Timing.Format("January 2, 2006 at 3:04 PM")), "", "L", false)
+		zone := transferZone(itinerary, transfer)
+		pdf.MultiCell(0, 5, fmt.Sprintf("Timing: %s", formatLocal(transfer.Timing, zone, "January 2, 2006 at 3:04 PM")), "", "L", false)
+		if transfer.FlightNumber != "" {
+			pdf.MultiCell(0, 5, fmt.Sprintf("Flight: %s", transfer.FlightNumber), "", "L", false)
+		}
 		
 		pdf.Ln(4)
 	}
 }
 
-func (s *PDFService) addPaymentPlan(pdf *gofpdf.Fpdf, plan *models.PaymentPlan) {
+func (s *PDFService) addPaymentPlan(pdf *gofpdf.Fpdf, plan *models.PaymentPlan, notes pdfNotes) {
 	s.addSectionTitle(pdf, "Payment Plan")
 
 	// Total amount
 	pdf.SetFont("Arial", "B", 14)
 	pdf.SetTextColor(220, 20, 60)
-	pdf.CellFormat(0, 10, fmt.Sprintf("Total Amount: %.2f", plan.AmountDue), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 10, fmt.Sprintf("Total Amount: %s", s.currencies.Format(plan.AmountDue)), "", 1, "L", false, 0, "")
 	
 	pdf.Ln(5)
 
@@ -278,13 +676,20 @@ func (s *PDFService) addPaymentPlan(pdf *gofpdf.Fpdf, plan *models.PaymentPlan)
 	for _, inst := range plan.Installments {
 		pdf.SetFont("Arial", "B", 10)
 		pdf.SetTextColor(0, 0, 0)
-		pdf.CellFormat(0, 7, fmt.Sprintf("Installment %d - %.2f", 
-			inst.InstallmentNumber ,inst.Amount), "", 1, "L", false, 0, "")
+		amount := s.currencies.Format(inst.Amount)
+		if inst.Amount.Currency != plan.AmountDue.Currency {
+			if converted, err := s.currencies.forPlan(*plan).Convert(inst.Amount, plan.AmountDue.Currency); err == nil {
+				amount += fmt.Sprintf(" (%s)", s.currencies.Format(converted))
+			}
+		}
+		pdf.CellFormat(0, 7, fmt.Sprintf("Installment %d - %s", 
+			inst.InstallmentNumber ,amount), "", 1, "L", false, 0, "")
 
 		pdf.SetFont("Arial", "", 9)
 		pdf.SetTextColor(60, 60, 60)
 		pdf.MultiCell(0, 5, fmt.Sprintf("Due Date: %s", inst.DueDate.Format("January 2, 2006")), "", "L", false)
 		pdf.MultiCell(0, 5, fmt.Sprintf("Status: %s", inst.Status), "", "L", false)
+		s.addNotes(pdf, notes.forInstallment(inst.InstallmentNumber))
 		
 		pdf.Ln(3)
 	}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrUnknownTheme  = errors.New("unknown PDF theme")
+	ErrInvalidThemes = errors.New("invalid PDF themes")
+)
+
+// DefaultThemeName is used when no theme is configured or requested
+const DefaultThemeName = "classic"
+
+// RGB is a colour used when drawing PDF elements
+type RGB struct {
+	R int `json:"r"`
+	G int `json:"g"`
+	B int `json:"b"`
+}
+
+// PDFTheme controls the branding and status markings of generated PDFs
+type PDFTheme struct {
+	Name string `json:"name"`
+
+	// watermark stamped diagonally across every page while an itinerary
+	// is not yet confirmed, or once it is cancelled or expired
+	DraftWatermark     string  `json:"draft_watermark"`
+	QuoteWatermark     string  `json:"quote_watermark"`
+	CancelledWatermark string  `json:"cancelled_watermark"`
+	ExpiredWatermark   string  `json:"expired_watermark"`
+	WatermarkColor     RGB     `json:"watermark_color"`
+	WatermarkAlpha     float64 `json:"watermark_alpha"`
+
+	// itemised price table below the payment plan of priced itineraries
+	ShowPriceBreakdown bool `json:"show_price_breakdown"`
+}
+
+// builtinThemes returns the themes available out of the box
+func builtinThemes() map[string]PDFTheme {
+	return map[string]PDFTheme{
+		"classic": {
+			Name:               "classic",
+			DraftWatermark:     "DRAFT",
+			QuoteWatermark:     "QUOTE — NOT CONFIRMED",
+			CancelledWatermark: "CANCELLED",
+			ExpiredWatermark:   "QUOTE EXPIRED",
+			WatermarkColor:     RGB{220, 20, 60},
+			WatermarkAlpha:     0.12,
+			ShowPriceBreakdown: true,
+		},
+		"minimal": {
+			Name:               "minimal",
+			DraftWatermark:     "DRAFT",
+			QuoteWatermark:     "QUOTE",
+			CancelledWatermark: "CANCELLED",
+			ExpiredWatermark:   "EXPIRED",
+			WatermarkColor:     RGB{150, 150, 150},
+			WatermarkAlpha:     0.10,
+		},
+	}
+}
+
+// LoadThemes reads a JSON array of themes from a file. A theme named after a
+// built-in theme only changes the fields it sets; a new theme starts from
+// the default theme.
+func LoadThemes(path string) ([]PDFTheme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidThemes, err)
+	}
+
+	var entries []json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidThemes, err)
+	}
+
+	builtin := builtinThemes()
+	themes := make([]PDFTheme, 0, len(entries))
+	for i, entry := range entries {
+		var named struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(entry, &named); err != nil || named.Name == "" {
+			return nil, fmt.Errorf("%w: theme %d has no name", ErrInvalidThemes, i+1)
+		}
+		theme, ok := builtin[named.Name]
+		if !ok {
+			theme = builtin[DefaultThemeName]
+		}
+		decoder := json.NewDecoder(bytes.NewReader(entry))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&theme); err != nil {
+			return nil, fmt.Errorf("%w: theme %q: %w", ErrInvalidThemes, named.Name, err)
+		}
+		themes = append(themes, theme)
+	}
+	return themes, nil
+}
+
+// RegisterTheme adds or replaces a theme on the PDF service
+func (s *PDFService) RegisterTheme(theme PDFTheme) {
+	s.themes[theme.Name] = theme
+}
+
+// theme resolves a theme by name, falling back to the service default
+func (s *PDFService) theme(name string) (*PDFTheme, error) {
+	if name == "" {
+		name = s.defaultTheme
+	}
+	theme, ok := s.themes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTheme, name)
+	}
+	return &theme, nil
+}
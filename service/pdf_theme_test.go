@@ -0,0 +1,74 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadThemes(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		want    map[string]PDFTheme
+		wantErr error
+	}{
+		{"built-in theme keeps the fields it doesn't set",
+			`[{"name": "classic", "draft_watermark": "ENTWURF"}]`,
+			map[string]PDFTheme{"classic": {DraftWatermark: "ENTWURF", QuoteWatermark: "QUOTE — NOT CONFIRMED", ShowPriceBreakdown: true}}, nil},
+		{"new theme starts from the default",
+			`[{"name": "agency", "quote_watermark": "OFFER", "show_price_breakdown": false}]`,
+			map[string]PDFTheme{"agency": {DraftWatermark: "DRAFT", QuoteWatermark: "OFFER"}}, nil},
+		{"theme without a name", `[{"draft_watermark": "X"}]`, nil, ErrInvalidThemes},
+		{"misspelt field", `[{"name": "classic", "draft_watermak": "X"}]`, nil, ErrInvalidThemes},
+		{"not a list", `{"name": "classic"}`, nil, ErrInvalidThemes},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "themes.json")
+			if err := os.WriteFile(path, []byte(test.file), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			themes, err := LoadThemes(path)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			for _, theme := range themes {
+				want, ok := test.want[theme.Name]
+				if !ok {
+					t.Fatalf("unexpected theme %q", theme.Name)
+				}
+				if theme.DraftWatermark != want.DraftWatermark || theme.QuoteWatermark != want.QuoteWatermark ||
+					theme.ShowPriceBreakdown != want.ShowPriceBreakdown {
+					t.Errorf("got %+v, want %+v", theme, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPDFServiceDefaultTheme(t *testing.T) {
+	tests := []struct {
+		name    string
+		theme   string
+		themes  []PDFTheme
+		wantErr error
+	}{
+		{"no theme configured", "", nil, nil},
+		{"built-in theme", "minimal", nil, nil},
+		{"configured theme", "agency", []PDFTheme{{Name: "agency"}}, nil},
+		{"unknown theme", "clasic", nil, ErrUnknownTheme},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewPDFService(t.TempDir(), PDFSettings{DefaultTheme: test.theme, Themes: test.themes})
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("got error %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
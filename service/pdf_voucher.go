@@ -0,0 +1,228 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+var (
+	ErrVoucherNotFound = errors.New("voucher not found")
+	ErrNoVouchers      = errors.New("itinerary has no hotels or transfers to issue vouchers for")
+)
+
+// AgencyContact identifies the issuing agency on vouchers
+type AgencyContact struct {
+	Name  string
+	Phone string
+	Email string
+}
+
+// voucherField is a single label/value row printed on a voucher
+type voucherField struct {
+	label string
+	value string
+}
+
+// GenerateHotelVoucher renders a single page voucher for one hotel booking.
+// The index is 1-based and matches the numbering in the itinerary PDF.
+func (s *PDFService) GenerateHotelVoucher(itinerary *models.Itinerary, index int, opts PDFOptions) (string, error) {
+	pdf, err := s.renderHotelVoucher(itinerary, index, opts)
+	if err != nil {
+		return "", err
+	}
+
+	filename := fmt.Sprintf("voucher_hotel_%d_%s_%s.pdf", index, itinerary.ID, time.Now().Format("20060102_150405"))
+	return s.save(pdf, filename)
+}
+
+// GenerateTransferVoucher renders a single page voucher for one transfer.
+// The index is 1-based and matches the numbering in the itinerary PDF.
+func (s *PDFService) GenerateTransferVoucher(itinerary *models.Itinerary, index int, opts PDFOptions) (string, error) {
+	pdf, err := s.renderTransferVoucher(itinerary, index, opts)
+	if err != nil {
+		return "", err
+	}
+
+	filename := fmt.Sprintf("voucher_transfer_%d_%s_%s.pdf", index, itinerary.ID, time.Now().Format("20060102_150405"))
+	return s.save(pdf, filename)
+}
+
+// GenerateVoucherBundle renders every hotel and transfer voucher of an
+// itinerary and bundles them into a single ZIP archive
+func (s *PDFService) GenerateVoucherBundle(itinerary *models.Itinerary, opts PDFOptions) (string, error) {
+	if len(itinerary.Hotels) == 0 && len(itinerary.Transfers) == 0 {
+		return "", ErrNoVouchers
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	for i := range itinerary.Hotels {
+		pdf, err := s.renderHotelVoucher(itinerary, i+1, opts)
+		if err != nil {
+			return "", err
+		}
+		if err := addToArchive(archive, fmt.Sprintf("hotel_%02d.pdf", i+1), pdf); err != nil {
+			return "", err
+		}
+	}
+
+	for i := range itinerary.Transfers {
+		pdf, err := s.renderTransferVoucher(itinerary, i+1, opts)
+		if err != nil {
+			return "", err
+		}
+		if err := addToArchive(archive, fmt.Sprintf("transfer_%02d.pdf", i+1), pdf); err != nil {
+			return "", err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return "", fmt.Errorf("failed to create voucher archive: %w", err)
+	}
+
+	if err := os.MkdirAll(s.outputDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory : %w", err)
+	}
+
+	filename := fmt.Sprintf("vouchers_%s_%s.zip", itinerary.ID, time.Now().Format("20060102_150405"))
+	path := filepath.Join(s.outputDir, filename)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to save voucher archive: %w", err)
+	}
+
+	return path, nil
+}
+
+// addToArchive writes a rendered document into a ZIP archive
+func addToArchive(archive *zip.Writer, name string, pdf *gofpdf.Fpdf) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to archive: %w", name, err)
+	}
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return nil
+}
+
+func (s *PDFService) renderHotelVoucher(itinerary *models.Itinerary, index int, opts PDFOptions) (*gofpdf.Fpdf, error) {
+	if index < 1 || index > len(itinerary.Hotels) {
+		return nil, fmt.Errorf("%w: hotel %d", ErrVoucherNotFound, index)
+	}
+	hotel := itinerary.Hotels[index-1]
+
+	pdf, err := s.newDocument(itinerary, opts)
+	if err != nil {
+		return nil, err
+	}
+	pdf.AddPage()
+
+	s.addVoucher(pdf, itinerary, "Hotel Voucher", hotel.Name, []voucherField{
+		{"Guest", guestName(itinerary)},
+		{"Booking Reference", hotel.BookingReference},
+		{"Check-in", formatLocalDate(hotel.CheckInDate, hotel.TimeZone, "Monday, January 2, 2006")},
+		{"Check-out", formatLocalDate(hotel.CheckOutDate, hotel.TimeZone, "Monday, January 2, 2006")},
+		{"Nights", fmt.Sprintf("%d", hotel.Nights)},
+		{"City", hotel.City},
+		{"Address", hotel.Address},
+	})
+
+	return pdf, nil
+}
+
+func (s *PDFService) renderTransferVoucher(itinerary *models.Itinerary, index int, opts PDFOptions) (*gofpdf.Fpdf, error) {
+	if index < 1 || index > len(itinerary.Transfers) {
+		return nil, fmt.Errorf("%w: transfer %d", ErrVoucherNotFound, index)
+	}
+	transfer := itinerary.Transfers[index-1]
+
+	pdf, err := s.newDocument(itinerary, opts)
+	if err != nil {
+		return nil, err
+	}
+	pdf.AddPage()
+
+	s.addVoucher(pdf, itinerary, "Transfer Voucher", fmt.Sprintf("%s to %s", transfer.From, transfer.To), []voucherField{
+		{"Guest", guestName(itinerary)},
+		{"Booking Reference", transfer.BookingReference},
+		{"Pick-up", formatLocal(transfer.Timing, transferZone(itinerary, transfer), "Monday, January 2, 2006 at 3:04 PM")},
+		{"From", transfer.From},
+		{"To", transfer.To},
+		{"Mode", transfer.Mode},
+		{"Flight", transfer.FlightNumber},
+	})
+
+	return pdf, nil
+}
+
+// addVoucher lays out a voucher page: heading, booking details and the
+// agency contact for the supplier
+func (s *PDFService) addVoucher(pdf *gofpdf.Fpdf, itinerary *models.Itinerary, kind, title string, fields []voucherField) {
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFont("Arial", "B", 22)
+	pdf.SetTextColor(25, 25, 112)
+	pdf.CellFormat(0, 14, kind, "", 1, "L", false, 0, "")
+
+	pdf.SetFont("Arial", "B", 16)
+	pdf.SetTextColor(70, 130, 180)
+	pdf.MultiCell(0, 8, tr(title), "", "L", false)
+	pdf.Ln(6)
+
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		pdf.SetFont("Arial", "B", 11)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.CellFormat(50, 8, field.label, "B", 0, "L", false, 0, "")
+		pdf.SetFont("Arial", "", 11)
+		pdf.SetTextColor(60, 60, 60)
+		pdf.CellFormat(0, 8, tr(field.value), "B", 1, "L", false, 0, "")
+	}
+
+	pdf.Ln(4)
+	pdf.SetFont("Arial", "I", 9)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.MultiCell(0, 5, fmt.Sprintf("Trip: %s (%s)", tr(itinerary.Title), itinerary.ID), "", "L", false)
+	if itinerary.ConfirmationNumber != "" {
+		pdf.MultiCell(0, 5, fmt.Sprintf("Confirmation No. %s", itinerary.ConfirmationNumber), "", "L", false)
+	}
+
+	// Agency contact
+	if s.agency.Name != "" || s.agency.Phone != "" || s.agency.Email != "" {
+		pdf.Ln(10)
+		pdf.SetFont("Arial", "B", 12)
+		pdf.SetTextColor(25, 25, 112)
+		pdf.CellFormat(0, 8, "Issued by", "", 1, "L", false, 0, "")
+
+		pdf.SetFont("Arial", "", 10)
+		pdf.SetTextColor(60, 60, 60)
+		for _, line := range []string{s.agency.Name, s.agency.Phone, s.agency.Email} {
+			if line != "" {
+				pdf.MultiCell(0, 5, tr(line), "", "L", false)
+			}
+		}
+	}
+}
+
+// guestName returns the name printed on vouchers for the itinerary
+func guestName(itinerary *models.Itinerary) string {
+	if itinerary.CustomerName != "" {
+		return itinerary.CustomerName
+	}
+	return itinerary.UserID
+}
@@ -0,0 +1,342 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// pricing issue codes
+const (
+	CodeInvalidPrice = "invalid_price"
+
+	// warnings
+	CodeRateWithoutTravellers = "rate_without_travellers"
+)
+
+// ChildAgeLimit is the age from which a traveller pays the adult rate
+const ChildAgeLimit = 12
+
+// GetPricing itemises the price of an itinerary
+func (s *ItineraryService) GetPricing(id string) (*models.PriceBreakdown, error) {
+	itinerary, err := s.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return priceBreakdown(itinerary, s.currencies.forPlan(itinerary.PaymentPlan))
+}
+
+// applyPricing derives the amount due from the price components when any
+// element of the itinerary has a price. When that changes the amount due,
+// the installments are scaled to it. Without prices, or when a price can't
+// be converted into the currency of the payment plan, the amount typed in is
+// kept.
+func applyPricing(itinerary *models.Itinerary, currencies *CurrencyService) {
+	if !hasPricing(itinerary) {
+		return
+	}
+	breakdown, err := priceBreakdown(itinerary, currencies)
+	if err != nil || breakdown.Total.Equal(itinerary.PaymentPlan.AmountDue) {
+		return
+	}
+	itinerary.PaymentPlan = scalePaymentPlan(itinerary.PaymentPlan, breakdown.Total, currencies)
+}
+
+// hasPricing tells whether any hotel, flight, transfer or activity has a price
+func hasPricing(itinerary *models.Itinerary) bool {
+	return len(pricedElements(itinerary)) > 0
+}
+
+// pricedElement is an element of an itinerary with a price
+type pricedElement struct {
+	path        string
+	description string
+	price       *models.Price
+}
+
+// pricedElements lists the priced elements of an itinerary in document order
+func pricedElements(itinerary *models.Itinerary) []pricedElement {
+	var elements []pricedElement
+	for i, day := range itinerary.Days {
+		for _, slot := range timeSlots {
+			for j, activity := range slotActivities(day.Activities, slot.name) {
+				if activity.Price != nil {
+					elements = append(elements, pricedElement{fmt.Sprintf("days[%d].activities.%s[%d]", i, slot.name, j),
+						fmt.Sprintf("Day %d: %s", day.DayNumber, activity.Name), activity.Price})
+				}
+			}
+		}
+	}
+	for i, hotel := range itinerary.Hotels {
+		if hotel.Price != nil {
+			elements = append(elements, pricedElement{fmt.Sprintf("hotels[%d]", i),
+				fmt.Sprintf("%s (%d nights)", hotel.Name, hotel.Nights), hotel.Price})
+		}
+	}
+	for i, flight := range itinerary.Flights {
+		if flight.Price != nil {
+			elements = append(elements, pricedElement{fmt.Sprintf("flights[%d]", i),
+				fmt.Sprintf("Flight %s %s to %s", flight.FlightNumber, flight.From, flight.To), flight.Price})
+		}
+	}
+	for i, transfer := range itinerary.Transfers {
+		if transfer.Price != nil {
+			elements = append(elements, pricedElement{fmt.Sprintf("transfers[%d]", i),
+				fmt.Sprintf("Transfer %s to %s", transfer.From, transfer.To), transfer.Price})
+		}
+	}
+	return elements
+}
+
+// priceBreakdown prices every element with a price component. Every line is
+// rounded to the minor units of its currency and converted component by
+// component into the currency of the payment plan; the totals add up the
+// converted lines.
+func priceBreakdown(itinerary *models.Itinerary, currencies *CurrencyService) (*models.PriceBreakdown, error) {
+	adults, children := countTravellers(itinerary)
+	currency := itinerary.PaymentPlan.AmountDue.Currency
+	zero := models.NewMoney(decimal.Zero, currency)
+
+	breakdown := &models.PriceBreakdown{
+		Lines:     []models.PriceLine{},
+		Net:       zero,
+		Markup:    zero,
+		Taxes:     zero,
+		Total:     zero,
+		AmountDue: itinerary.PaymentPlan.AmountDue,
+	}
+	for _, element := range pricedElements(itinerary) {
+		line := priceLine(element.price, adults, children, currencies)
+		line.Element, line.Description = element.path, element.description
+
+		net, err := currencies.Convert(line.Net, currency)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", element.description, err)
+		}
+		markup, err := currencies.Convert(line.Markup, currency)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", element.description, err)
+		}
+		taxes, err := currencies.Convert(line.Taxes, currency)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", element.description, err)
+		}
+		line.CustomerTotal = models.NewMoney(net.Amount.Add(markup.Amount).Add(taxes.Amount), currency)
+		breakdown.Lines = append(breakdown.Lines, line)
+
+		breakdown.Net.Amount = breakdown.Net.Amount.Add(net.Amount)
+		breakdown.Markup.Amount = breakdown.Markup.Amount.Add(markup.Amount)
+		breakdown.Taxes.Amount = breakdown.Taxes.Amount.Add(taxes.Amount)
+		breakdown.Total.Amount = breakdown.Total.Amount.Add(line.CustomerTotal.Amount)
+	}
+	breakdown.Derived = len(breakdown.Lines) > 0
+	return breakdown, nil
+}
+
+// priceLine prices one element in the currency of its price for the given
+// number of adults and children, unless the price has counts of its own
+func priceLine(price *models.Price, adults, children int, currencies *CurrencyService) models.PriceLine {
+	if price.Adults != nil {
+		adults = *price.Adults
+	}
+	if price.Children != nil {
+		children = *price.Children
+	}
+
+	hundred := decimal.NewFromInt(100)
+	net := price.NetCost.Add(price.AdultRate.Mul(decimal.NewFromInt(int64(adults)))).
+		Add(price.ChildRate.Mul(decimal.NewFromInt(int64(children))))
+	markup := price.Markup.Add(net.Mul(price.MarkupPercent).Div(hundred))
+	taxes := price.Taxes.Add(net.Add(markup).Mul(price.TaxPercent).Div(hundred))
+
+	line := models.PriceLine{
+		Adults:   adults,
+		Children: children,
+		Net:      currencies.Round(models.NewMoney(net, price.Currency)),
+		Markup:   currencies.Round(models.NewMoney(markup, price.Currency)),
+		Taxes:    currencies.Round(models.NewMoney(taxes, price.Currency)),
+	}
+	line.Total = models.NewMoney(line.Net.Amount.Add(line.Markup.Amount).Add(line.Taxes.Amount), price.Currency)
+	return line
+}
+
+// countTravellers splits the travellers into adults and children by their
+// age on the first day of the trip. Travellers without a date of birth are
+// counted as adults.
+func countTravellers(itinerary *models.Itinerary) (adults, children int) {
+	for _, traveller := range itinerary.Travellers {
+		if traveller.DateOfBirth != nil && ageOn(*traveller.DateOfBirth, itinerary.StartDate) < ChildAgeLimit {
+			children++
+		} else {
+			adults++
+		}
+	}
+	return adults, children
+}
+
+// ageOn returns the age in whole years of someone born on birth at date
+func ageOn(birth, date time.Time) int {
+	age := date.Year() - birth.Year()
+	if date.Month() < birth.Month() || date.Month() == birth.Month() && date.Day() < birth.Day() {
+		age--
+	}
+	return age
+}
+
+// validatePrices checks that no price component is negative and warns about
+// per-person rates without anyone to apply them to
+func validatePrices(itinerary *models.Itinerary) []ValidationIssue {
+	var issues []ValidationIssue
+	adults, children := countTravellers(itinerary)
+
+	for _, element := range pricedElements(itinerary) {
+		price := element.price
+		for _, component := range []struct {
+			field string
+			value decimal.Decimal
+		}{
+			{"net_cost", price.NetCost},
+			{"adult_rate", price.AdultRate},
+			{"child_rate", price.ChildRate},
+			{"markup", price.Markup},
+			{"markup_percent", price.MarkupPercent},
+			{"taxes", price.Taxes},
+			{"tax_percent", price.TaxPercent},
+		} {
+			if component.value.IsNegative() {
+				issues = append(issues, issue(element.path+".price."+component.field, CodeInvalidPrice,
+					"%s: %s must not be negative", element.description, component.field))
+			}
+		}
+		if price.Adults != nil && *price.Adults < 0 || price.Children != nil && *price.Children < 0 {
+			issues = append(issues, issue(element.path+".price", CodeInvalidPrice,
+				"%s: the number of adults and children must not be negative", element.description))
+		}
+
+		adultsCharged, childrenCharged := adults, children
+		if price.Adults != nil {
+			adultsCharged = *price.Adults
+		}
+		if price.Children != nil {
+			childrenCharged = *price.Children
+		}
+		if price.AdultRate.IsPositive() && adultsCharged == 0 || price.ChildRate.IsPositive() && childrenCharged == 0 {
+			issues = append(issues, warning(element.path+".price", CodeRateWithoutTravellers,
+				"%s: a per-person rate is set but there is nobody to charge it to", element.description))
+		}
+	}
+	return issues
+}
+
+// scalePrice returns a copy of a price with every amount multiplied by
+// factor, used when a priced hotel stay is cut short. Percentages and
+// counts are kept.
+func scalePrice(price *models.Price, factor decimal.Decimal) *models.Price {
+	if price == nil {
+		return nil
+	}
+	scaled := *copyPrice(price)
+	scaled.NetCost = price.NetCost.Mul(factor)
+	scaled.AdultRate = price.AdultRate.Mul(factor)
+	scaled.ChildRate = price.ChildRate.Mul(factor)
+	scaled.Markup = price.Markup.Mul(factor)
+	scaled.Taxes = price.Taxes.Mul(factor)
+	return &scaled
+}
+
+// copyPrice returns a deep copy of an optional price
+func copyPrice(price *models.Price) *models.Price {
+	if price == nil {
+		return nil
+	}
+	copied := *price
+	copied.Adults = copyCount(price.Adults)
+	copied.Children = copyCount(price.Children)
+	return &copied
+}
+
+// copyCount returns a copy of an optional traveller count
+func copyCount(count *int) *int {
+	if count == nil {
+		return nil
+	}
+	copied := *count
+	return &copied
+}
+
+// nightShare is the share of a stay's nights kept, used to scale its price
+func nightShare(nights, of int) decimal.Decimal {
+	return decimal.NewFromInt(int64(nights)).Div(decimal.NewFromInt(int64(of)))
+}
+
+// addPrices returns the price of two stays joined into one. Amounts and
+// rates are added up; the currency, percentages and counts come from the
+// first price. When the currencies differ the first price is kept.
+func addPrices(a, b *models.Price) *models.Price {
+	if a == nil || b == nil || a.Currency != b.Currency {
+		if a == nil {
+			return copyPrice(b)
+		}
+		return copyPrice(a)
+	}
+	sum := *copyPrice(a)
+	sum.NetCost = a.NetCost.Add(b.NetCost)
+	sum.AdultRate = a.AdultRate.Add(b.AdultRate)
+	sum.ChildRate = a.ChildRate.Add(b.ChildRate)
+	sum.Markup = a.Markup.Add(b.Markup)
+	sum.Taxes = a.Taxes.Add(b.Taxes)
+	return &sum
+}
+
+// scalePaymentPlan spreads a new amount due over the installments of a plan
+// in proportion to their amounts converted into the currency of the plan.
+// Every installment is rounded to the minor units of its own currency and the
+// last installment in the currency of the plan takes the rounding
+// difference, so the plan adds up exactly.
+func scalePaymentPlan(plan models.PaymentPlan, amount models.Money, currencies *CurrencyService) models.PaymentPlan {
+	scaled := plan
+	scaled.Installments = append([]models.Installment{}, plan.Installments...)
+	scaled.AmountDue = currencies.Round(amount)
+	if len(scaled.Installments) == 0 {
+		return scaled
+	}
+
+	balancing := len(scaled.Installments) - 1
+	weights := make([]decimal.Decimal, len(scaled.Installments))
+	total := decimal.Zero
+	for i, installment := range scaled.Installments {
+		if installment.Amount.Currency == amount.Currency {
+			balancing = i
+		}
+		if converted, err := currencies.Convert(installment.Amount, amount.Currency); err == nil {
+			weights[i] = converted.Amount
+			total = total.Add(converted.Amount)
+		}
+	}
+
+	remaining := scaled.AmountDue.Amount
+	for i := range scaled.Installments {
+		if i == balancing {
+			continue
+		}
+		share := decimal.NewFromInt(1).Div(decimal.NewFromInt(int64(len(scaled.Installments))))
+		if total.IsPositive() {
+			share = weights[i].Div(total)
+		}
+		part, err := currencies.Convert(models.NewMoney(scaled.AmountDue.Amount.Mul(share), amount.Currency), scaled.Installments[i].Amount.Currency)
+		if err != nil {
+			continue
+		}
+		scaled.Installments[i].Amount = part
+		if back, err := currencies.Convert(part, amount.Currency); err == nil {
+			remaining = remaining.Sub(back.Amount)
+		}
+	}
+	if last, err := currencies.Convert(models.NewMoney(remaining, amount.Currency), scaled.Installments[balancing].Amount.Currency); err == nil {
+		scaled.Installments[balancing].Amount = last
+	}
+	return scaled
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/repository"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// usd and inr return amounts in US dollars and Indian rupees
+func usd(amount string) models.Money {
+	return models.NewMoney(decimal.RequireFromString(amount), "USD")
+}
+
+func inr(amount string) models.Money {
+	return models.NewMoney(decimal.RequireFromString(amount), "INR")
+}
+
+// pricingCurrencies returns a currency service where a dollar is 80 rupees
+func pricingCurrencies() *CurrencyService {
+	rates, err := ParseExchangeRates("USD=1,INR=80")
+	if err != nil {
+		panic(err)
+	}
+	return NewCurrencyService("INR", rates)
+}
+
+// safariTrip returns a safari in the Masai Mara for two adults and a nine
+// year old, with a lodge priced in dollars per person and a flight priced in
+// rupees for the whole family
+func safariTrip() *models.Itinerary {
+	date := func(d int) time.Time { return time.Date(2025, time.July, d, 0, 0, 0, 0, time.UTC) }
+	born := func(year int) *time.Time { d := time.Date(year, time.July, 11, 0, 0, 0, 0, time.UTC); return &d }
+	return &models.Itinerary{
+		ID:        "mara-1",
+		UserID:    "agent-safari",
+		Title:     "Masai Mara",
+		StartDate: date(10),
+		EndDate:   date(12),
+		Days: []models.Day{
+			{DayNumber: 1, Date: date(10), Title: "Arrival"},
+			{DayNumber: 2, Date: date(11), Title: "Game drive"},
+			{DayNumber: 3, Date: date(12), Title: "Departure"},
+		},
+		Hotels: []models.Hotel{
+			{Name: "Mara Serena", City: "Masai Mara", CheckInDate: date(10), CheckOutDate: date(12), Nights: 2,
+				Price: &models.Price{Currency: "USD", AdultRate: decimal.NewFromInt(200), ChildRate: decimal.NewFromInt(100),
+					MarkupPercent: decimal.NewFromInt(10), TaxPercent: decimal.NewFromInt(16)}},
+		},
+		Flights: []models.Flight{
+			{FlightNumber: "KQ 205", From: "BOM", To: "NBO", Departure: date(10).Add(2 * time.Hour), Arrival: date(10).Add(8 * time.Hour),
+				Price: &models.Price{Currency: "INR", NetCost: decimal.NewFromInt(30000), Markup: decimal.NewFromInt(1500)}},
+		},
+		Travellers: []models.Traveller{
+			{ID: "t1", Name: "Kabir Shah"},
+			{ID: "t2", Name: "Nisha Shah", DateOfBirth: born(1988)},
+			{ID: "t3", Name: "Zoya Shah", DateOfBirth: born(2016)},
+		},
+		PaymentPlan: models.PaymentPlan{
+			AmountDue: inr("82540"),
+			DueDate:   date(1),
+			Installments: []models.Installment{
+				{InstallmentNumber: 1, Amount: inr("82540"), DueDate: date(1), Status: "Pending"},
+			},
+		},
+		Status: models.StatusDraft,
+	}
+}
+
+// installmentStrings writes the amounts of the installments of a plan with
+// two decimals and their currency
+func installmentStrings(plan models.PaymentPlan) []string {
+	amounts := []string{}
+	for _, installment := range plan.Installments {
+		amounts = append(amounts, installment.Amount.Amount.StringFixed(2)+" "+installment.Amount.Currency)
+	}
+	return amounts
+}
+
+func TestCountTravellers(t *testing.T) {
+	trip := safariTrip()
+	if adults, children := countTravellers(trip); adults != 2 || children != 1 {
+		t.Errorf("got %d adults and %d children, want 2 and 1", adults, children)
+	}
+
+	// a child turning twelve the day after the trip starts still pays the child rate
+	birthday := time.Date(2013, time.July, 11, 0, 0, 0, 0, time.UTC)
+	trip.Travellers[2].DateOfBirth = &birthday
+	if _, children := countTravellers(trip); children != 1 {
+		t.Errorf("got %d children, want 1", children)
+	}
+	birthday = birthday.AddDate(0, 0, -1)
+	if _, children := countTravellers(trip); children != 0 {
+		t.Errorf("got %d children on the twelfth birthday, want 0", children)
+	}
+}
+
+func TestPriceLine(t *testing.T) {
+	two, none := 2, 0
+	tests := []struct {
+		name  string
+		price models.Price
+		want  []string
+	}{
+		{"per person with percentages", *safariTrip().Hotels[0].Price, []string{"500.00", "50.00", "88.00", "638.00"}},
+		{"fixed amounts", models.Price{Currency: "USD", NetCost: decimal.NewFromInt(120), Markup: decimal.NewFromInt(15),
+			Taxes: decimal.NewFromInt(5)}, []string{"120.00", "15.00", "5.00", "140.00"}},
+		{"own counts", models.Price{Currency: "USD", AdultRate: decimal.NewFromInt(40), ChildRate: decimal.NewFromInt(25),
+			Adults: &two, Children: &none}, []string{"80.00", "0.00", "0.00", "80.00"}},
+		{"rounded to cents", models.Price{Currency: "USD", NetCost: decimal.RequireFromString("10.005"),
+			TaxPercent: decimal.RequireFromString("7.5")}, []string{"10.01", "0.00", "0.75", "10.76"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			line := priceLine(&test.price, 2, 1, pricingCurrencies())
+			got := []string{line.Net.Amount.StringFixed(2), line.Markup.Amount.StringFixed(2),
+				line.Taxes.Amount.StringFixed(2), line.Total.Amount.StringFixed(2)}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got net, markup, taxes and total %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPriceBreakdown(t *testing.T) {
+	breakdown, err := priceBreakdown(safariTrip(), pricingCurrencies())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	for _, line := range breakdown.Lines {
+		lines = append(lines, fmt.Sprintf("%s %s %s", line.Element, line.Total.Amount.StringFixed(2), line.CustomerTotal.Amount.StringFixed(2)))
+	}
+	want := []string{"hotels[0] 638.00 51040.00", "flights[0] 31500.00 31500.00"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("got lines %v, want %v", lines, want)
+	}
+	if !breakdown.Total.Equal(inr("82540")) || !breakdown.Net.Equal(inr("70000")) || !breakdown.Derived {
+		t.Errorf("got total %s and net %s", breakdown.Total.Amount, breakdown.Net.Amount)
+	}
+
+	noRate := safariTrip()
+	noRate.Flights[0].Price.Currency = "KES"
+	if _, err := priceBreakdown(noRate, pricingCurrencies()); !errors.Is(err, ErrNoExchangeRate) {
+		t.Errorf("got error %v, want %v", err, ErrNoExchangeRate)
+	}
+}
+
+func TestScalePaymentPlan(t *testing.T) {
+	plan := func(amounts ...models.Money) models.PaymentPlan {
+		plan := models.PaymentPlan{AmountDue: inr("1")}
+		for i, amount := range amounts {
+			plan.Installments = append(plan.Installments, models.Installment{InstallmentNumber: i + 1, Amount: amount})
+		}
+		return plan
+	}
+
+	tests := []struct {
+		name   string
+		plan   models.PaymentPlan
+		amount models.Money
+		want   []string
+	}{
+		{"in proportion", plan(inr("300"), inr("700")), inr("2000"), []string{"600.00 INR", "1400.00 INR"}},
+		{"last installment takes the rounding", plan(inr("100"), inr("100"), inr("100")), inr("100"),
+			[]string{"33.33 INR", "33.33 INR", "33.34 INR"}},
+		{"foreign installments keep their currency", plan(usd("100"), inr("8000")), inr("32000"),
+			[]string{"200.00 USD", "16000.00 INR"}},
+		{"an installment in the plan currency balances", plan(inr("8000"), usd("100")), inr("8000"),
+			[]string{"4000.00 INR", "50.00 USD"}},
+		{"zero installments share equally", plan(inr("0"), inr("0")), inr("10"), []string{"5.00 INR", "5.00 INR"}},
+		{"no installments", plan(), inr("123.456"), []string{}},
+	}
+
+	currencies := pricingCurrencies()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original := installmentStrings(test.plan)
+			scaled := scalePaymentPlan(test.plan, test.amount, currencies)
+			if !scaled.AmountDue.Equal(currencies.Round(test.amount)) {
+				t.Errorf("got amount due %s, want %s", scaled.AmountDue.Amount, test.amount.Amount)
+			}
+			if got := installmentStrings(scaled); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+			if !reflect.DeepEqual(installmentStrings(test.plan), original) {
+				t.Error("the original plan was changed")
+			}
+		})
+	}
+}
+
+func TestCopyPriceIsDeep(t *testing.T) {
+	adults, children := 2, 1
+	price := &models.Price{Currency: "USD", AdultRate: decimal.NewFromInt(50), Adults: &adults, Children: &children}
+
+	copied := copyPrice(price)
+	*copied.Adults, *copied.Children = 4, 3
+	scaled := scalePrice(price, decimal.NewFromInt(2))
+	*scaled.Adults = 6
+	joined := addPrices(price, nil)
+	*joined.Children = 5
+
+	if adults != 2 || children != 1 {
+		t.Errorf("changing the copies changed the counts of the original to %d and %d", adults, children)
+	}
+	if copyPrice(nil) != nil {
+		t.Error("copied a missing price")
+	}
+}
+
+func TestCustomerViewPrices(t *testing.T) {
+	itineraries := NewItineraryService(repository.NewInMemoryRepo(), nil)
+	itineraries.SetCurrencies(pricingCurrencies())
+	trip := safariTrip()
+
+	view := itineraries.CustomerView(trip)
+	want := []models.CustomerPrice{
+		{Element: "hotels[0]", Description: "Mara Serena (2 nights)", CustomerTotal: inr("51040")},
+		{Element: "flights[0]", Description: "Flight KQ 205 BOM to NBO", CustomerTotal: inr("31500")},
+	}
+	if len(view.Prices) != len(want) {
+		t.Fatalf("got prices %+v", view.Prices)
+	}
+	for i, price := range view.Prices {
+		if price.Element != want[i].Element || price.Description != want[i].Description || !price.CustomerTotal.Equal(want[i].CustomerTotal) {
+			t.Errorf("got %+v, want %+v", price, want[i])
+		}
+	}
+
+	encoded, err := json.Marshal(view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hidden := range []string{`"price"`, `"net_cost"`, `"adult_rate"`, `"markup"`, `"tax_percent"`} {
+		if strings.Contains(string(encoded), hidden) {
+			t.Errorf("the customer view shows %s", hidden)
+		}
+	}
+	if trip.Hotels[0].Price == nil || trip.Flights[0].Price == nil {
+		t.Error("the customer view removed the prices of the itinerary")
+	}
+}
+
+func TestPriceSerialisesEveryComponent(t *testing.T) {
+	// a customer_total sent by a client is not part of a price and can't hide its components
+	var price models.Price
+	if err := json.Unmarshal([]byte(`{"currency":"USD","net_cost":"120","markup":"15","customer_total":{"amount":"1","currency":"USD"}}`), &price); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(price)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"net_cost":"120"`, `"markup":"15"`} {
+		if !strings.Contains(string(encoded), field) {
+			t.Errorf("got %s, want it to contain %s", encoded, field)
+		}
+	}
+	if strings.Contains(string(encoded), "customer_total") {
+		t.Errorf("got %s", encoded)
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/repository"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidDecision = errors.New("invalid quote decision")
+	ErrNotQuoted       = errors.New("itinerary is not awaiting a quote response")
+	ErrQuoteLinkUsed   = errors.New("quote link has already been used")
+)
+
+// CodeMissingComments flags a change request without any comment
+const CodeMissingComments = "missing_comments"
+
+// decisionStatuses is the status a quoted itinerary moves to on each decision
+var decisionStatuses = map[string]string{
+	models.DecisionAccept:         models.StatusConfirmed,
+	models.DecisionReject:         models.StatusCancelled,
+	models.DecisionRequestChanges: models.StatusDraft,
+}
+
+// decisionReasons is the reason recorded in the status history for each decision
+var decisionReasons = map[string]string{
+	models.DecisionAccept:         "quote accepted by the customer",
+	models.DecisionReject:         "quote rejected by the customer",
+	models.DecisionRequestChanges: "changes requested by the customer",
+}
+
+// QuoteService records customer responses to quotes and the change requests
+// agents still have to work on
+type QuoteService struct {
+	repo        repository.QuoteRevisionRepository
+	itineraries *ItineraryService
+}
+
+// NewQuoteService creates a new quote service
+func NewQuoteService(repo repository.QuoteRevisionRepository, itineraries *ItineraryService) *QuoteService {
+	return &QuoteService{
+		repo:        repo,
+		itineraries: itineraries,
+	}
+}
+
+// NextRevision returns the number of the revision the answer to the current
+// quote of an itinerary will create, which is what quote links are issued for
+func (s *QuoteService) NextRevision(id string) (int, error) {
+	quoted, err := s.itineraries.GetItinerary(id)
+	if err != nil {
+		return 0, err
+	}
+	if quoted.Status != models.StatusQuoted {
+		return 0, fmt.Errorf("%w: it is %s", ErrNotQuoted, quoted.Status)
+	}
+
+	previous, err := s.repo.GetByItineraryID(id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get revisions: %w", err)
+	}
+	return len(previous) + 1, nil
+}
+
+// Respond applies a customer decision to a quoted itinerary through a quote
+// link issued for the given revision. Accepting confirms it, rejecting
+// cancels it and requesting changes sends it back to draft for the agent.
+// Every response is kept as a new revision holding the itinerary as it was
+// quoted, so a link can only be answered once.
+func (s *QuoteService) Respond(id string, revision int, req *models.QuoteResponseReq) (*models.QuoteRevision, error) {
+	status, ok := decisionStatuses[req.Decision]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidDecision, req.Decision)
+	}
+
+	quoted, err := s.itineraries.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.repo.GetByItineraryID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revisions: %w", err)
+	}
+	if revision != len(previous)+1 {
+		return nil, ErrQuoteLinkUsed
+	}
+	if quoted.Status != models.StatusQuoted {
+		return nil, fmt.Errorf("%w: it is %s", ErrNotQuoted, quoted.Status)
+	}
+	if req.Decision == models.DecisionRequestChanges && len(req.Comments) == 0 {
+		return nil, &ValidationError{Issues: []ValidationIssue{
+			issue("comments", CodeMissingComments, "a change request needs at least one comment"),
+		}}
+	}
+	if issues := checkCommentTargets(quoted, req.Comments); len(issues) > 0 {
+		return nil, &ValidationError{Issues: issues}
+	}
+
+	// record the response first and drop it again if the status can't move,
+	// so a status change never goes without its revision
+	response := &models.QuoteRevision{
+		ID:          uuid.New().String(),
+		ItineraryID: id,
+		Number:      revision,
+		Decision:    req.Decision,
+		RespondedBy: req.RespondedBy,
+		RespondedAt: time.Now(),
+		Comments:    make([]models.QuoteComment, len(req.Comments)),
+		Itinerary:   *copyItinerary(quoted),
+	}
+	for i, comment := range req.Comments {
+		comment.ElementRef = anchorElementRef(quoted, comment.ElementRef)
+		response.Comments[i] = comment
+	}
+	// a concurrent answer through the same link loses here
+	if err := s.repo.Create(response); err != nil {
+		if errors.Is(err, repository.ErrRevisionExists) {
+			return nil, ErrQuoteLinkUsed
+		}
+		return nil, fmt.Errorf("failed to create revision: %w", err)
+	}
+
+	if _, err := s.itineraries.TransitionStatus(id, &models.StatusTransitionReq{
+		Status:    status,
+		ChangedBy: req.RespondedBy,
+		Reason:    decisionReasons[req.Decision],
+	}); err != nil {
+		s.repo.Delete(response.ID)
+		return nil, err
+	}
+	return response, nil
+}
+
+// Revisions returns the customer responses to the quotes of an itinerary in order
+func (s *QuoteService) Revisions(id string) ([]*models.QuoteRevision, error) {
+	if _, err := s.itineraries.GetItinerary(id); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.repo.GetByItineraryID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// DeleteItineraryRevisions deletes every revision of an itinerary, and with
+// them its change requests, used when the itinerary is deleted
+func (s *QuoteService) DeleteItineraryRevisions(itineraryID string) error {
+	revisions, err := s.repo.GetByItineraryID(itineraryID)
+	if err != nil {
+		return fmt.Errorf("failed to get revisions: %w", err)
+	}
+	for _, revision := range revisions {
+		if err := s.repo.Delete(revision.ID); err != nil {
+			return fmt.Errorf("failed to delete revision: %w", err)
+		}
+	}
+	return nil
+}
+
+// PendingChangeRequests returns the change requests agents haven't answered
+// yet, oldest first. A request is pending while it is the latest response to
+// the itinerary and the itinerary hasn't been quoted again.
+func (s *QuoteService) PendingChangeRequests() ([]models.ChangeRequest, error) {
+	revisions, err := s.repo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revisions: %w", err)
+	}
+
+	latest := make(map[string]*models.QuoteRevision)
+	for _, revision := range revisions {
+		if current, ok := latest[revision.ItineraryID]; !ok || revision.Number > current.Number {
+			latest[revision.ItineraryID] = revision
+		}
+	}
+
+	queue := []models.ChangeRequest{}
+	for _, revision := range revisions {
+		if latest[revision.ItineraryID] != revision || revision.Decision != models.DecisionRequestChanges {
+			continue
+		}
+		itinerary, err := s.itineraries.GetItinerary(revision.ItineraryID)
+		if err != nil || itinerary.Status != models.StatusDraft {
+			continue
+		}
+		queue = append(queue, models.ChangeRequest{
+			ItineraryID:  itinerary.ID,
+			Title:        itinerary.Title,
+			CustomerName: itinerary.CustomerName,
+			UserID:       itinerary.UserID,
+			Revision:     revision.Number,
+			RespondedBy:  revision.RespondedBy,
+			RespondedAt:  revision.RespondedAt,
+			Comments:     revision.Comments,
+		})
+	}
+	return queue, nil
+}
+
+// checkCommentTargets reports the comments pointing at an element the
+// itinerary doesn't have
+func checkCommentTargets(itinerary *models.Itinerary, comments []models.QuoteComment) []ValidationIssue {
+	var issues []ValidationIssue
+	for i, comment := range comments {
+		issues = append(issues, checkElementRef(itinerary, fmt.Sprintf("comments[%d]", i), comment.ElementRef)...)
+	}
+	return issues
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/repository"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// marrakechTrip returns a valid, quoted two day trip to Marrakech
+func marrakechTrip(id string) *models.Itinerary {
+	date := func(d int) time.Time { return time.Date(2025, time.November, d, 0, 0, 0, 0, time.UTC) }
+	due := models.NewMoney(decimal.NewFromInt(85000), "INR")
+	return &models.Itinerary{
+		ID:           id,
+		UserID:       "agent-morocco",
+		Title:        "Marrakech getaway",
+		CustomerName: "Farah Khan",
+		StartDate:    date(20),
+		EndDate:      date(21),
+		Days: []models.Day{
+			{DayNumber: 1, Date: date(20), Title: "Medina"},
+			{DayNumber: 2, Date: date(21), Title: "Atlas mountains"},
+		},
+		Hotels: []models.Hotel{
+			{Name: "Riad Kniza", City: "Marrakech", CheckInDate: date(20), CheckOutDate: date(21), Nights: 1},
+		},
+		PaymentPlan: models.PaymentPlan{
+			AmountDue: due,
+			DueDate:   date(1),
+			Installments: []models.Installment{
+				{InstallmentNumber: 1, Amount: due, DueDate: date(1), Status: "Pending"},
+			},
+		},
+		Status: models.StatusQuoted,
+	}
+}
+
+// quoteFixture is a quote service over in memory repositories
+type quoteFixture struct {
+	itineraries *ItineraryService
+	quotes      *QuoteService
+}
+
+// newQuoteFixture stores the given itineraries and wires the quote service
+// the way the routes do
+func newQuoteFixture(t *testing.T, stored ...*models.Itinerary) quoteFixture {
+	t.Helper()
+	repo := repository.NewInMemoryRepo()
+	for _, itinerary := range stored {
+		if err := repo.Create(itinerary); err != nil {
+			t.Fatal(err)
+		}
+	}
+	itineraries := NewItineraryService(repo, nil)
+	quotes := NewQuoteService(repository.NewInMemoryQuoteRevisionRepo(), itineraries)
+	itineraries.OnDelete(quotes.DeleteItineraryRevisions)
+	return quoteFixture{itineraries: itineraries, quotes: quotes}
+}
+
+// changes returns a change request with one comment on the whole quote
+func changes(text string) *models.QuoteResponseReq {
+	return &models.QuoteResponseReq{
+		Decision:    models.DecisionRequestChanges,
+		RespondedBy: "Farah Khan",
+		Comments:    []models.QuoteComment{{Text: text}},
+	}
+}
+
+func TestQuoteRespond(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        *models.QuoteResponseReq
+		revision   int
+		wantStatus string
+		wantErr    error
+	}{
+		{"accept", &models.QuoteResponseReq{Decision: models.DecisionAccept, RespondedBy: "Farah Khan"}, 1,
+			models.StatusConfirmed, nil},
+		{"reject", &models.QuoteResponseReq{Decision: models.DecisionReject, RespondedBy: "Farah Khan"}, 1,
+			models.StatusCancelled, nil},
+		{"request changes", changes("A riad with a pool, please"), 1, models.StatusDraft, nil},
+		{"unknown decision", &models.QuoteResponseReq{Decision: "maybe", RespondedBy: "Farah Khan"}, 1,
+			models.StatusQuoted, ErrInvalidDecision},
+		{"link for another revision", &models.QuoteResponseReq{Decision: models.DecisionAccept, RespondedBy: "Farah Khan"}, 2,
+			models.StatusQuoted, ErrQuoteLinkUsed},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fixture := newQuoteFixture(t, marrakechTrip("mar-1"))
+			revision, err := fixture.quotes.Respond("mar-1", test.revision, test.req)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if err == nil && (revision.Number != 1 || revision.Itinerary.Status != models.StatusQuoted) {
+				t.Errorf("got revision %d of a %s itinerary", revision.Number, revision.Itinerary.Status)
+			}
+			if stored, _ := fixture.itineraries.GetItinerary("mar-1"); stored.Status != test.wantStatus {
+				t.Errorf("got status %s, want %s", stored.Status, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestQuoteRespondRejectsInvalidAnswers(t *testing.T) {
+	draft := marrakechTrip("mar-2")
+	draft.Status = models.StatusDraft
+	fixture := newQuoteFixture(t, marrakechTrip("mar-1"), draft)
+
+	if _, err := fixture.quotes.Respond("missing", 1, changes("Hello")); !errors.Is(err, ErrItineraryNotFound) {
+		t.Errorf("unknown itinerary: got error %v", err)
+	}
+	if _, err := fixture.quotes.Respond("mar-2", 1, changes("Hello")); !errors.Is(err, ErrNotQuoted) {
+		t.Errorf("draft: got error %v", err)
+	}
+
+	var validation *ValidationError
+	noComments := &models.QuoteResponseReq{Decision: models.DecisionRequestChanges, RespondedBy: "Farah Khan"}
+	if _, err := fixture.quotes.Respond("mar-1", 1, noComments); !errors.As(err, &validation) ||
+		validation.Issues[0].Code != CodeMissingComments {
+		t.Errorf("change request without comments: got error %v", err)
+	}
+	unknownDay := changes("Skip this day")
+	unknownDay.Comments[0].Day = 5
+	if _, err := fixture.quotes.Respond("mar-1", 1, unknownDay); !errors.As(err, &validation) {
+		t.Errorf("comment on a missing day: got error %v", err)
+	}
+
+	if revisions, _ := fixture.quotes.Revisions("mar-1"); len(revisions) != 0 {
+		t.Errorf("rejected answers created %d revisions", len(revisions))
+	}
+}
+
+func TestQuoteLinkIsSingleUse(t *testing.T) {
+	fixture := newQuoteFixture(t, marrakechTrip("mar-1"))
+
+	revision, err := fixture.quotes.NextRevision("mar-1")
+	if err != nil || revision != 1 {
+		t.Fatalf("got revision %d and error %v", revision, err)
+	}
+	if _, err := fixture.quotes.Respond("mar-1", revision, changes("Add a hammam")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fixture.quotes.NextRevision("mar-1"); !errors.Is(err, ErrNotQuoted) {
+		t.Errorf("draft: got error %v, want %v", err, ErrNotQuoted)
+	}
+
+	// the agent quotes again, the old link stays used while a new one works
+	if _, err := fixture.itineraries.TransitionStatus("mar-1", &models.StatusTransitionReq{Status: models.StatusQuoted, ChangedBy: "agent-morocco"}); err != nil {
+		t.Fatal(err)
+	}
+	accept := &models.QuoteResponseReq{Decision: models.DecisionAccept, RespondedBy: "Farah Khan"}
+	if _, err := fixture.quotes.Respond("mar-1", revision, accept); !errors.Is(err, ErrQuoteLinkUsed) {
+		t.Errorf("reused link: got error %v, want %v", err, ErrQuoteLinkUsed)
+	}
+	next, err := fixture.quotes.NextRevision("mar-1")
+	if err != nil || next != 2 {
+		t.Fatalf("got revision %d and error %v", next, err)
+	}
+	if _, err := fixture.quotes.Respond("mar-1", next, accept); err != nil {
+		t.Fatal(err)
+	}
+
+	revisions, _ := fixture.quotes.Revisions("mar-1")
+	if len(revisions) != 2 || revisions[0].Decision != models.DecisionRequestChanges || revisions[1].Decision != models.DecisionAccept {
+		t.Errorf("got revisions %+v", revisions)
+	}
+}
+
+func TestPendingChangeRequests(t *testing.T) {
+	fixture := newQuoteFixture(t, marrakechTrip("mar-1"), marrakechTrip("mar-2"), marrakechTrip("mar-3"))
+	for _, id := range []string{"mar-1", "mar-2", "mar-3"} {
+		if _, err := fixture.quotes.Respond(id, 1, changes("Cheaper flights for "+id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// mar-2 was quoted again and mar-3 deleted, so only mar-1 is waiting
+	if _, err := fixture.itineraries.TransitionStatus("mar-2", &models.StatusTransitionReq{Status: models.StatusQuoted, ChangedBy: "agent-morocco"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fixture.itineraries.DeleteItinerary("mar-3"); err != nil {
+		t.Fatal(err)
+	}
+
+	queue, err := fixture.quotes.PendingChangeRequests()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(queue) != 1 || queue[0].ItineraryID != "mar-1" || queue[0].Revision != 1 || queue[0].Comments[0].Text != "Cheaper flights for mar-1" {
+		t.Errorf("got queue %+v", queue)
+	}
+
+	// deleting the itinerary deleted its revisions with it
+	revisions, err := fixture.quotes.repo.GetByItineraryID("mar-3")
+	if err != nil || len(revisions) != 0 {
+		t.Errorf("got %d revisions of a deleted itinerary", len(revisions))
+	}
+}
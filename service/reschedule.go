@@ -0,0 +1,89 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"time"
+)
+
+var ErrInvalidReschedule = errors.New("give either offset_days or new_start_date")
+
+// Reschedule moves a whole itinerary by a number of days, or to a new start
+// date. Days, hotel stays, flights, transfers and payment dates move together;
+// with KeepInstallmentDates the payment plan keeps its due dates. Flights and
+// transfers keep their local wall clock time, also across daylight saving
+// changes. The result goes through the regular update validation.
+func (s *ItineraryService) Reschedule(id string, req *models.RescheduleReq) (*models.Itinerary, error) {
+	if (req.OffsetDays == nil) == (req.NewStartDate == nil) {
+		return nil, ErrInvalidReschedule
+	}
+
+	itinerary, err := s.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := 0
+	if req.OffsetDays != nil {
+		offset = *req.OffsetDays
+	} else {
+		offset = daysBetween(itinerary.StartDate, *req.NewStartDate)
+	}
+
+	return s.UpdateItinerary(id, shiftedItinerary(itinerary, offset, req.KeepInstallmentDates))
+}
+
+// shiftedItinerary returns an update moving every date of an itinerary by
+// offset days. The stored itinerary is left untouched.
+func shiftedItinerary(itinerary *models.Itinerary, offset int, keepInstallmentDates bool) *models.UpdateItineraryReq {
+	shiftDate := func(t time.Time) time.Time {
+		return t.AddDate(0, 0, offset)
+	}
+	// instants are shifted on the local calendar so 10:00 stays 10:00
+	shiftInstant := func(t time.Time, zone string) time.Time {
+		return inZone(t, zone).AddDate(0, 0, offset).UTC()
+	}
+
+	startDate := shiftDate(itinerary.StartDate)
+	endDate := shiftDate(itinerary.EndDate)
+	req := &models.UpdateItineraryReq{
+		StartDate: &startDate,
+		EndDate:   &endDate,
+		Days:      make([]models.Day, len(itinerary.Days)),
+		Hotels:    make([]models.Hotel, len(itinerary.Hotels)),
+		Flights:   make([]models.Flight, len(itinerary.Flights)),
+		Transfers: make([]models.Transfer, len(itinerary.Transfers)),
+	}
+
+	for i, day := range itinerary.Days {
+		day.Date = shiftDate(day.Date)
+		req.Days[i] = day
+	}
+	for i, hotel := range itinerary.Hotels {
+		hotel.CheckInDate = shiftDate(hotel.CheckInDate)
+		hotel.CheckOutDate = shiftDate(hotel.CheckOutDate)
+		req.Hotels[i] = hotel
+	}
+	for i, flight := range itinerary.Flights {
+		flight.Departure = shiftInstant(flight.Departure, flight.DepartureTimeZone)
+		flight.Arrival = shiftInstant(flight.Arrival, flight.ArrivalTimeZone)
+		req.Flights[i] = flight
+	}
+	for i, transfer := range itinerary.Transfers {
+		transfer.Timing = shiftInstant(transfer.Timing, transferZone(itinerary, transfer))
+		req.Transfers[i] = transfer
+	}
+
+	if !keepInstallmentDates {
+		plan := itinerary.PaymentPlan
+		plan.DueDate = shiftDate(plan.DueDate)
+		plan.Installments = make([]models.Installment, len(itinerary.PaymentPlan.Installments))
+		for i, installment := range itinerary.PaymentPlan.Installments {
+			installment.DueDate = shiftDate(installment.DueDate)
+			plan.Installments[i] = installment
+		}
+		req.PaymentPlan = &plan
+	}
+
+	return req
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/repository"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// alpsTrip stores a ski week in Zermatt that starts a week before the
+// clocks go forward in Switzerland, and returns its ID
+func alpsTrip(t *testing.T, itineraries *ItineraryService) string {
+	t.Helper()
+	zurich, err := time.LoadLocation("Europe/Zurich")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(day int) time.Time { return time.Date(2025, time.March, day, 0, 0, 0, 0, time.UTC) }
+	chf := func(amount int64) models.Money { return models.NewMoney(decimal.NewFromInt(amount), "CHF") }
+
+	created, err := itineraries.CreateItinerary(&models.CreateItineraryReq{
+		UserID:       "agent-alps",
+		Title:        "Zermatt ski week",
+		Destination:  "Zermatt",
+		StartDate:    date(23),
+		EndDate:      date(25),
+		GenerateDays: true,
+		Hotels: []models.Hotel{{Name: "Riffelalp Resort", City: "Zermatt", CheckInDate: date(23), CheckOutDate: date(25),
+			Nights: 2, Address: "Riffelalp 1"}},
+		Flights: []models.Flight{{FlightNumber: "LX 154", Airline: "LX", From: "BOM", To: "ZRH",
+			Departure: time.Date(2025, time.March, 23, 1, 50, 0, 0, time.FixedZone("IST", 19800)),
+			Arrival:   time.Date(2025, time.March, 23, 7, 0, 0, 0, zurich)}},
+		Transfers: []models.Transfer{{From: "Zurich Airport (ZRH)", To: "Riffelalp Resort", Mode: "Train", FlightNumber: "LX 154",
+			Timing: time.Date(2025, time.March, 23, 8, 0, 0, 0, zurich)}},
+		PaymentPlan: models.PaymentPlan{
+			AmountDue: chf(6000),
+			DueDate:   date(1),
+			Installments: []models.Installment{
+				{InstallmentNumber: 1, Amount: chf(2000), DueDate: date(1)},
+				{InstallmentNumber: 2, Amount: chf(4000), DueDate: date(15)},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created.ID
+}
+
+func TestReschedule(t *testing.T) {
+	offset := func(days int) *int { return &days }
+	april := func(day int) *time.Time {
+		date := time.Date(2025, time.April, day, 0, 0, 0, 0, time.UTC)
+		return &date
+	}
+
+	tests := []struct {
+		name            string
+		req             models.RescheduleReq
+		wantStart       string
+		wantArrival     string
+		wantPickUp      string
+		wantInstallment string
+		wantErr         error
+	}{
+		// the clocks go forward on March 30, the flight still lands at 07:00 local
+		{"a week later across the clock change", models.RescheduleReq{OffsetDays: offset(7)},
+			"2025-03-30", "2025-03-30T07:00:00+02:00", "2025-03-30T08:00:00+02:00", "2025-03-08", nil},
+		{"to a new start date", models.RescheduleReq{NewStartDate: april(6)},
+			"2025-04-06", "2025-04-06T07:00:00+02:00", "2025-04-06T08:00:00+02:00", "2025-03-15", nil},
+		{"earlier", models.RescheduleReq{OffsetDays: offset(-2)},
+			"2025-03-21", "2025-03-21T07:00:00+01:00", "2025-03-21T08:00:00+01:00", "2025-02-27", nil},
+		{"installments keep their dates", models.RescheduleReq{OffsetDays: offset(7), KeepInstallmentDates: true},
+			"2025-03-30", "2025-03-30T07:00:00+02:00", "2025-03-30T08:00:00+02:00", "2025-03-01", nil},
+		{"both an offset and a date", models.RescheduleReq{OffsetDays: offset(7), NewStartDate: april(6)}, "", "", "", "", ErrInvalidReschedule},
+		{"neither an offset nor a date", models.RescheduleReq{}, "", "", "", "", ErrInvalidReschedule},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itineraries := NewItineraryService(repository.NewInMemoryRepo(), nil)
+			id := alpsTrip(t, itineraries)
+
+			got, err := itineraries.Reschedule(id, &test.req)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if start := got.StartDate.Format("2006-01-02"); start != test.wantStart {
+				t.Errorf("got start %s, want %s", start, test.wantStart)
+			}
+			if days := got.Days; len(days) != 3 || days[0].Date.Format("2006-01-02") != test.wantStart {
+				t.Errorf("got days %+v starting on the wrong date", days)
+			}
+			if hotel := got.Hotels[0]; !hotel.CheckInDate.Equal(got.StartDate) || hotel.CheckOutDate.Sub(hotel.CheckInDate) != 48*time.Hour {
+				t.Errorf("got a stay from %s to %s", hotel.CheckInDate, hotel.CheckOutDate)
+			}
+			if arrival := got.Flights[0].Arrival.Format(time.RFC3339); arrival != test.wantArrival {
+				t.Errorf("got arrival %s, want %s", arrival, test.wantArrival)
+			}
+			if pickUp := got.Transfers[0].Timing.Format(time.RFC3339); pickUp != test.wantPickUp {
+				t.Errorf("got pick-up %s, want %s", pickUp, test.wantPickUp)
+			}
+			if due := got.PaymentPlan.Installments[0].DueDate.Format("2006-01-02"); due != test.wantInstallment {
+				t.Errorf("got first installment due %s, want %s", due, test.wantInstallment)
+			}
+		})
+	}
+}
+
+func TestRescheduleUnknownItinerary(t *testing.T) {
+	days := 3
+	itineraries := NewItineraryService(repository.NewInMemoryRepo(), nil)
+	if _, err := itineraries.Reschedule("missing", &models.RescheduleReq{OffsetDays: &days}); err == nil {
+		t.Error("rescheduled an itinerary that doesn't exist")
+	}
+}
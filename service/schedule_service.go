@@ -0,0 +1,297 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule issue codes
+const (
+	CodeInvalidActivityTimes = "invalid_activity_times"
+	CodeActivityOverlap      = "activity_overlap"
+	CodeActivityOutsideSlot  = "activity_outside_slot"
+	CodeUnparsedDuration     = "unparsed_duration"
+)
+
+// defaultActivityMinutes is assumed for activities without a known duration
+const defaultActivityMinutes = 60
+
+// timeSlot is one of the morning, afternoon and evening buckets of a day.
+// Activities without a start time are planned from the slot's usual start.
+type timeSlot struct {
+	name       string
+	start, end models.TimeOfDay
+	usualStart models.TimeOfDay
+}
+
+// timeSlots are the bounds of the activity buckets in local time
+var timeSlots = []timeSlot{
+	{"morning", 6 * 60, 12 * 60, 9 * 60},
+	{"afternoon", 12 * 60, 17 * 60, 14 * 60},
+	{"evening", 17 * 60, 24 * 60, 19 * 60},
+}
+
+// slotActivities returns the activities of a day's bucket
+func slotActivities(activities models.Activities, slot string) []models.Activity {
+	switch slot {
+	case "morning":
+		return activities.Morning
+	case "afternoon":
+		return activities.Afternoon
+	default:
+		return activities.Evening
+	}
+}
+
+// TimelineEntry is an activity placed on the clock. Activities without a
+// start time are placed after the previous one of their slot, or at the
+// slot's usual start, and marked as estimated.
+type TimelineEntry struct {
+	Slot            string           `json:"slot"`
+	Name            string           `json:"name"`
+	Location        string           `json:"location"`
+	Start           models.TimeOfDay `json:"start"`
+	End             models.TimeOfDay `json:"end"`
+	DurationMinutes int              `json:"duration_minutes"`
+	Estimated       bool             `json:"estimated"`
+
+	// start and end as instants in the day's time zone
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+
+	path     string
+	fixed    bool
+	activity models.Activity
+}
+
+// TimelineDay is the schedule of a single day
+type TimelineDay struct {
+	DayNumber int             `json:"day_number"`
+	Date      time.Time       `json:"date"`
+	Title     string          `json:"title"`
+	TimeZone  string          `json:"time_zone,omitempty"`
+	Entries   []TimelineEntry `json:"entries"`
+}
+
+// Timeline is the hour by hour view of an itinerary
+type Timeline struct {
+	ItineraryID string        `json:"itinerary_id"`
+	Days        []TimelineDay `json:"days"`
+}
+
+// ScheduleService places activities on the clock and checks their times
+type ScheduleService struct{}
+
+// NewScheduleService creates a new schedule service
+func NewScheduleService() *ScheduleService {
+	return &ScheduleService{}
+}
+
+// Timeline builds the schedule of every day of an itinerary
+func (s *ScheduleService) Timeline(itinerary *models.Itinerary) *Timeline {
+	timeline := &Timeline{
+		ItineraryID: itinerary.ID,
+		Days:        []TimelineDay{},
+	}
+	for i, day := range itinerary.Days {
+		timeline.Days = append(timeline.Days, TimelineDay{
+			DayNumber: day.DayNumber,
+			Date:      day.Date,
+			Title:     day.Title,
+			TimeZone:  day.TimeZone,
+			Entries:   s.schedule(i, day),
+		})
+	}
+	return timeline
+}
+
+// schedule places the activities of a day slot by slot
+func (s *ScheduleService) schedule(dayIndex int, day models.Day) []TimelineEntry {
+	entries := []TimelineEntry{}
+	for _, slot := range timeSlots {
+		cursor := slot.usualStart
+		for j, activity := range slotActivities(day.Activities, slot.name) {
+			entry := TimelineEntry{
+				Slot:            slot.name,
+				Name:            activity.Name,
+				Location:        activity.Location,
+				DurationMinutes: activityMinutes(activity),
+				path:            fmt.Sprintf("days[%d].activities.%s[%d]", dayIndex, slot.name, j),
+				fixed:           activity.StartTime != nil || activity.EndTime != nil,
+				activity:        activity,
+			}
+
+			switch {
+			case activity.StartTime != nil:
+				entry.Start = *activity.StartTime
+			case activity.EndTime != nil:
+				// an activity can't start before midnight, the slot check flags it
+				entry.Start = max(*activity.EndTime-models.TimeOfDay(entry.DurationMinutes), 0)
+			default:
+				entry.Start = cursor
+			}
+			if activity.EndTime != nil {
+				entry.End = *activity.EndTime
+			} else {
+				entry.End = entry.Start + models.TimeOfDay(entry.DurationMinutes)
+			}
+			entry.Estimated = activity.StartTime == nil || (activity.EndTime == nil && activity.DurationMinutes == 0)
+
+			entry.StartsAt = atLocalTime(day.Date, entry.Start, day.TimeZone)
+			entry.EndsAt = atLocalTime(day.Date, entry.End, day.TimeZone)
+
+			if entry.End > cursor {
+				cursor = entry.End
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// ValidationRule checks activity times: an end before its start and two
+// timed activities overlapping are errors, an activity running outside its
+// slot or a duration that can't be read are warnings
+func (s *ScheduleService) ValidationRule() ValidationRule {
+	return func(itinerary *models.Itinerary) []ValidationIssue {
+		var issues []ValidationIssue
+
+		for i, day := range itinerary.Days {
+			for _, slot := range timeSlots {
+				for j, activity := range slotActivities(day.Activities, slot.name) {
+					path := fmt.Sprintf("days[%d].activities.%s[%d]", i, slot.name, j)
+					if activity.StartTime != nil && activity.EndTime != nil && *activity.EndTime <= *activity.StartTime {
+						issues = append(issues, issue(path+".end_time", CodeInvalidActivityTimes,
+							"%s must end after it starts (%s-%s)", activity.Name, activity.StartTime, activity.EndTime))
+					}
+					if activity.Duration != "" && activity.DurationMinutes == 0 {
+						if _, ok := parseDuration(activity.Duration); !ok {
+							issues = append(issues, warning(path+".duration", CodeUnparsedDuration,
+								"duration %q of %s could not be understood, use e.g. \"2 hours\" or \"90 minutes\"", activity.Duration, activity.Name))
+						}
+					}
+				}
+			}
+
+			entries := s.schedule(i, day)
+			for _, entry := range entries {
+				slot := slotBounds(entry.Slot)
+				if entry.Start < slot.start || entry.End > slot.end {
+					issues = append(issues, warning(entry.path, CodeActivityOutsideSlot,
+						"day %d: %s (%s-%s) runs outside the %s slot (%s-%s)", day.DayNumber, entry.Name,
+						entry.Start, entry.End, entry.Slot, slot.start, slot.end))
+				}
+			}
+			issues = append(issues, overlaps(day, entries)...)
+		}
+
+		return issues
+	}
+}
+
+// overlaps reports timed activities of a day that overlap each other
+func overlaps(day models.Day, entries []TimelineEntry) []ValidationIssue {
+	var timed []TimelineEntry
+	for _, entry := range entries {
+		if entry.fixed {
+			timed = append(timed, entry)
+		}
+	}
+	sort.SliceStable(timed, func(a, b int) bool { return timed[a].Start < timed[b].Start })
+
+	var issues []ValidationIssue
+	for n := 1; n < len(timed); n++ {
+		for m := 0; m < n; m++ {
+			if timed[n].Start < timed[m].End {
+				issues = append(issues, issue(timed[n].path, CodeActivityOverlap,
+					"day %d: %s (%s-%s) overlaps %s (%s-%s)", day.DayNumber, timed[n].Name, timed[n].Start,
+					timed[n].End, timed[m].Name, timed[m].Start, timed[m].End))
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// slotBounds returns the bounds of a named slot
+func slotBounds(name string) timeSlot {
+	for _, slot := range timeSlots {
+		if slot.name == name {
+			return slot
+		}
+	}
+	return timeSlot{name: name, start: 0, end: 24 * 60}
+}
+
+// activityMinutes returns how long an activity takes: its typed duration,
+// the time between its start and end, or the default
+func activityMinutes(activity models.Activity) int {
+	switch {
+	case activity.DurationMinutes > 0:
+		return activity.DurationMinutes
+	case activity.StartTime != nil && activity.EndTime != nil && *activity.EndTime > *activity.StartTime:
+		return int(*activity.EndTime - *activity.StartTime)
+	}
+	return defaultActivityMinutes
+}
+
+// normalizeActivities fills in the typed duration of every activity from its
+// free text duration, or from its start and end times
+func normalizeActivities(itinerary *models.Itinerary) {
+	for i := range itinerary.Days {
+		activities := &itinerary.Days[i].Activities
+		for _, slot := range [][]models.Activity{activities.Morning, activities.Afternoon, activities.Evening} {
+			for j := range slot {
+				activity := &slot[j]
+				if activity.DurationMinutes > 0 {
+					continue
+				}
+				if minutes, ok := parseDuration(activity.Duration); ok {
+					activity.DurationMinutes = minutes
+				} else if activity.StartTime != nil && activity.EndTime != nil && *activity.EndTime > *activity.StartTime {
+					activity.DurationMinutes = int(*activity.EndTime - *activity.StartTime)
+				}
+			}
+		}
+	}
+}
+
+// durationPart matches one amount of a free text duration, e.g. "2 hours",
+// "1.5h" or "30 mins"
+var durationPart = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*(hours?|hrs?|h|minutes?|mins?|m)\b`)
+
+// parseDuration reads a free text duration such as "2 hours", "1h 30m",
+// "90 minutes" or "half day" into minutes. A range like "2-3 hours" is read
+// as its upper bound.
+func parseDuration(text string) (int, bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	switch {
+	case text == "":
+		return 0, false
+	case strings.Contains(text, "half day") || strings.Contains(text, "half-day"):
+		return 4 * 60, true
+	case strings.Contains(text, "full day") || strings.Contains(text, "full-day") || strings.Contains(text, "all day"):
+		return 8 * 60, true
+	}
+
+	total := 0.0
+	for _, m := range durationPart.FindAllStringSubmatch(text, -1) {
+		amount, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return 0, false
+		}
+		if strings.HasPrefix(m[2], "h") {
+			amount *= 60
+		}
+		total += amount
+	}
+	if total <= 0 {
+		return 0, false
+	}
+	return int(total + 0.5), true
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// clock returns a pointer to a local time of day, e.g. clock(9, 30)
+func clock(hour, minute int) *models.TimeOfDay {
+	at := models.TimeOfDay(hour*60 + minute)
+	return &at
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   int
+		wantOK bool
+	}{
+		{"2 hours", 120, true},
+		{"90 minutes", 90, true},
+		{"1h 30m", 90, true},
+		{"1.5h", 90, true},
+		{"45 mins", 45, true},
+		{"2-3 hours", 180, true},
+		{"Half day", 240, true},
+		{"full-day", 480, true},
+		{"", 0, false},
+		{"a while", 0, false},
+		{"0 hours", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			got, ok := parseDuration(test.text)
+			if got != test.want || ok != test.wantOK {
+				t.Errorf("got %d, %v, want %d, %v", got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestScheduleTimeline(t *testing.T) {
+	day := models.Day{
+		DayNumber: 1,
+		Date:      time.Date(2025, time.November, 20, 0, 0, 0, 0, time.UTC),
+		TimeZone:  "Asia/Kolkata",
+		Activities: models.Activities{
+			Morning: []models.Activity{
+				{Name: "Amber Fort", DurationMinutes: 150},
+				{Name: "Jal Mahal"},
+			},
+			Afternoon: []models.Activity{
+				{Name: "City Palace", StartTime: clock(13, 0), EndTime: clock(15, 30)},
+				{Name: "Hawa Mahal", DurationMinutes: 45},
+			},
+			Evening: []models.Activity{
+				{Name: "Chokhi Dhani dinner", EndTime: clock(22, 0), DurationMinutes: 180},
+			},
+		},
+	}
+
+	want := []struct {
+		name       string
+		start, end models.TimeOfDay
+		estimated  bool
+	}{
+		{"Amber Fort", 9 * 60, 11*60 + 30, true},
+		// placed after the previous activity of the slot, for the default hour
+		{"Jal Mahal", 11*60 + 30, 12*60 + 30, true},
+		{"City Palace", 13 * 60, 15*60 + 30, false},
+		{"Hawa Mahal", 15*60 + 30, 16*60 + 15, true},
+		// an end time and a duration give the start
+		{"Chokhi Dhani dinner", 19 * 60, 22 * 60, true},
+	}
+
+	timeline := NewScheduleService().Timeline(&models.Itinerary{ID: "it-jaipur", Days: []models.Day{day}})
+	entries := timeline.Days[0].Entries
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(entries), len(want))
+	}
+	for i, entry := range entries {
+		if entry.Name != want[i].name || entry.Start != want[i].start || entry.End != want[i].end || entry.Estimated != want[i].estimated {
+			t.Errorf("got %s %s-%s estimated %v, want %s %s-%s estimated %v", entry.Name, entry.Start, entry.End, entry.Estimated,
+				want[i].name, want[i].start, want[i].end, want[i].estimated)
+		}
+	}
+
+	// 13:00 in Jaipur is 07:30 UTC
+	if got, want := entries[2].StartsAt.UTC(), time.Date(2025, time.November, 20, 7, 30, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("got City Palace at %s, want %s", got, want)
+	}
+}
+
+func TestScheduleValidationRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		activity models.Activities
+		want     []string
+	}{
+		{"activities in their slots", models.Activities{
+			Morning:   []models.Activity{{Name: "Amber Fort", StartTime: clock(9, 0), EndTime: clock(11, 0)}},
+			Afternoon: []models.Activity{{Name: "City Palace", StartTime: clock(13, 0), EndTime: clock(15, 0)}},
+		}, nil},
+		{"ends before it starts", models.Activities{
+			Morning: []models.Activity{{Name: "Amber Fort", StartTime: clock(11, 0), EndTime: clock(9, 0)}},
+		}, []string{"error " + CodeInvalidActivityTimes}},
+		{"timed activities overlap", models.Activities{
+			Afternoon: []models.Activity{
+				{Name: "City Palace", StartTime: clock(13, 0), EndTime: clock(15, 0)},
+				{Name: "Jantar Mantar", StartTime: clock(14, 30), EndTime: clock(16, 0)},
+			},
+		}, []string{"error " + CodeActivityOverlap}},
+		// estimated times never overlap a timed activity
+		{"estimated activity next to a timed one", models.Activities{
+			Afternoon: []models.Activity{
+				{Name: "City Palace", StartTime: clock(13, 0), EndTime: clock(15, 0)},
+				{Name: "Hawa Mahal"},
+			},
+		}, nil},
+		{"runs past the end of its slot", models.Activities{
+			Morning: []models.Activity{{Name: "Amber Fort", StartTime: clock(11, 0), DurationMinutes: 120}},
+		}, []string{"warning " + CodeActivityOutsideSlot}},
+		{"duration that can't be read", models.Activities{
+			Evening: []models.Activity{{Name: "Sound and light show", Duration: "until late"}},
+		}, []string{"warning " + CodeUnparsedDuration}},
+	}
+
+	rule := NewScheduleService().ValidationRule()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itinerary := &models.Itinerary{Days: []models.Day{{DayNumber: 1, Activities: test.activity}}}
+			var got []string
+			for _, found := range rule(itinerary) {
+				got = append(got, found.Severity+" "+found.Code)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
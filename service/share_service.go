@@ -0,0 +1,163 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidShareSignature = errors.New("invalid share link signature")
+	ErrShareLinkExpired      = errors.New("share link has expired")
+)
+
+// DefaultShareLinkTTL is how long share links stay valid when no TTL is configured
+const DefaultShareLinkTTL = 90 * 24 * time.Hour
+
+// DefaultQuoteLinkTTL is how long quote links stay valid when no TTL is configured
+const DefaultQuoteLinkTTL = 14 * 24 * time.Hour
+
+// ShareLinkService signs and verifies public links to the live itinerary and
+// the links customers answer quotes with. Every link carries its expiry, and
+// itinerary, flight and quote links are signed in different domains so one
+// can't be turned into another.
+type ShareLinkService struct {
+	baseURL  string
+	secret   []byte
+	ttl      time.Duration
+	quoteTTL time.Duration
+	now      func() time.Time
+}
+
+// NewShareLinkService creates a new share link service. When no secret is
+// configured a random one is generated, so links only last until restart.
+func NewShareLinkService(baseURL, secret string, ttl time.Duration) (*ShareLinkService, error) {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("generating share link secret: %w", err)
+		}
+	}
+	if ttl <= 0 {
+		ttl = DefaultShareLinkTTL
+	}
+
+	return &ShareLinkService{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		secret:   key,
+		ttl:      ttl,
+		quoteTTL: DefaultQuoteLinkTTL,
+		now:      time.Now,
+	}, nil
+}
+
+// SetQuoteLinkTTL changes how long quote links stay valid
+func (s *ShareLinkService) SetQuoteLinkTTL(ttl time.Duration) {
+	if ttl > 0 {
+		s.quoteTTL = ttl
+	}
+}
+
+// ItineraryURL returns the signed share URL for an itinerary
+func (s *ShareLinkService) ItineraryURL(itineraryID string) string {
+	expires := s.expiry()
+	query := url.Values{}
+	query.Set("exp", expires)
+	query.Set("sig", s.sign("itinerary", itineraryID, expires))
+
+	return fmt.Sprintf("%s/api/v1/share/%s?%s",
+		s.baseURL, url.PathEscape(itineraryID), query.Encode())
+}
+
+// FlightURL returns the signed share URL for a single flight of an itinerary.
+// The booking reference is optional; when given it is signed with the link
+// and the flight must still carry it.
+func (s *ShareLinkService) FlightURL(itineraryID, flightNumber, bookingReference string) string {
+	expires := s.expiry()
+	query := url.Values{}
+	if bookingReference != "" {
+		query.Set("ref", bookingReference)
+	}
+	query.Set("exp", expires)
+	query.Set("sig", s.sign("flight", itineraryID, flightNumber, bookingReference, expires))
+
+	return fmt.Sprintf("%s/api/v1/share/%s/flights/%s?%s",
+		s.baseURL, url.PathEscape(itineraryID), url.PathEscape(flightNumber), query.Encode())
+}
+
+// QuoteURL returns the link the customer answers a quote with, and when it
+// expires. The link is signed for one revision, the one its answer will
+// create, so it can only be used once. It is only handed to the agent and
+// never printed.
+func (s *ShareLinkService) QuoteURL(itineraryID string, revision int) (string, time.Time) {
+	expiresAt := s.now().Add(s.quoteTTL)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	rev := strconv.Itoa(revision)
+	query := url.Values{}
+	query.Set("rev", rev)
+	query.Set("exp", expires)
+	query.Set("token", s.sign("quote", itineraryID, rev, expires))
+
+	return fmt.Sprintf("%s/api/v1/share/%s/quote?%s",
+		s.baseURL, url.PathEscape(itineraryID), query.Encode()), time.Unix(expiresAt.Unix(), 0)
+}
+
+// VerifyQuote checks the token and expiry of a quote link and returns the
+// revision it was issued for
+func (s *ShareLinkService) VerifyQuote(itineraryID, revision, expires, token string) (int, error) {
+	if err := s.verify(expires, token, "quote", itineraryID, revision, expires); err != nil {
+		return 0, err
+	}
+	number, err := strconv.Atoi(revision)
+	if err != nil {
+		return 0, ErrInvalidShareSignature
+	}
+	return number, nil
+}
+
+// Verify checks the signature and expiry of an itinerary share link
+func (s *ShareLinkService) Verify(itineraryID, expires, signature string) error {
+	return s.verify(expires, signature, "itinerary", itineraryID, expires)
+}
+
+// VerifyFlight checks the signature and expiry of a flight share link
+func (s *ShareLinkService) VerifyFlight(itineraryID, flightNumber, bookingReference, expires, signature string) error {
+	return s.verify(expires, signature, "flight", itineraryID, flightNumber, bookingReference, expires)
+}
+
+// verify compares the signature before looking at the expiry, so an expired
+// link is only reported as such when it was issued by this service
+func (s *ShareLinkService) verify(expires, signature string, parts ...string) error {
+	if !hmac.Equal([]byte(s.sign(parts...)), []byte(signature)) {
+		return ErrInvalidShareSignature
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidShareSignature
+	}
+	if !s.now().Before(time.Unix(unix, 0)) {
+		return ErrShareLinkExpired
+	}
+	return nil
+}
+
+// expiry returns the expiry of a link issued now as unix seconds
+func (s *ShareLinkService) expiry() string {
+	return strconv.FormatInt(s.now().Add(s.ttl).Unix(), 10)
+}
+
+// sign computes the HMAC signature of the parts of a link, the first part
+// being its kind
+func (s *ShareLinkService) sign(parts ...string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
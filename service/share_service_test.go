@@ -0,0 +1,161 @@
+package service
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// issued is the time the test links are created at
+var issued = time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// testShareLinks returns a share link service whose clock reads at
+func testShareLinks(t *testing.T, at time.Time) *ShareLinkService {
+	t.Helper()
+	links, err := NewShareLinkService("https://trips.example.com/", "s3cret", 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links.now = func() time.Time { return at }
+	return links
+}
+
+// linkQuery returns the query of a share URL
+func linkQuery(t *testing.T, link string) url.Values {
+	t.Helper()
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Query()
+}
+
+func TestShareLinkVerify(t *testing.T) {
+	query := linkQuery(t, testShareLinks(t, issued).ItineraryURL("it-1"))
+	exp, sig := query.Get("exp"), query.Get("sig")
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		id      string
+		exp     string
+		sig     string
+		wantErr error
+	}{
+		{"valid", issued.Add(time.Hour), "it-1", exp, sig, nil},
+		{"other itinerary", issued, "it-2", exp, sig, ErrInvalidShareSignature},
+		{"extended expiry", issued, "it-1", "99999999999", sig, ErrInvalidShareSignature},
+		{"missing signature", issued, "it-1", exp, "", ErrInvalidShareSignature},
+		{"expired", issued.Add(24 * time.Hour), "it-1", exp, sig, ErrShareLinkExpired},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := testShareLinks(t, test.at).Verify(test.id, test.exp, test.sig)
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("got error %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestShareLinkVerifyFlight(t *testing.T) {
+	links := testShareLinks(t, issued)
+	withRef := linkQuery(t, links.FlightURL("it-1", "AF 218", "XK9P2L"))
+	withoutRef := linkQuery(t, links.FlightURL("it-1", "AF 218", ""))
+
+	tests := []struct {
+		name    string
+		flight  string
+		ref     string
+		query   url.Values
+		wantErr error
+	}{
+		{"with booking reference", "AF 218", "XK9P2L", withRef, nil},
+		{"without booking reference", "AF 218", "", withoutRef, nil},
+		{"other booking reference", "AF 218", "AAAAAA", withRef, ErrInvalidShareSignature},
+		{"booking reference dropped", "AF 218", "", withRef, ErrInvalidShareSignature},
+		{"booking reference added", "AF 218", "XK9P2L", withoutRef, ErrInvalidShareSignature},
+		{"other flight", "AF 219", "", withoutRef, ErrInvalidShareSignature},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := links.VerifyFlight("it-1", test.flight, test.ref, test.query.Get("exp"), test.query.Get("sig"))
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("got error %v, want %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestShareLinkKindsAreSignedSeparately(t *testing.T) {
+	links := testShareLinks(t, issued)
+
+	flight := linkQuery(t, links.FlightURL("it-1", "AF 218", ""))
+	if err := links.Verify("it-1", flight.Get("exp"), flight.Get("sig")); !errors.Is(err, ErrInvalidShareSignature) {
+		t.Errorf("flight signature opened the itinerary: %v", err)
+	}
+
+	itinerary := linkQuery(t, links.ItineraryURL("it-1"))
+	if err := links.VerifyFlight("it-1", "AF 218", "", itinerary.Get("exp"), itinerary.Get("sig")); !errors.Is(err, ErrInvalidShareSignature) {
+		t.Errorf("itinerary signature opened a flight: %v", err)
+	}
+	if _, err := links.VerifyQuote("it-1", "1", itinerary.Get("exp"), itinerary.Get("sig")); !errors.Is(err, ErrInvalidShareSignature) {
+		t.Errorf("itinerary signature answered a quote: %v", err)
+	}
+}
+
+func TestShareLinkVerifyQuote(t *testing.T) {
+	links := testShareLinks(t, issued)
+	links.SetQuoteLinkTTL(48 * time.Hour)
+	link, expiresAt := links.QuoteURL("it-1", 2)
+	if !strings.HasPrefix(link, "https://trips.example.com/api/v1/share/it-1/quote?") || !expiresAt.Equal(issued.Add(48*time.Hour)) {
+		t.Errorf("got %s expiring at %s", link, expiresAt)
+	}
+	query := linkQuery(t, link)
+	if query.Has("sig") {
+		t.Errorf("got a share signature in %s", link)
+	}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		id       string
+		revision string
+		wantErr  error
+	}{
+		{"valid", issued.Add(47 * time.Hour), "it-1", "2", nil},
+		{"other revision", issued, "it-1", "3", ErrInvalidShareSignature},
+		{"other itinerary", issued, "it-2", "2", ErrInvalidShareSignature},
+		{"expired", issued.Add(48 * time.Hour), "it-1", "2", ErrShareLinkExpired},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checker := testShareLinks(t, test.at)
+			revision, err := checker.VerifyQuote(test.id, test.revision, query.Get("exp"), query.Get("token"))
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("got error %v, want %v", err, test.wantErr)
+			}
+			if err == nil && revision != 2 {
+				t.Errorf("got revision %d, want 2", revision)
+			}
+		})
+	}
+}
+
+func TestFlightURL(t *testing.T) {
+	link := testShareLinks(t, issued).FlightURL("it-1", "AF 218", "")
+	if !strings.HasPrefix(link, "https://trips.example.com/api/v1/share/it-1/flights/AF%20218?") {
+		t.Errorf("got %s", link)
+	}
+	if linkQuery(t, link).Has("ref") {
+		t.Errorf("got a booking reference in %s", link)
+	}
+	if want := "1740916800"; linkQuery(t, link).Get("exp") != want {
+		t.Errorf("got expiry %s, want %s", linkQuery(t, link).Get("exp"), want)
+	}
+}
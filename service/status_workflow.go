@@ -0,0 +1,201 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidStatus     = errors.New("invalid itinerary status")
+	ErrInvalidTransition = errors.New("status transition not allowed")
+	ErrItineraryLocked   = errors.New("itinerary is locked")
+	ErrStatusNotEditable = errors.New("status can't be changed by an update")
+)
+
+// statusTransitions lists the statuses an itinerary may move to from each
+// status. Completed and cancelled itineraries are final.
+var statusTransitions = map[string][]string{
+	models.StatusDraft:      {models.StatusQuoted, models.StatusCancelled},
+	models.StatusQuoted:     {models.StatusDraft, models.StatusConfirmed, models.StatusExpired, models.StatusCancelled},
+	models.StatusExpired:    {models.StatusDraft, models.StatusCancelled},
+	models.StatusConfirmed:  {models.StatusInProgress, models.StatusCancelled},
+	models.StatusInProgress: {models.StatusCompleted, models.StatusCancelled},
+	models.StatusCompleted:  {},
+	models.StatusCancelled:  {},
+}
+
+// AllowedTransitions returns the statuses an itinerary in the given status
+// may move to
+func AllowedTransitions(status string) []string {
+	return append([]string{}, statusTransitions[status]...)
+}
+
+// TransitionStatus moves an itinerary to a new status and records who did it
+// and when. Quoting and confirming require an itinerary without validation
+// errors. Confirming stamps a confirmation number and issue date the first time.
+func (s *ItineraryService) TransitionStatus(id string, req *models.StatusTransitionReq) (*models.Itinerary, error) {
+	stored, err := s.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := statusTransitions[req.Status]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidStatus, req.Status)
+	}
+	if !canTransition(stored.Status, req.Status) {
+		allowed := AllowedTransitions(stored.Status)
+		if len(allowed) == 0 {
+			return nil, fmt.Errorf("%w: %s is final", ErrInvalidTransition, stored.Status)
+		}
+		return nil, fmt.Errorf("%w: %s can move to %s, not %s", ErrInvalidTransition, stored.Status,
+			strings.Join(allowed, ", "), req.Status)
+	}
+
+	if req.Status == models.StatusQuoted || req.Status == models.StatusConfirmed {
+		if err := s.validator.Check(stored); err != nil {
+			return nil, err
+		}
+	}
+
+	updated := *stored
+	itinerary := &updated
+	now := time.Now()
+
+	itinerary.StatusHistory = append(append([]models.StatusChange{}, stored.StatusHistory...), models.StatusChange{
+		From:      stored.Status,
+		To:        req.Status,
+		ChangedBy: req.ChangedBy,
+		ChangedAt: now,
+		Reason:    req.Reason,
+	})
+	itinerary.Status = req.Status
+	if req.Status == models.StatusConfirmed {
+		if itinerary.ConfirmationNumber == "" {
+			itinerary.ConfirmationNumber = newConfirmationNumber()
+		}
+		if itinerary.ConfirmedAt == nil {
+			itinerary.ConfirmedAt = &now
+		}
+	}
+	itinerary.UpdatedAt = now
+
+	if err := s.repo.Update(id, itinerary); err != nil {
+		return nil, fmt.Errorf("failed to update itinerary: %w", err)
+	}
+	return itinerary, nil
+}
+
+// canTransition tells whether an itinerary may move between two statuses
+func canTransition(from, to string) bool {
+	for _, status := range statusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// isReadOnly tells whether an itinerary in the given status can't be edited
+// at all. Expired quotes are moved back to draft to be revised.
+func isReadOnly(status string) bool {
+	switch status {
+	case models.StatusCompleted, models.StatusCancelled, models.StatusExpired:
+		return true
+	}
+	return false
+}
+
+// isBooked tells whether an itinerary in the given status is a booking whose
+// dates, reservations and prices are fixed
+func isBooked(status string) bool {
+	return status == models.StatusConfirmed || status == models.StatusInProgress
+}
+
+// checkLocks rejects changes to the dates, hotels, flights, transfers and
+// price of a booked itinerary. Activities, descriptive fields, booking
+// references and installment statuses can still change.
+func checkLocks(stored, updated *models.Itinerary) error {
+	if !isBooked(stored.Status) {
+		return nil
+	}
+
+	var locked []string
+	if !stored.StartDate.Equal(updated.StartDate) || !stored.EndDate.Equal(updated.EndDate) {
+		locked = append(locked, "dates")
+	}
+	if !sameHotels(stored.Hotels, updated.Hotels) {
+		locked = append(locked, "hotels")
+	}
+	if !sameFlights(stored.Flights, updated.Flights) {
+		locked = append(locked, "flights")
+	}
+	if !sameTransfers(stored.Transfers, updated.Transfers) {
+		locked = append(locked, "transfers")
+	}
+	if !samePrice(stored.PaymentPlan, updated.PaymentPlan) {
+		locked = append(locked, "payment plan")
+	}
+
+	if len(locked) > 0 {
+		return fmt.Errorf("%w: %s can't change once an itinerary is %s", ErrItineraryLocked,
+			strings.Join(locked, ", "), stored.Status)
+	}
+	return nil
+}
+
+// sameHotels compares the stays of two hotel lists
+func sameHotels(a, b []models.Hotel) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].City != b[i].City || !a[i].CheckInDate.Equal(b[i].CheckInDate) ||
+			!a[i].CheckOutDate.Equal(b[i].CheckOutDate) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameFlights compares the flights of two lists
+func sameFlights(a, b []models.Flight) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].FlightNumber != b[i].FlightNumber || a[i].From != b[i].From || a[i].To != b[i].To ||
+			!a[i].Departure.Equal(b[i].Departure) || !a[i].Arrival.Equal(b[i].Arrival) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameTransfers compares the transfers of two lists
+func sameTransfers(a, b []models.Transfer) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].From != b[i].From || a[i].To != b[i].To || a[i].Mode != b[i].Mode || !a[i].Timing.Equal(b[i].Timing) {
+			return false
+		}
+	}
+	return true
+}
+
+// samePrice compares the amounts and due dates of two payment plans
+func samePrice(a, b models.PaymentPlan) bool {
+	if !a.AmountDue.Equal(b.AmountDue) || !a.DueDate.Equal(b.DueDate) || len(a.Installments) != len(b.Installments) {
+		return false
+	}
+	for i := range a.Installments {
+		if !a.Installments[i].Amount.Equal(b.Installments[i].Amount) || !a.Installments[i].DueDate.Equal(b.Installments[i].DueDate) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/repository"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// october returns midnight UTC of a date in October 2025
+func october(d int) time.Time {
+	return time.Date(2025, time.October, d, 0, 0, 0, 0, time.UTC)
+}
+
+// goaTrip returns a valid three day trip to Goa with one hotel stay, an
+// airport transfer and a single installment
+func goaTrip() *models.Itinerary {
+	due := models.NewMoney(decimal.NewFromInt(60000), "INR")
+	return &models.Itinerary{
+		ID:        "goa-1",
+		UserID:    "agent-goa",
+		Title:     "Goa long weekend",
+		StartDate: october(10),
+		EndDate:   october(12),
+		Days: []models.Day{
+			{DayNumber: 1, Date: october(10), Title: "Arrival"},
+			{DayNumber: 2, Date: october(11), Title: "Old Goa"},
+			{DayNumber: 3, Date: october(12), Title: "Departure"},
+		},
+		Hotels: []models.Hotel{
+			{Name: "Taj Fort Aguada", City: "Goa", CheckInDate: october(10), CheckOutDate: october(12), Nights: 2},
+		},
+		Transfers: []models.Transfer{
+			{From: "Goa airport", To: "Taj Fort Aguada", Timing: october(10).Add(12 * time.Hour)},
+		},
+		PaymentPlan: models.PaymentPlan{
+			AmountDue: due,
+			DueDate:   october(1),
+			Installments: []models.Installment{
+				{InstallmentNumber: 1, Amount: due, DueDate: october(1), Status: "Pending"},
+			},
+		},
+		Status: models.StatusDraft,
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{models.StatusDraft, models.StatusQuoted, true},
+		{models.StatusDraft, models.StatusCancelled, true},
+		{models.StatusDraft, models.StatusConfirmed, false},
+		{models.StatusDraft, models.StatusDraft, false},
+		{models.StatusQuoted, models.StatusDraft, true},
+		{models.StatusQuoted, models.StatusConfirmed, true},
+		{models.StatusQuoted, models.StatusExpired, true},
+		{models.StatusQuoted, models.StatusInProgress, false},
+		{models.StatusExpired, models.StatusDraft, true},
+		{models.StatusExpired, models.StatusQuoted, false},
+		{models.StatusConfirmed, models.StatusInProgress, true},
+		{models.StatusConfirmed, models.StatusDraft, false},
+		{models.StatusInProgress, models.StatusCompleted, true},
+		{models.StatusInProgress, models.StatusConfirmed, false},
+		{models.StatusCompleted, models.StatusCancelled, false},
+		{models.StatusCancelled, models.StatusDraft, false},
+		{"unknown", models.StatusDraft, false},
+		{models.StatusDraft, "unknown", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.from+" to "+test.to, func(t *testing.T) {
+			if got := canTransition(test.from, test.to); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckLocks(t *testing.T) {
+	tests := []struct {
+		name   string
+		status string
+		change func(itinerary *models.Itinerary)
+		locked bool
+	}{
+		{"draft may change anything", models.StatusDraft, func(it *models.Itinerary) {
+			it.EndDate = october(13)
+			it.PaymentPlan.AmountDue = models.NewMoney(decimal.NewFromInt(70000), "INR")
+		}, false},
+		{"quoted may change anything", models.StatusQuoted, func(it *models.Itinerary) {
+			it.Hotels = nil
+		}, false},
+		{"unchanged booking", models.StatusConfirmed, nil, false},
+		{"activities and descriptions", models.StatusConfirmed, func(it *models.Itinerary) {
+			it.Title = "New title"
+			it.Days[0].Activities.Morning = []models.Activity{{Name: "Basilica of Bom Jesus"}}
+		}, false},
+		{"booking references", models.StatusConfirmed, func(it *models.Itinerary) {
+			it.Hotels[0].BookingReference = "TAJ-4471"
+		}, false},
+		{"installment status", models.StatusInProgress, func(it *models.Itinerary) {
+			it.PaymentPlan.Installments[0].Status = "Paid"
+		}, false},
+		{"dates", models.StatusConfirmed, func(it *models.Itinerary) {
+			it.StartDate = october(11)
+		}, true},
+		{"hotel dates", models.StatusConfirmed, func(it *models.Itinerary) {
+			it.Hotels[0].CheckOutDate = october(11)
+		}, true},
+		{"flights", models.StatusInProgress, func(it *models.Itinerary) {
+			it.Flights = []models.Flight{{FlightNumber: "6E 5312"}}
+		}, true},
+		{"transfer time", models.StatusConfirmed, func(it *models.Itinerary) {
+			it.Transfers[0].Timing = it.Transfers[0].Timing.Add(time.Hour)
+		}, true},
+		{"amount due", models.StatusConfirmed, func(it *models.Itinerary) {
+			it.PaymentPlan.AmountDue = models.NewMoney(decimal.NewFromInt(70000), "INR")
+			it.PaymentPlan.Installments[0].Amount = models.NewMoney(decimal.NewFromInt(70000), "INR")
+		}, true},
+		{"installment due date", models.StatusConfirmed, func(it *models.Itinerary) {
+			it.PaymentPlan.Installments[0].DueDate = october(11)
+		}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stored := goaTrip()
+			stored.Status = test.status
+			updated := copyItinerary(stored)
+			if test.change != nil {
+				test.change(updated)
+			}
+
+			err := checkLocks(stored, updated)
+			if locked := errors.Is(err, ErrItineraryLocked); locked != test.locked {
+				t.Errorf("got %v, want locked %v", err, test.locked)
+			}
+		})
+	}
+}
+
+func TestTransitionStatus(t *testing.T) {
+	repo := repository.NewInMemoryRepo()
+	if err := repo.Create(goaTrip()); err != nil {
+		t.Fatal(err)
+	}
+	itineraries := NewItineraryService(repo, nil)
+
+	steps := []struct {
+		status  string
+		wantErr error
+	}{
+		{models.StatusConfirmed, ErrInvalidTransition},
+		{"Booked", ErrInvalidStatus},
+		{models.StatusQuoted, nil},
+		{models.StatusConfirmed, nil},
+		{models.StatusInProgress, nil},
+		{models.StatusCompleted, nil},
+		{models.StatusCancelled, ErrInvalidTransition},
+	}
+	var confirmation string
+	for _, step := range steps {
+		itinerary, err := itineraries.TransitionStatus("goa-1", &models.StatusTransitionReq{Status: step.status, ChangedBy: "agent-goa"})
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("moving to %s: got error %v, want %v", step.status, err, step.wantErr)
+		}
+		if err != nil {
+			continue
+		}
+		if itinerary.Status != step.status {
+			t.Errorf("got status %s, want %s", itinerary.Status, step.status)
+		}
+		if step.status == models.StatusConfirmed {
+			confirmation = itinerary.ConfirmationNumber
+		}
+		// the confirmation number is stamped once
+		if confirmation != "" && itinerary.ConfirmationNumber != confirmation {
+			t.Errorf("confirmation number changed from %s to %s", confirmation, itinerary.ConfirmationNumber)
+		}
+	}
+	if confirmation == "" {
+		t.Error("confirming stamped no confirmation number")
+	}
+
+	stored, _ := itineraries.GetItinerary("goa-1")
+	if got := len(stored.StatusHistory); got != 4 {
+		t.Errorf("got %d status changes, want 4", got)
+	}
+}
+
+func TestQuotingNeedsAValidItinerary(t *testing.T) {
+	repo := repository.NewInMemoryRepo()
+	invalid := goaTrip()
+	invalid.Hotels[0].Nights = 3
+	if err := repo.Create(invalid); err != nil {
+		t.Fatal(err)
+	}
+	itineraries := NewItineraryService(repo, nil)
+
+	_, err := itineraries.TransitionStatus("goa-1", &models.StatusTransitionReq{Status: models.StatusQuoted, ChangedBy: "agent-goa"})
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("got error %v, want a validation error", err)
+	}
+	if stored, _ := itineraries.GetItinerary("goa-1"); stored.Status != models.StatusDraft {
+		t.Errorf("got status %s, want %s", stored.Status, models.StatusDraft)
+	}
+}
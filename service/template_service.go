@@ -0,0 +1,281 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/repository"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrTemplateNotFound = repository.ErrTemplateNotFound
+
+// CodeDayOutOfRange flags a template hotel, flight or transfer on a day the
+// template doesn't have
+const CodeDayOutOfRange = "day_out_of_range"
+
+// TemplateSearch filters the template library. Zero values match everything.
+type TemplateSearch struct {
+	Destination string
+	Nights      int
+	MinNights   int
+	MaxNights   int
+}
+
+// TemplateService manages reusable packages and turns them into itineraries
+type TemplateService struct {
+	repo        repository.TemplateRepository
+	itineraries *ItineraryService
+}
+
+// NewTemplateService creates a new template service
+func NewTemplateService(repo repository.TemplateRepository, itineraries *ItineraryService) *TemplateService {
+	return &TemplateService{
+		repo:        repo,
+		itineraries: itineraries,
+	}
+}
+
+// CreateTemplate checks and stores a new template
+func (s *TemplateService) CreateTemplate(template *models.Template) (*models.Template, error) {
+	sort.SliceStable(template.Days, func(i, j int) bool { return template.Days[i].DayNumber < template.Days[j].DayNumber })
+	if issues := validateTemplate(template); len(issues) > 0 {
+		return nil, &ValidationError{Issues: issues}
+	}
+
+	now := time.Now()
+	template.ID = uuid.New().String()
+	template.Nights = len(template.Days) - 1
+	template.CreatedAt = now
+	template.UpdatedAt = now
+
+	if err := s.repo.Create(template); err != nil {
+		return nil, fmt.Errorf("failed to create template: %w", err)
+	}
+	return template, nil
+}
+
+// GetTemplate retrieves a template by ID
+func (s *TemplateService) GetTemplate(id string) (*models.Template, error) {
+	template, err := s.repo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, repository.ErrTemplateNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	return template, nil
+}
+
+// SearchTemplates returns the templates matching the search, sorted by
+// length and title. The destination matches any part of the template's
+// destination or title, ignoring case.
+func (s *TemplateService) SearchTemplates(search TemplateSearch) ([]*models.Template, error) {
+	templates, err := s.repo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get templates: %w", err)
+	}
+
+	destination := strings.ToLower(strings.TrimSpace(search.Destination))
+	found := []*models.Template{}
+	for _, template := range templates {
+		if destination != "" && !strings.Contains(strings.ToLower(template.Destination), destination) &&
+			!strings.Contains(strings.ToLower(template.Title), destination) {
+			continue
+		}
+		if search.Nights > 0 && template.Nights != search.Nights {
+			continue
+		}
+		if search.MinNights > 0 && template.Nights < search.MinNights {
+			continue
+		}
+		if search.MaxNights > 0 && template.Nights > search.MaxNights {
+			continue
+		}
+		found = append(found, template)
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Nights != found[j].Nights {
+			return found[i].Nights < found[j].Nights
+		}
+		return found[i].Title < found[j].Title
+	})
+	return found, nil
+}
+
+// DeleteTemplate deletes a template
+func (s *TemplateService) DeleteTemplate(id string) error {
+	if err := s.repo.Delete(id); err != nil {
+		if errors.Is(err, repository.ErrTemplateNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete template: %w", err)
+	}
+	return nil
+}
+
+// Instantiate builds a full itinerary from a template for a customer and a
+// start date. It is created like any other itinerary, so it is validated.
+func (s *TemplateService) Instantiate(id string, req *models.InstantiateTemplateReq) (*models.Itinerary, error) {
+	template, err := s.GetTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.itineraries.CreateItinerary(instantiateTemplate(template, req))
+}
+
+// instantiateTemplate places the days of a template from the start date and
+// turns day numbers and local times into dates and instants
+func instantiateTemplate(template *models.Template, req *models.InstantiateTemplateReq) *models.CreateItineraryReq {
+	start := dateOf(req.StartDate)
+	dayDate := func(dayNumber int) time.Time {
+		return start.AddDate(0, 0, dayNumber-1)
+	}
+	// localInstant is a local time of a template day in the given zone
+	localInstant := func(dayNumber int, at models.TimeOfDay, zone string) time.Time {
+		return atLocalTime(dayDate(dayNumber), at, zone)
+	}
+
+	create := &models.CreateItineraryReq{
+		UserID:       req.UserID,
+		CustomerName: req.CustomerName,
+		Title:        template.Title,
+		Destination:  template.Destination,
+		StartDate:    start,
+		EndDate:      dayDate(len(template.Days)),
+		PaymentPlan:  req.PaymentPlan,
+		Inclusions:   append([]string{}, template.Inclusions...),
+		Exclusions:   append([]string{}, template.Exclusions...),
+	}
+
+	dayZones := make(map[int]string, len(template.Days))
+	for _, day := range template.Days {
+		dayZones[day.DayNumber] = day.TimeZone
+		create.Days = append(create.Days, models.Day{
+			DayNumber:  day.DayNumber,
+			Date:       dayDate(day.DayNumber),
+			Title:      day.Title,
+			TimeZone:   day.TimeZone,
+			Activities: copyActivities(day.Activities),
+		})
+	}
+
+	for _, hotel := range template.Hotels {
+		create.Hotels = append(create.Hotels, models.Hotel{
+			Name:         hotel.Name,
+			City:         hotel.City,
+			Address:      hotel.Address,
+			CheckInDate:  dayDate(hotel.CheckInDay),
+			CheckOutDate: dayDate(hotel.CheckOutDay),
+			Nights:       hotel.CheckOutDay - hotel.CheckInDay,
+			TimeZone:     hotel.TimeZone,
+			Coordinates:  copyCoordinates(hotel.Coordinates),
+		})
+	}
+
+	// time zones are needed to place the local times, missing ones come from the airports
+	flights := &models.Itinerary{}
+	for _, flight := range template.Flights {
+		flights.Flights = append(flights.Flights, models.Flight{
+			FlightNumber:      flight.FlightNumber,
+			Airline:           flight.Airline,
+			From:              flight.From,
+			To:                flight.To,
+			DepartureTimeZone: flight.DepartureTimeZone,
+			ArrivalTimeZone:   flight.ArrivalTimeZone,
+		})
+	}
+	applyAirportTimeZones(flights)
+	for i, flight := range template.Flights {
+		f := flights.Flights[i]
+		f.Departure = localInstant(flight.DepartureDay, flight.DepartureTime, f.DepartureTimeZone)
+		f.Arrival = localInstant(flight.ArrivalDay, flight.ArrivalTime, f.ArrivalTimeZone)
+		create.Flights = append(create.Flights, f)
+	}
+
+	for _, transfer := range template.Transfers {
+		create.Transfers = append(create.Transfers, models.Transfer{
+			From:         transfer.From,
+			To:           transfer.To,
+			Mode:         transfer.Mode,
+			Timing:       localInstant(transfer.Day, transfer.Time, dayZones[transfer.Day]),
+			FlightNumber: transfer.FlightNumber,
+		})
+	}
+
+	return create
+}
+
+// validateTemplate checks that the days of a template are numbered 1 to n
+// and that every stay, flight and transfer falls on one of them
+func validateTemplate(template *models.Template) []ValidationIssue {
+	var issues []ValidationIssue
+	for i, day := range template.Days {
+		if day.DayNumber != i+1 {
+			issues = append(issues, issue(fmt.Sprintf("days[%d].day_number", i), CodeDayNumberOutOfSequence,
+				"expected day number %d, got %d", i+1, day.DayNumber))
+		}
+		if _, err := loadZone(day.TimeZone); err != nil {
+			issues = append(issues, issue(fmt.Sprintf("days[%d].time_zone", i), CodeInvalidTimeZone, "unknown time zone %q", day.TimeZone))
+		}
+	}
+
+	last := len(template.Days)
+	checkDay := func(path string, dayNumber int) {
+		if dayNumber < 1 || dayNumber > last {
+			issues = append(issues, issue(path, CodeDayOutOfRange, "day %d is not part of the %d day template", dayNumber, last))
+		}
+	}
+	for i, hotel := range template.Hotels {
+		path := fmt.Sprintf("hotels[%d]", i)
+		checkDay(path+".check_in_day", hotel.CheckInDay)
+		checkDay(path+".check_out_day", hotel.CheckOutDay)
+		if hotel.CheckOutDay <= hotel.CheckInDay {
+			issues = append(issues, issue(path+".check_out_day", CodeInvalidHotelDates,
+				"%s: check-out day must be after check-in day", hotel.Name))
+		}
+	}
+	for i, flight := range template.Flights {
+		path := fmt.Sprintf("flights[%d]", i)
+		checkDay(path+".departure_day", flight.DepartureDay)
+		checkDay(path+".arrival_day", flight.ArrivalDay)
+	}
+	for i, transfer := range template.Transfers {
+		checkDay(fmt.Sprintf("transfers[%d].day", i), transfer.Day)
+	}
+
+	return issues
+}
+
+// copyActivities returns a deep copy of a day's activities
+func copyActivities(activities models.Activities) models.Activities {
+	copySlot := func(slot []models.Activity) []models.Activity {
+		copied := make([]models.Activity, len(slot))
+		for i, activity := range slot {
+			activity.Coordinates = copyCoordinates(activity.Coordinates)
+			activity.Price = copyPrice(activity.Price)
+			copied[i] = activity
+		}
+		return copied
+	}
+	return models.Activities{
+		Morning:   copySlot(activities.Morning),
+		Afternoon: copySlot(activities.Afternoon),
+		Evening:   copySlot(activities.Evening),
+	}
+}
+
+// copyCoordinates returns a copy of optional coordinates
+func copyCoordinates(coordinates *models.Coordinates) *models.Coordinates {
+	if coordinates == nil {
+		return nil
+	}
+	copied := *coordinates
+	return &copied
+}
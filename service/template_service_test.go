@@ -0,0 +1,237 @@
+package service
+
+import (
+	"errors"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/repository"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// kyotoTemplate returns a three day package: a flight from Delhi landing in
+// Osaka on day 1, a train to Kyoto and two nights there
+func kyotoTemplate() *models.Template {
+	return &models.Template{
+		Title:       "Kyoto in autumn",
+		Destination: "Kyoto, Japan",
+		Days: []models.TemplateDay{
+			{DayNumber: 1, Title: "Arrival", TimeZone: "Asia/Tokyo"},
+			{DayNumber: 2, Title: "Temples", TimeZone: "Asia/Tokyo", Activities: models.Activities{
+				Morning: []models.Activity{{Name: "Fushimi Inari", Location: "Fushimi Inari Taisha, Kyoto"}},
+			}},
+			{DayNumber: 3, Title: "Departure", TimeZone: "Asia/Tokyo"},
+		},
+		Hotels: []models.TemplateHotel{
+			{Name: "Hotel Granvia", City: "Kyoto", Address: "Karasuma-dori", CheckInDay: 1, CheckOutDay: 3},
+		},
+		Flights: []models.TemplateFlight{
+			{FlightNumber: "JL 750", Airline: "JL", From: "DEL", To: "KIX", DepartureDay: 1, DepartureTime: 0,
+				ArrivalDay: 1, ArrivalTime: 12 * 60},
+		},
+		Transfers: []models.TemplateTransfer{
+			{From: "Kansai International Airport (KIX)", To: "Hotel Granvia", Mode: "Haruka express", Day: 1,
+				Time: 13 * 60, FlightNumber: "JL 750"},
+		},
+	}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(template *models.Template)
+		want   []string
+	}{
+		{"valid", nil, nil},
+		{"day numbers with a gap", func(tpl *models.Template) {
+			tpl.Days[2].DayNumber = 4
+		}, []string{CodeDayNumberOutOfSequence}},
+		{"unknown time zone", func(tpl *models.Template) {
+			tpl.Days[0].TimeZone = "Asia/Kyoto"
+		}, []string{CodeInvalidTimeZone}},
+		{"check-out after the last day", func(tpl *models.Template) {
+			tpl.Hotels[0].CheckOutDay = 4
+		}, []string{CodeDayOutOfRange}},
+		{"check-out before check-in", func(tpl *models.Template) {
+			tpl.Hotels[0].CheckInDay, tpl.Hotels[0].CheckOutDay = 2, 2
+		}, []string{CodeInvalidHotelDates}},
+		{"flight and transfer on days the template doesn't have", func(tpl *models.Template) {
+			tpl.Flights[0].ArrivalDay = 0
+			tpl.Transfers[0].Day = 5
+		}, []string{CodeDayOutOfRange, CodeDayOutOfRange}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			template := kyotoTemplate()
+			if test.change != nil {
+				test.change(template)
+			}
+			var got []string
+			for _, found := range validateTemplate(template) {
+				got = append(got, found.Code)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSearchTemplates(t *testing.T) {
+	templates := NewTemplateService(repository.NewInMemoryTemplateRepo(), nil)
+	for _, template := range []*models.Template{
+		kyotoTemplate(),
+		{Title: "Tokyo weekend", Destination: "Tokyo", Days: []models.TemplateDay{{DayNumber: 1, Title: "Shibuya"}, {DayNumber: 2, Title: "Asakusa"}}},
+		{Title: "Golden triangle", Destination: "Rajasthan", Days: []models.TemplateDay{{DayNumber: 1, Title: "Delhi"}}},
+	} {
+		if _, err := templates.CreateTemplate(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		search TemplateSearch
+		want   []string
+	}{
+		{"everything, shortest first", TemplateSearch{}, []string{"Golden triangle", "Tokyo weekend", "Kyoto in autumn"}},
+		{"destination ignoring case", TemplateSearch{Destination: "japan"}, []string{"Kyoto in autumn"}},
+		{"destination in the title", TemplateSearch{Destination: "triangle"}, []string{"Golden triangle"}},
+		{"exact nights", TemplateSearch{Nights: 1}, []string{"Tokyo weekend"}},
+		{"range of nights", TemplateSearch{MinNights: 1, MaxNights: 2}, []string{"Tokyo weekend", "Kyoto in autumn"}},
+		{"nothing matches", TemplateSearch{Destination: "Lisbon"}, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			found, err := templates.SearchTemplates(test.search)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, template := range found {
+				got = append(got, template.Title)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInstantiateTemplate(t *testing.T) {
+	itineraries := NewItineraryService(repository.NewInMemoryRepo(), nil)
+	templates := NewTemplateService(repository.NewInMemoryTemplateRepo(), itineraries)
+
+	template, err := templates.CreateTemplate(kyotoTemplate())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if template.Nights != 2 {
+		t.Errorf("got %d nights, want 2", template.Nights)
+	}
+
+	yen := models.NewMoney(decimal.NewFromInt(240000), "JPY")
+	start := time.Date(2025, time.November, 14, 0, 0, 0, 0, time.UTC)
+	itinerary, err := templates.Instantiate(template.ID, &models.InstantiateTemplateReq{
+		UserID:       "agent-kyoto",
+		CustomerName: "Meera Nair",
+		StartDate:    start,
+		PaymentPlan: models.PaymentPlan{
+			AmountDue:    yen,
+			DueDate:      start,
+			Installments: []models.Installment{{InstallmentNumber: 1, Amount: yen, DueDate: start}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := itinerary.EndDate.Format("2006-01-02"); got != "2025-11-16" {
+		t.Errorf("got end date %s, want 2025-11-16", got)
+	}
+	if hotel := itinerary.Hotels[0]; hotel.Nights != 2 || hotel.CheckOutDate.Format("2006-01-02") != "2025-11-16" {
+		t.Errorf("got a stay of %d nights until %s", hotel.Nights, hotel.CheckOutDate)
+	}
+	// the local times are placed in the zones of the airports and of the day
+	flight := itinerary.Flights[0]
+	if got := flight.Departure.Format(time.RFC3339); got != "2025-11-14T00:00:00+05:30" {
+		t.Errorf("got departure %s", got)
+	}
+	if got := flight.Arrival.Format(time.RFC3339); got != "2025-11-14T12:00:00+09:00" {
+		t.Errorf("got arrival %s", got)
+	}
+	if got := itinerary.Transfers[0].Timing.Format(time.RFC3339); got != "2025-11-14T13:00:00+09:00" {
+		t.Errorf("got transfer at %s", got)
+	}
+
+	// the itinerary doesn't share the template's activities
+	itinerary.Days[1].Activities.Morning[0].Name = "Kinkaku-ji"
+	if stored, _ := templates.GetTemplate(template.ID); stored.Days[1].Activities.Morning[0].Name != "Fushimi Inari" {
+		t.Error("changing the itinerary changed the template")
+	}
+}
+
+func TestTemplateNotFound(t *testing.T) {
+	templates := NewTemplateService(repository.NewInMemoryTemplateRepo(), nil)
+	if _, err := templates.GetTemplate("missing"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("got error %v, want %v", err, ErrTemplateNotFound)
+	}
+	if err := templates.DeleteTemplate("missing"); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("got error %v, want %v", err, ErrTemplateNotFound)
+	}
+}
+
+func TestCloneItinerary(t *testing.T) {
+	itineraries := NewItineraryService(repository.NewInMemoryRepo(), nil)
+	templates := NewTemplateService(repository.NewInMemoryTemplateRepo(), itineraries)
+	template, err := templates.CreateTemplate(kyotoTemplate())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yen := models.NewMoney(decimal.NewFromInt(240000), "JPY")
+	start := time.Date(2025, time.November, 14, 0, 0, 0, 0, time.UTC)
+	source, err := templates.Instantiate(template.ID, &models.InstantiateTemplateReq{
+		UserID:    "agent-kyoto",
+		StartDate: start,
+		PaymentPlan: models.PaymentPlan{
+			AmountDue:    yen,
+			DueDate:      start,
+			Installments: []models.Installment{{InstallmentNumber: 1, Amount: yen, DueDate: start, Status: "Paid"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hotels := append([]models.Hotel{}, source.Hotels...)
+	hotels[0].BookingReference = "GRANVIA-88"
+	if source, err = itineraries.UpdateItinerary(source.ID, &models.UpdateItineraryReq{Hotels: hotels}); err != nil {
+		t.Fatal(err)
+	}
+
+	next := time.Date(2026, time.April, 3, 0, 0, 0, 0, time.UTC)
+	clone, err := itineraries.CloneItinerary(source.ID, &models.CloneItineraryReq{UserID: "agent-spring", StartDate: &next})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if clone.ID == source.ID || clone.UserID != "agent-spring" || clone.Status != models.StatusDraft {
+		t.Errorf("got clone %s of %s for %s in %s", clone.ID, source.ID, clone.UserID, clone.Status)
+	}
+	if !clone.StartDate.Equal(next) || !clone.Hotels[0].CheckInDate.Equal(next) {
+		t.Errorf("got a clone starting %s with check-in %s", clone.StartDate, clone.Hotels[0].CheckInDate)
+	}
+	if clone.Hotels[0].BookingReference != "" {
+		t.Errorf("copied the booking reference %s", clone.Hotels[0].BookingReference)
+	}
+	if status := clone.PaymentPlan.Installments[0].Status; status != "Pending" {
+		t.Errorf("got installment %s, want Pending", status)
+	}
+	if stored, _ := itineraries.GetItinerary(source.ID); stored.Hotels[0].BookingReference != "GRANVIA-88" {
+		t.Error("cloning changed the source")
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/reference"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CodeInvalidTimeZone flags a time zone that is not a known IANA name
+const CodeInvalidTimeZone = "invalid_time_zone"
+
+// zoneCache avoids reloading the tz database for every lookup
+var zoneCache sync.Map
+
+// loadZone resolves an IANA time zone name, an empty name is UTC
+func loadZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := zoneCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	zoneCache.Store(name, loc)
+	return loc, nil
+}
+
+// inZone converts an instant to the wall clock of the given zone. Unknown
+// zones fall back to UTC so display code never fails.
+func inZone(t time.Time, zone string) time.Time {
+	loc, err := loadZone(zone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return t.In(loc)
+}
+
+// formatLocal formats an instant in the given zone followed by the zone
+// abbreviation, e.g. "June 15, 2025 at 11:00 PM CEST"
+func formatLocal(t time.Time, zone, layout string) string {
+	return inZone(t, zone).Format(layout + " MST")
+}
+
+// formatDuration formats a duration as hours and minutes, e.g. "13h 05m"
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + formatDuration(-d)
+	}
+	d = d.Round(time.Minute)
+	return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// FlightDuration returns the time in the air, computed between instants so it
+// is correct across time zones
+func FlightDuration(flight models.Flight) time.Duration {
+	return flight.Arrival.Sub(flight.Departure)
+}
+
+// localDayBounds returns the instants at which a calendar day starts and ends
+// in the given zone
+func localDayBounds(date time.Time, zone string) (time.Time, time.Time) {
+	loc, err := loadZone(zone)
+	if err != nil {
+		loc = time.UTC
+	}
+	y, m, d := date.Date()
+	start := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	return start, start.AddDate(0, 0, 1)
+}
+
+// atLocalTime returns the instant of a local time of day on a calendar date
+// in the given zone
+func atLocalTime(date time.Time, at models.TimeOfDay, zone string) time.Time {
+	loc, err := loadZone(zone)
+	if err != nil {
+		loc = time.UTC
+	}
+	y, m, d := date.Date()
+	return time.Date(y, m, d, 0, int(at), 0, 0, loc)
+}
+
+// zoneForInstant finds the time zone of the trip day an instant falls on.
+// Days are calendar dates interpreted in their own zone, so a 01:00 arrival
+// in Tokyo belongs to the Tokyo day even though it is the previous day in UTC.
+func zoneForInstant(itinerary *models.Itinerary, t time.Time) string {
+	for _, day := range itinerary.Days {
+		start, end := localDayBounds(day.Date, day.TimeZone)
+		if !t.Before(start) && t.Before(end) {
+			return day.TimeZone
+		}
+	}
+	return ""
+}
+
+// localDateOf returns the calendar date of an instant in the given zone
+func localDateOf(t time.Time, zone string) time.Time {
+	return dateOf(inZone(t, zone))
+}
+
+// transferZone finds the time zone a transfer happens in. A transfer meeting
+// a flight takes the zone of its departure airport before take-off and of its
+// arrival airport after, others the zone of the trip day they fall on or of
+// the hotel booked that day. An empty zone is UTC.
+func transferZone(itinerary *models.Itinerary, transfer models.Transfer) string {
+	if transfer.FlightNumber != "" {
+		for _, flight := range itinerary.Flights {
+			if flight.FlightNumber != transfer.FlightNumber {
+				continue
+			}
+			zone := flight.ArrivalTimeZone
+			if transfer.Timing.Before(flight.Departure) {
+				zone = flight.DepartureTimeZone
+			}
+			if zone != "" {
+				return zone
+			}
+		}
+	}
+
+	if zone := zoneForInstant(itinerary, transfer.Timing); zone != "" {
+		return zone
+	}
+
+	date := dateOf(transfer.Timing.UTC())
+	for _, hotel := range itinerary.Hotels {
+		if hotel.TimeZone != "" && !date.Before(dateOf(hotel.CheckInDate)) && !date.After(dateOf(hotel.CheckOutDate)) {
+			return hotel.TimeZone
+		}
+	}
+	return ""
+}
+
+// normalizeInstants keeps flight and transfer times as the same instants but
+// in the local time of where they happen, so they are written out with the
+// local offset, e.g. 2025-06-15T23:00:00+02:00. Transfers take the zone given
+// by transferZone.
+func normalizeInstants(itinerary *models.Itinerary) {
+	for i := range itinerary.Flights {
+		flight := &itinerary.Flights[i]
+		flight.Departure = inZone(flight.Departure, flight.DepartureTimeZone)
+		flight.Arrival = inZone(flight.Arrival, flight.ArrivalTimeZone)
+	}
+	for i := range itinerary.Transfers {
+		transfer := &itinerary.Transfers[i]
+		transfer.Timing = inZone(transfer.Timing, transferZone(itinerary, *transfer))
+	}
+}
+
+// applyHotelTimeZones gives hotels without a time zone the one of the trip
+// day they check in on, or else the one of their city's airports
+func applyHotelTimeZones(itinerary *models.Itinerary) {
+	catalog := reference.Default()
+	for i := range itinerary.Hotels {
+		hotel := &itinerary.Hotels[i]
+		if hotel.TimeZone != "" {
+			continue
+		}
+		if day := dayIndexForDate(itinerary.Days, dateOf(hotel.CheckInDate)); day >= 0 {
+			hotel.TimeZone = itinerary.Days[day].TimeZone
+		}
+		if hotel.TimeZone == "" {
+			hotel.TimeZone, _ = catalog.CityTimeZone(hotel.City)
+		}
+	}
+}
+
+// formatLocalDate formats a calendar date followed by the time zone it is
+// local to, e.g. "June 15, 2025 (Asia/Tokyo)"
+func formatLocalDate(date time.Time, zone, layout string) string {
+	if zone == "" {
+		return date.Format(layout)
+	}
+	return fmt.Sprintf("%s (%s)", date.Format(layout), zone)
+}
+
+// validateTimeZones checks that every time zone is a known IANA name
+func validateTimeZones(itinerary *models.Itinerary) []ValidationIssue {
+	var issues []ValidationIssue
+	check := func(path, zone string) {
+		if _, err := loadZone(zone); err != nil {
+			issues = append(issues, issue(path, CodeInvalidTimeZone, "unknown time zone %q", zone))
+		}
+	}
+
+	for i, day := range itinerary.Days {
+		check(fmt.Sprintf("days[%d].time_zone", i), day.TimeZone)
+	}
+	for i, hotel := range itinerary.Hotels {
+		check(fmt.Sprintf("hotels[%d].time_zone", i), hotel.TimeZone)
+	}
+	for i, flight := range itinerary.Flights {
+		check(fmt.Sprintf("flights[%d].departure_time_zone", i), flight.DepartureTimeZone)
+		check(fmt.Sprintf("flights[%d].arrival_time_zone", i), flight.ArrivalTimeZone)
+	}
+
+	return issues
+}
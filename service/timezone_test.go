@@ -0,0 +1,158 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"testing"
+	"time"
+)
+
+// mustZone loads an IANA time zone for a test
+func mustZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+// tokyoTrip returns two days in Tokyo followed by a day in Paris, reached by
+// an overnight flight
+func tokyoTrip() *models.Itinerary {
+	date := func(d int) time.Time { return time.Date(2025, time.April, d, 0, 0, 0, 0, time.UTC) }
+	return &models.Itinerary{
+		StartDate: date(7),
+		EndDate:   date(9),
+		Days: []models.Day{
+			{DayNumber: 1, Date: date(7), TimeZone: "Asia/Tokyo"},
+			{DayNumber: 2, Date: date(8), TimeZone: "Asia/Tokyo"},
+			{DayNumber: 3, Date: date(9), TimeZone: "Europe/Paris"},
+		},
+		Flights: []models.Flight{{
+			FlightNumber: "AF 275",
+			From:         "HND",
+			To:           "CDG",
+			// 22:00 in Tokyo on the 8th, 04:30 in Paris on the 9th
+			Departure: time.Date(2025, time.April, 8, 13, 0, 0, 0, time.UTC),
+			Arrival:   time.Date(2025, time.April, 9, 2, 30, 0, 0, time.UTC),
+		}},
+	}
+}
+
+func TestZoneForInstant(t *testing.T) {
+	tests := []struct {
+		name    string
+		instant time.Time
+		want    string
+	}{
+		// 01:00 in Tokyo on the 8th is still the 7th in UTC
+		{"early morning ahead of UTC", time.Date(2025, time.April, 7, 16, 0, 0, 0, time.UTC), "Asia/Tokyo"},
+		{"late evening in Tokyo", time.Date(2025, time.April, 8, 14, 59, 0, 0, time.UTC), "Asia/Tokyo"},
+		// midnight in Tokyo on the 9th falls on no Tokyo day and before the Paris day starts
+		{"between the Tokyo and Paris days", time.Date(2025, time.April, 8, 15, 0, 0, 0, time.UTC), ""},
+		{"Paris day", time.Date(2025, time.April, 9, 12, 0, 0, 0, time.UTC), "Europe/Paris"},
+		{"after the trip", time.Date(2025, time.April, 10, 12, 0, 0, 0, time.UTC), ""},
+	}
+
+	itinerary := tokyoTrip()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := zoneForInstant(itinerary, test.instant); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTransferZone(t *testing.T) {
+	tests := []struct {
+		name     string
+		change   func(itinerary *models.Itinerary)
+		transfer models.Transfer
+		want     string
+	}{
+		{"airport drop-off before take-off", nil,
+			models.Transfer{FlightNumber: "AF 275", Timing: time.Date(2025, time.April, 8, 10, 0, 0, 0, time.UTC)}, "Asia/Tokyo"},
+		{"airport pick-up after landing", func(it *models.Itinerary) {
+			it.Days[2].TimeZone = ""
+		}, models.Transfer{FlightNumber: "AF 275", Timing: time.Date(2025, time.April, 9, 3, 30, 0, 0, time.UTC)}, "Europe/Paris"},
+		{"flight without zones falls back to the day", func(it *models.Itinerary) {
+			it.Flights[0].From, it.Flights[0].To = "", ""
+		}, models.Transfer{FlightNumber: "AF 275", Timing: time.Date(2025, time.April, 8, 10, 0, 0, 0, time.UTC)}, "Asia/Tokyo"},
+		{"unlinked transfer takes the day", nil,
+			models.Transfer{Timing: time.Date(2025, time.April, 7, 3, 0, 0, 0, time.UTC)}, "Asia/Tokyo"},
+		{"day without zone takes the hotel", func(it *models.Itinerary) {
+			it.Days[2].TimeZone = ""
+			it.Hotels = []models.Hotel{{Name: "Hotel du Louvre", City: "Paris", CheckInDate: it.Days[2].Date, CheckOutDate: it.EndDate, Nights: 1}}
+		}, models.Transfer{Timing: time.Date(2025, time.April, 9, 18, 0, 0, 0, time.UTC)}, "Europe/Paris"},
+		{"nothing known is UTC", func(it *models.Itinerary) {
+			it.Days[2].TimeZone = ""
+		}, models.Transfer{Timing: time.Date(2025, time.April, 9, 18, 0, 0, 0, time.UTC)}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itinerary := tokyoTrip()
+			if test.change != nil {
+				test.change(itinerary)
+			}
+			applyAirportTimeZones(itinerary)
+			applyHotelTimeZones(itinerary)
+			if got := transferZone(itinerary, test.transfer); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeInstants(t *testing.T) {
+	itinerary := tokyoTrip()
+	// the Paris day has no zone, so the pick-up can only learn it from the flight
+	itinerary.Days[2].TimeZone = ""
+	itinerary.Transfers = []models.Transfer{
+		{From: "CDG", To: "Hotel du Louvre", FlightNumber: "AF 275", Timing: time.Date(2025, time.April, 9, 3, 30, 0, 0, time.UTC)},
+		{From: "Hotel Okura", To: "Senso-ji", Timing: time.Date(2025, time.April, 7, 1, 0, 0, 0, time.UTC)},
+	}
+	applyAirportTimeZones(itinerary)
+	normalizeInstants(itinerary)
+
+	tests := []struct {
+		name string
+		got  time.Time
+		want time.Time
+	}{
+		{"departure", itinerary.Flights[0].Departure, time.Date(2025, time.April, 8, 22, 0, 0, 0, mustZone(t, "Asia/Tokyo"))},
+		{"arrival", itinerary.Flights[0].Arrival, time.Date(2025, time.April, 9, 4, 30, 0, 0, mustZone(t, "Europe/Paris"))},
+		{"pick-up after the flight", itinerary.Transfers[0].Timing, time.Date(2025, time.April, 9, 5, 30, 0, 0, mustZone(t, "Europe/Paris"))},
+		{"transfer on a Tokyo day", itinerary.Transfers[1].Timing, time.Date(2025, time.April, 7, 10, 0, 0, 0, mustZone(t, "Asia/Tokyo"))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if !test.got.Equal(test.want) {
+				t.Errorf("moved the instant to %s, want %s", test.got, test.want)
+			}
+			if got, want := test.got.Format(time.RFC3339), test.want.Format(time.RFC3339); got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyHotelTimeZones(t *testing.T) {
+	itinerary := tokyoTrip()
+	itinerary.Hotels = []models.Hotel{
+		{Name: "Hotel Okura", City: "Tokyo", CheckInDate: itinerary.Days[0].Date},
+		{Name: "Hotel du Louvre", City: "Paris", CheckInDate: itinerary.Days[2].Date, TimeZone: "Europe/Paris"},
+		{Name: "Le Negresco", City: "Nice", CheckInDate: itinerary.EndDate.AddDate(0, 0, 1)},
+		{Name: "Gasthof", City: "Nowhere", CheckInDate: itinerary.EndDate.AddDate(0, 0, 1)},
+	}
+	applyHotelTimeZones(itinerary)
+
+	want := []string{"Asia/Tokyo", "Europe/Paris", "Europe/Paris", ""}
+	for i, hotel := range itinerary.Hotels {
+		if hotel.TimeZone != want[i] {
+			t.Errorf("%s: got %q, want %q", hotel.Name, hotel.TimeZone, want[i])
+		}
+	}
+}
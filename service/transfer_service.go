@@ -0,0 +1,314 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/reference"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// transfer issue codes
+const (
+	CodeUnknownTransferFlight  = "unknown_transfer_flight"
+	CodeTransferFlightConflict = "transfer_flight_conflict"
+	CodeTransferTooLate        = "transfer_too_late"
+)
+
+// transfer directions relative to a flight
+const (
+	TransferArrival   = "arrival"
+	TransferDeparture = "departure"
+)
+
+const (
+	// DefaultArrivalBuffer is the time between landing and the pick-up at the airport
+	DefaultArrivalBuffer = 45 * time.Minute
+
+	// DefaultDepartureBuffer is the time between the hotel pick-up and take-off
+	DefaultDepartureBuffer = 3 * time.Hour
+
+	// defaultTransferMode is used for generated transfers
+	defaultTransferMode = "Private Car"
+
+	// matchWindow is how far an unlinked transfer may be from a flight and
+	// still be taken as its airport transfer
+	matchWindow = 6 * time.Hour
+)
+
+// TransferBuffers sets how generated transfers are timed around flights
+type TransferBuffers struct {
+	AfterArrival    time.Duration
+	BeforeDeparture time.Duration
+}
+
+// TransferSuggestion is a transfer the planner proposes for a flight
+type TransferSuggestion struct {
+	Direction string          `json:"direction"`
+	Transfer  models.Transfer `json:"transfer"`
+}
+
+// TransferPlan lists the missing airport transfers and the conflicts of the
+// existing ones
+type TransferPlan struct {
+	Suggestions []TransferSuggestion `json:"suggestions"`
+	Conflicts   []ValidationIssue    `json:"conflicts"`
+}
+
+// TransferService aligns transfers with the flights and hotels of a trip
+type TransferService struct {
+	itineraries *ItineraryService
+	journeys    *JourneyService
+	buffers     TransferBuffers
+}
+
+// NewTransferService creates a new transfer service, zero buffers fall back
+// to the defaults
+func NewTransferService(itineraries *ItineraryService, journeys *JourneyService, buffers TransferBuffers) *TransferService {
+	if buffers.AfterArrival <= 0 {
+		buffers.AfterArrival = DefaultArrivalBuffer
+	}
+	if buffers.BeforeDeparture <= 0 {
+		buffers.BeforeDeparture = DefaultDepartureBuffer
+	}
+
+	return &TransferService{
+		itineraries: itineraries,
+		journeys:    journeys,
+		buffers:     buffers,
+	}
+}
+
+// Suggest plans the transfers of a stored itinerary
+func (s *TransferService) Suggest(id string) (*TransferPlan, error) {
+	itinerary, err := s.itineraries.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Plan(itinerary), nil
+}
+
+// Generate adds the suggested transfers to a stored itinerary. The update
+// goes through the regular validation so a conflicting plan is rejected.
+func (s *TransferService) Generate(id string) (*models.Itinerary, error) {
+	itinerary, err := s.itineraries.GetItinerary(id)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := s.Plan(itinerary)
+	if len(plan.Suggestions) == 0 {
+		return itinerary, nil
+	}
+
+	transfers := append([]models.Transfer{}, itinerary.Transfers...)
+	for _, suggestion := range plan.Suggestions {
+		transfers = append(transfers, suggestion.Transfer)
+	}
+	sort.SliceStable(transfers, func(i, j int) bool {
+		return transfers[i].Timing.Before(transfers[j].Timing)
+	})
+
+	return s.itineraries.UpdateItinerary(id, &models.UpdateItineraryReq{Transfers: transfers})
+}
+
+// Plan proposes an airport to hotel transfer after every journey and a hotel
+// to airport transfer before every journey. Connections need no transfer and
+// journeys from or to home (no hotel that night) are skipped, as are flights
+// that already have a transfer.
+func (s *TransferService) Plan(itinerary *models.Itinerary) *TransferPlan {
+	plan := &TransferPlan{
+		Suggestions: []TransferSuggestion{},
+		Conflicts:   s.conflicts(itinerary),
+	}
+
+	for _, journey := range s.journeys.Analyze(itinerary).Journeys {
+		first := itinerary.Flights[journey.Legs[0].FlightIndex]
+		last := itinerary.Flights[journey.Legs[len(journey.Legs)-1].FlightIndex]
+
+		if hotel, ok := departureHotel(itinerary, first); ok && findTransfer(itinerary, first, TransferDeparture) < 0 {
+			plan.Suggestions = append(plan.Suggestions, TransferSuggestion{
+				Direction: TransferDeparture,
+				Transfer: models.Transfer{
+					From:         hotel.Name,
+					To:           airportLabel(first.From),
+					Mode:         defaultTransferMode,
+					Timing:       first.Departure.Add(-s.buffers.BeforeDeparture),
+					FlightNumber: first.FlightNumber,
+				},
+			})
+		}
+
+		if hotel, ok := arrivalHotel(itinerary, last); ok && findTransfer(itinerary, last, TransferArrival) < 0 {
+			plan.Suggestions = append(plan.Suggestions, TransferSuggestion{
+				Direction: TransferArrival,
+				Transfer: models.Transfer{
+					From:         airportLabel(last.To),
+					To:           hotel.Name,
+					Mode:         defaultTransferMode,
+					Timing:       last.Arrival.Add(s.buffers.AfterArrival),
+					FlightNumber: last.FlightNumber,
+				},
+			})
+		}
+	}
+
+	return plan
+}
+
+// ValidationRule reports the conflicts between transfers and their flights
+func (s *TransferService) ValidationRule() ValidationRule {
+	return s.conflicts
+}
+
+// conflicts checks every transfer linked to a flight: a pick-up cannot be
+// before landing, a drop-off cannot be after take-off and should leave the
+// departure buffer
+func (s *TransferService) conflicts(itinerary *models.Itinerary) []ValidationIssue {
+	issues := []ValidationIssue{}
+
+	for i, transfer := range itinerary.Transfers {
+		if transfer.FlightNumber == "" {
+			continue
+		}
+		path := fmt.Sprintf("transfers[%d]", i)
+
+		flight, ok := flightByNumber(itinerary, transfer.FlightNumber)
+		if !ok {
+			issues = append(issues, issue(path+".flight_number", CodeUnknownTransferFlight,
+				"transfer from %s to %s is linked to flight %s which is not in the itinerary",
+				transfer.From, transfer.To, transfer.FlightNumber))
+			continue
+		}
+
+		switch transferDirection(transfer, flight) {
+		case TransferArrival:
+			if transfer.Timing.Before(flight.Arrival) {
+				issues = append(issues, issue(path+".time", CodeTransferFlightConflict,
+					"pick-up from %s at %s is before flight %s lands at %s", transfer.From,
+					formatLocal(transfer.Timing, flight.ArrivalTimeZone, "Jan 2 15:04"), flight.FlightNumber,
+					formatLocal(flight.Arrival, flight.ArrivalTimeZone, "Jan 2 15:04")))
+			}
+		case TransferDeparture:
+			lead := flight.Departure.Sub(transfer.Timing)
+			switch {
+			case lead < 0:
+				issues = append(issues, issue(path+".time", CodeTransferFlightConflict,
+					"transfer to %s at %s is after flight %s departs at %s", transfer.To,
+					formatLocal(transfer.Timing, flight.DepartureTimeZone, "Jan 2 15:04"), flight.FlightNumber,
+					formatLocal(flight.Departure, flight.DepartureTimeZone, "Jan 2 15:04")))
+			case lead < s.buffers.BeforeDeparture:
+				issues = append(issues, warning(path+".time", CodeTransferTooLate,
+					"transfer to %s leaves only %s before flight %s departs, at least %s is recommended",
+					transfer.To, formatDuration(lead), flight.FlightNumber, formatDuration(s.buffers.BeforeDeparture)))
+			}
+		}
+	}
+
+	return issues
+}
+
+// findTransfer returns the index of the transfer serving a flight in the given
+// direction, either linked by flight number or an unlinked transfer naming
+// the airport close to the flight time, or -1
+func findTransfer(itinerary *models.Itinerary, flight models.Flight, direction string) int {
+	for i, transfer := range itinerary.Transfers {
+		if transfer.FlightNumber != "" {
+			if strings.EqualFold(transfer.FlightNumber, flight.FlightNumber) && transferDirection(transfer, flight) == direction {
+				return i
+			}
+			continue
+		}
+
+		switch direction {
+		case TransferArrival:
+			if mentionsAirport(transfer.From, flight.To) && !transfer.Timing.Before(flight.Arrival.Add(-matchWindow)) &&
+				!transfer.Timing.After(flight.Arrival.Add(matchWindow)) {
+				return i
+			}
+		case TransferDeparture:
+			if mentionsAirport(transfer.To, flight.From) && !transfer.Timing.Before(flight.Departure.Add(-matchWindow)) &&
+				!transfer.Timing.After(flight.Departure.Add(matchWindow)) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// transferDirection tells whether a transfer linked to a flight is the
+// pick-up after landing or the drop-off before take-off. The airport named
+// in the transfer decides, otherwise its time relative to the flight.
+func transferDirection(transfer models.Transfer, flight models.Flight) string {
+	switch {
+	case mentionsAirport(transfer.From, flight.To):
+		return TransferArrival
+	case mentionsAirport(transfer.To, flight.From):
+		return TransferDeparture
+	}
+
+	midpoint := flight.Departure.Add(FlightDuration(flight) / 2)
+	if transfer.Timing.Before(midpoint) {
+		return TransferDeparture
+	}
+	return TransferArrival
+}
+
+// mentionsAirport reports whether a place names the airport, by IATA code or
+// airport name when it is in the reference data
+func mentionsAirport(place, airport string) bool {
+	place = strings.ToLower(place)
+	if known, ok := reference.Default().Airport(airport); ok {
+		for _, word := range strings.FieldsFunc(place, func(r rune) bool { return r == ' ' || r == '(' || r == ')' || r == ',' || r == '-' }) {
+			if strings.EqualFold(word, known.IATA) {
+				return true
+			}
+		}
+		return strings.Contains(place, strings.ToLower(known.Name))
+	}
+	return strings.Contains(place, strings.ToLower(strings.TrimSpace(airport)))
+}
+
+// airportLabel names an airport for a generated transfer, e.g. "Charles de Gaulle Airport (CDG)"
+func airportLabel(airport string) string {
+	if known, ok := reference.Default().Airport(airport); ok {
+		return fmt.Sprintf("%s (%s)", known.Name, known.IATA)
+	}
+	return airport
+}
+
+// arrivalHotel finds the hotel the traveller goes to after landing: the one
+// checking in on the local arrival date or covering that night
+func arrivalHotel(itinerary *models.Itinerary, flight models.Flight) (models.Hotel, bool) {
+	date := localDateOf(flight.Arrival, flight.ArrivalTimeZone)
+	for _, hotel := range itinerary.Hotels {
+		if !date.Before(dateOf(hotel.CheckInDate)) && date.Before(dateOf(hotel.CheckOutDate)) {
+			return hotel, true
+		}
+	}
+	return models.Hotel{}, false
+}
+
+// departureHotel finds the hotel the traveller leaves for the airport: the
+// one checking out on the local departure date or covering the night before
+func departureHotel(itinerary *models.Itinerary, flight models.Flight) (models.Hotel, bool) {
+	date := localDateOf(flight.Departure, flight.DepartureTimeZone)
+	for _, hotel := range itinerary.Hotels {
+		if date.After(dateOf(hotel.CheckInDate)) && !date.After(dateOf(hotel.CheckOutDate)) {
+			return hotel, true
+		}
+	}
+	return models.Hotel{}, false
+}
+
+// flightByNumber finds a flight of the itinerary by its flight number
+func flightByNumber(itinerary *models.Itinerary, flightNumber string) (models.Flight, bool) {
+	for _, flight := range itinerary.Flights {
+		if strings.EqualFold(flight.FlightNumber, flightNumber) {
+			return flight, true
+		}
+	}
+	return models.Flight{}, false
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// singaporeAt returns a wall clock time in Singapore in July 2025
+func singaporeAt(day, hour, minute int) time.Time {
+	return time.Date(2025, time.July, day, hour, minute, 0, 0, time.FixedZone("SGT", 8*60*60))
+}
+
+// singaporeTrip returns a four night stay in Singapore between a flight
+// from Delhi landing at 14:00 and one back leaving at 18:00
+func singaporeTrip() *models.Itinerary {
+	itinerary := &models.Itinerary{
+		StartDate: time.Date(2025, time.July, 3, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2025, time.July, 7, 0, 0, 0, 0, time.UTC),
+		Flights: []models.Flight{
+			{FlightNumber: "SQ 403", From: "DEL", To: "SIN", Departure: singaporeAt(3, 8, 30), Arrival: singaporeAt(3, 14, 0)},
+			{FlightNumber: "SQ 406", From: "SIN", To: "DEL", Departure: singaporeAt(7, 18, 0), Arrival: singaporeAt(7, 21, 30)},
+		},
+		Hotels: []models.Hotel{{
+			Name:         "Marina Bay Sands",
+			City:         "Singapore",
+			CheckInDate:  time.Date(2025, time.July, 3, 0, 0, 0, 0, time.UTC),
+			CheckOutDate: time.Date(2025, time.July, 7, 0, 0, 0, 0, time.UTC),
+			Nights:       4,
+		}},
+	}
+	applyAirportTimeZones(itinerary)
+	return itinerary
+}
+
+// testTransfers returns a transfer service with the default buffers
+func testTransfers() *TransferService {
+	return NewTransferService(nil, NewJourneyService(DefaultMinConnectionTime), TransferBuffers{})
+}
+
+func TestTransferPlan(t *testing.T) {
+	pickUp := models.Transfer{From: "Singapore Changi Airport (SIN)", To: "Marina Bay Sands", Mode: defaultTransferMode,
+		Timing: singaporeAt(3, 14, 45), FlightNumber: "SQ 403"}
+	dropOff := models.Transfer{From: "Marina Bay Sands", To: "Singapore Changi Airport (SIN)", Mode: defaultTransferMode,
+		Timing: singaporeAt(7, 15, 0), FlightNumber: "SQ 406"}
+
+	tests := []struct {
+		name   string
+		change func(itinerary *models.Itinerary)
+		want   []TransferSuggestion
+	}{
+		{"both airport transfers", nil, []TransferSuggestion{
+			{TransferArrival, pickUp}, {TransferDeparture, dropOff},
+		}},
+		{"pick-up already linked", func(it *models.Itinerary) {
+			it.Transfers = []models.Transfer{{From: "Changi", To: "Hotel", FlightNumber: "sq 403", Timing: singaporeAt(3, 15, 0)}}
+		}, []TransferSuggestion{{TransferDeparture, dropOff}}},
+		{"unlinked drop-off naming the airport", func(it *models.Itinerary) {
+			it.Transfers = []models.Transfer{{From: "Marina Bay Sands", To: "Changi (SIN)", Timing: singaporeAt(7, 14, 0)}}
+		}, []TransferSuggestion{{TransferArrival, pickUp}}},
+		{"unlinked drop-off on another day", func(it *models.Itinerary) {
+			it.Transfers = []models.Transfer{{From: "Marina Bay Sands", To: "Changi (SIN)", Timing: singaporeAt(5, 14, 0)}}
+		}, []TransferSuggestion{{TransferArrival, pickUp}, {TransferDeparture, dropOff}}},
+		{"no hotel to go to", func(it *models.Itinerary) {
+			it.Hotels = nil
+		}, []TransferSuggestion{}},
+	}
+
+	transfers := testTransfers()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itinerary := singaporeTrip()
+			if test.change != nil {
+				test.change(itinerary)
+			}
+			got := transfers.Plan(itinerary).Suggestions
+			if len(got) != len(test.want) {
+				t.Fatalf("got %d suggestions, want %d: %+v", len(got), len(test.want), got)
+			}
+			for i := range got {
+				want := test.want[i]
+				if got[i].Direction != want.Direction || !got[i].Transfer.Timing.Equal(want.Transfer.Timing) {
+					t.Errorf("got %s at %s, want %s at %s", got[i].Direction, got[i].Transfer.Timing, want.Direction, want.Transfer.Timing)
+				}
+				got[i].Transfer.Timing = want.Transfer.Timing
+				if got[i].Transfer != want.Transfer {
+					t.Errorf("got %+v, want %+v", got[i].Transfer, want.Transfer)
+				}
+			}
+		})
+	}
+}
+
+func TestTransferConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		transfer models.Transfer
+		want     []string
+	}{
+		{"pick-up after landing", models.Transfer{From: "Changi (SIN)", To: "Marina Bay Sands", FlightNumber: "SQ 403", Timing: singaporeAt(3, 14, 30)}, nil},
+		{"pick-up before landing", models.Transfer{From: "Changi (SIN)", To: "Marina Bay Sands", FlightNumber: "SQ 403", Timing: singaporeAt(3, 13, 0)},
+			[]string{"error " + CodeTransferFlightConflict}},
+		{"drop-off in good time", models.Transfer{From: "Marina Bay Sands", To: "Changi (SIN)", FlightNumber: "SQ 406", Timing: singaporeAt(7, 14, 0)}, nil},
+		{"drop-off close to take-off", models.Transfer{From: "Marina Bay Sands", To: "Changi (SIN)", FlightNumber: "SQ 406", Timing: singaporeAt(7, 16, 0)},
+			[]string{"warning " + CodeTransferTooLate}},
+		{"drop-off after take-off", models.Transfer{From: "Marina Bay Sands", To: "Changi (SIN)", FlightNumber: "SQ 406", Timing: singaporeAt(7, 18, 30)},
+			[]string{"error " + CodeTransferFlightConflict}},
+		// no airport named: the time decides, before the middle of the flight is a drop-off
+		{"direction from the time", models.Transfer{From: "Marina Bay Sands", To: "Terminal 3", FlightNumber: "SQ 406", Timing: singaporeAt(7, 18, 30)},
+			[]string{"error " + CodeTransferFlightConflict}},
+		{"unknown flight", models.Transfer{From: "Changi (SIN)", To: "Marina Bay Sands", FlightNumber: "SQ 999", Timing: singaporeAt(3, 15, 0)},
+			[]string{"error " + CodeUnknownTransferFlight}},
+		{"unlinked transfer", models.Transfer{From: "Marina Bay Sands", To: "Sentosa", Timing: singaporeAt(4, 10, 0)}, nil},
+	}
+
+	rule := testTransfers().ValidationRule()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itinerary := singaporeTrip()
+			itinerary.Transfers = []models.Transfer{test.transfer}
+			var got []string
+			for _, found := range rule(itinerary) {
+				got = append(got, found.Severity+" "+found.Code)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// traveller issue codes
+const (
+	CodeDuplicateTraveller        = "duplicate_traveller"
+	CodeUnknownTraveller          = "unknown_traveller"
+	CodeDuplicateAssignment       = "duplicate_assignment"
+	CodeDuplicateSeat             = "duplicate_seat"
+	CodeInvalidDateOfBirth        = "invalid_date_of_birth"
+	CodePassportExpiresDuringTrip = "passport_expires_during_trip"
+
+	// warnings
+	CodePassportValidityShort = "passport_validity_short"
+	CodeMissingPassportExpiry = "missing_passport_expiry"
+)
+
+// MinPassportValidityMonths is how long a passport should stay valid after
+// the end of the trip, the rule most countries apply on entry
+const MinPassportValidityMonths = 6
+
+// normalizeTravellers gives every traveller without an ID a generated one
+func normalizeTravellers(itinerary *models.Itinerary) {
+	for i := range itinerary.Travellers {
+		if itinerary.Travellers[i].ID == "" {
+			itinerary.Travellers[i].ID = uuid.New().String()
+		}
+	}
+}
+
+// validateTravellers checks that traveller IDs are unique and that every
+// seat and room is assigned to known travellers, each at most once per
+// flight or hotel stay
+func validateTravellers(itinerary *models.Itinerary) []ValidationIssue {
+	var issues []ValidationIssue
+
+	known := make(map[string]bool)
+	for i, traveller := range itinerary.Travellers {
+		path := fmt.Sprintf("travellers[%d]", i)
+		if known[traveller.ID] {
+			issues = append(issues, issue(path+".id", CodeDuplicateTraveller, "traveller ID %s is used twice", traveller.ID))
+		}
+		known[traveller.ID] = true
+
+		if traveller.DateOfBirth != nil && !dateOf(*traveller.DateOfBirth).Before(dateOf(itinerary.StartDate)) {
+			issues = append(issues, issue(path+".date_of_birth", CodeInvalidDateOfBirth,
+				"%s: date of birth must be before the trip", traveller.Name))
+		}
+	}
+
+	for i, flight := range itinerary.Flights {
+		assigned := make(map[string]bool)
+		seats := make(map[string]bool)
+		for j, passenger := range flight.Passengers {
+			path := fmt.Sprintf("flights[%d].passengers[%d]", i, j)
+			switch {
+			case !known[passenger.TravellerID]:
+				issues = append(issues, issue(path+".traveller_id", CodeUnknownTraveller,
+					"flight %s: unknown traveller %s", flight.FlightNumber, passenger.TravellerID))
+			case assigned[passenger.TravellerID]:
+				issues = append(issues, issue(path+".traveller_id", CodeDuplicateAssignment,
+					"flight %s: traveller %s is assigned twice", flight.FlightNumber, passenger.TravellerID))
+			}
+			assigned[passenger.TravellerID] = true
+
+			seat := strings.ToUpper(strings.TrimSpace(passenger.Seat))
+			if seat != "" && seats[seat] {
+				issues = append(issues, issue(path+".seat", CodeDuplicateSeat,
+					"flight %s: seat %s is assigned twice", flight.FlightNumber, passenger.Seat))
+			}
+			seats[seat] = true
+		}
+	}
+
+	for i, hotel := range itinerary.Hotels {
+		assigned := make(map[string]bool)
+		for j, room := range hotel.Rooms {
+			for k, id := range room.TravellerIDs {
+				path := fmt.Sprintf("hotels[%d].rooms[%d].traveller_ids[%d]", i, j, k)
+				switch {
+				case !known[id]:
+					issues = append(issues, issue(path, CodeUnknownTraveller, "%s: unknown traveller %s", hotel.Name, id))
+				case assigned[id]:
+					issues = append(issues, issue(path, CodeDuplicateAssignment,
+						"%s: traveller %s is in more than one room", hotel.Name, id))
+				}
+				assigned[id] = true
+			}
+		}
+	}
+
+	return issues
+}
+
+// validatePassports checks passport expiry against the travel dates. A
+// passport expiring before the trip ends is an error; one expiring within
+// MinPassportValidityMonths of the end is a warning, as is a flying
+// traveller without a known expiry.
+func validatePassports(itinerary *models.Itinerary) []ValidationIssue {
+	var issues []ValidationIssue
+
+	flying := make(map[string]bool)
+	for _, flight := range itinerary.Flights {
+		for _, passenger := range flight.Passengers {
+			flying[passenger.TravellerID] = true
+		}
+	}
+
+	end := dateOf(itinerary.EndDate)
+	for i, traveller := range itinerary.Travellers {
+		path := fmt.Sprintf("travellers[%d].passport_expiry", i)
+		if traveller.PassportExpiry == nil {
+			if flying[traveller.ID] {
+				issues = append(issues, warning(path, CodeMissingPassportExpiry,
+					"%s: passport expiry is unknown", traveller.Name))
+			}
+			continue
+		}
+
+		expiry := dateOf(*traveller.PassportExpiry)
+		switch {
+		case expiry.Before(end):
+			issues = append(issues, issue(path, CodePassportExpiresDuringTrip,
+				"%s: passport expires on %s, before the trip ends on %s", traveller.Name,
+				expiry.Format("2006-01-02"), end.Format("2006-01-02")))
+		case expiry.Before(end.AddDate(0, MinPassportValidityMonths, 0)):
+			issues = append(issues, warning(path, CodePassportValidityShort,
+				"%s: passport expires on %s, less than %d months after the trip", traveller.Name,
+				expiry.Format("2006-01-02"), MinPassportValidityMonths))
+		}
+	}
+
+	return issues
+}
+
+// travellerNames returns the names of the travellers with the given IDs,
+// falling back to the ID of unknown ones
+func travellerNames(travellers []models.Traveller, ids []string) []string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = id
+		for _, traveller := range travellers {
+			if traveller.ID == id {
+				names[i] = traveller.Name
+				break
+			}
+		}
+	}
+	return names
+}
+
+// copyTravellers returns a deep copy of a list of travellers
+func copyTravellers(travellers []models.Traveller) []models.Traveller {
+	if travellers == nil {
+		return nil
+	}
+	copied := make([]models.Traveller, len(travellers))
+	for i, traveller := range travellers {
+		traveller.DietaryNeeds = append([]string(nil), traveller.DietaryNeeds...)
+		if traveller.EmergencyContact != nil {
+			contact := *traveller.EmergencyContact
+			traveller.EmergencyContact = &contact
+		}
+		copied[i] = traveller
+	}
+	return copied
+}
+
+// copyRooms returns a deep copy of the room allocations of a hotel stay
+func copyRooms(rooms []models.RoomAllocation) []models.RoomAllocation {
+	if rooms == nil {
+		return nil
+	}
+	copied := make([]models.RoomAllocation, len(rooms))
+	for i, room := range rooms {
+		room.TravellerIDs = append([]string{}, room.TravellerIDs...)
+		copied[i] = room
+	}
+	return copied
+}
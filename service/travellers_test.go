@@ -0,0 +1,184 @@
+package service
+
+import (
+	"encoding/json"
+	"example/vigovia-itenary-api/models"
+	"example/vigovia-itenary-api/repository"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// august returns midnight UTC of a date in August 2025
+func august(d int) time.Time {
+	return time.Date(2025, time.August, d, 0, 0, 0, 0, time.UTC)
+}
+
+// icelandTrip returns a valid trip to Reykjavik from 1 to 4 August 2025 with
+// two travellers, both on the flight there and sharing a room
+func icelandTrip() *models.Itinerary {
+	due := models.NewMoney(decimal.NewFromInt(310000), "INR")
+	born := time.Date(1990, time.March, 3, 0, 0, 0, 0, time.UTC)
+	anyaExpiry := time.Date(2028, time.May, 1, 0, 0, 0, 0, time.UTC)
+	raviExpiry := time.Date(2029, time.January, 15, 0, 0, 0, 0, time.UTC)
+	return &models.Itinerary{
+		ID:        "ice-1",
+		UserID:    "agent-nordic",
+		Title:     "Iceland ring road",
+		StartDate: august(1),
+		EndDate:   august(4),
+		Days: []models.Day{
+			{DayNumber: 1, Date: august(1), Title: "Reykjavik"},
+			{DayNumber: 2, Date: august(2), Title: "Golden circle"},
+			{DayNumber: 3, Date: august(3), Title: "Blue lagoon"},
+			{DayNumber: 4, Date: august(4), Title: "Departure"},
+		},
+		Hotels: []models.Hotel{
+			{Name: "Hotel Borg", City: "Reykjavik", CheckInDate: august(1), CheckOutDate: august(4), Nights: 3,
+				Rooms: []models.RoomAllocation{{RoomType: "Twin", TravellerIDs: []string{"anya", "ravi"}}}},
+		},
+		Flights: []models.Flight{
+			{FlightNumber: "FI 455", From: "LHR", To: "KEF", Departure: august(1).Add(8 * time.Hour), Arrival: august(1).Add(11 * time.Hour),
+				Passengers: []models.FlightPassenger{{TravellerID: "anya", Seat: "12A"}, {TravellerID: "ravi", Seat: "12B"}}},
+		},
+		Travellers: []models.Traveller{
+			{ID: "anya", Name: "Anya Rao", DateOfBirth: &born, Nationality: "IN", PassportNumber: "Z1234567",
+				PassportExpiry: &anyaExpiry, DietaryNeeds: []string{"vegetarian"},
+				EmergencyContact: &models.EmergencyContact{Name: "Meena Rao", Phone: "+91 98200 00000"}},
+			{ID: "ravi", Name: "Ravi Rao", PassportNumber: "Z7654321", PassportExpiry: &raviExpiry},
+		},
+		PaymentPlan: models.PaymentPlan{
+			AmountDue: due,
+			DueDate:   august(1).AddDate(0, -1, 0),
+			Installments: []models.Installment{
+				{InstallmentNumber: 1, Amount: due, DueDate: august(1).AddDate(0, -1, 0), Status: "Pending"},
+			},
+		},
+		Status: models.StatusConfirmed,
+		StatusHistory: []models.StatusChange{
+			{To: models.StatusDraft, ChangedBy: "agent-nordic", ChangedAt: august(1).AddDate(0, -2, 0)},
+			{From: models.StatusDraft, To: models.StatusQuoted, ChangedBy: "agent-nordic", ChangedAt: august(1).AddDate(0, -2, 1)},
+			{From: models.StatusQuoted, To: models.StatusConfirmed, ChangedBy: "Anya Rao", ChangedAt: august(1).AddDate(0, -2, 2)},
+		},
+	}
+}
+
+func TestValidatePassports(t *testing.T) {
+	tests := []struct {
+		name   string
+		expiry *time.Time
+		flying bool
+		want   []string
+	}{
+		{"valid long after the trip", func() *time.Time { d := august(4).AddDate(1, 0, 0); return &d }(), true, nil},
+		{"exactly six months after the trip", func() *time.Time { d := august(4).AddDate(0, 6, 0); return &d }(), true, nil},
+		{"less than six months after the trip", func() *time.Time { d := august(4).AddDate(0, 6, -1); return &d }(), true,
+			[]string{CodePassportValidityShort}},
+		{"on the last day", func() *time.Time { d := august(4); return &d }(), true, []string{CodePassportValidityShort}},
+		{"during the trip", func() *time.Time { d := august(3); return &d }(), true, []string{CodePassportExpiresDuringTrip}},
+		{"unknown for a flying traveller", nil, true, []string{CodeMissingPassportExpiry}},
+		{"unknown for a traveller staying on the ground", nil, false, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itinerary := icelandTrip()
+			itinerary.Travellers[0].PassportExpiry = test.expiry
+			if !test.flying {
+				itinerary.Flights[0].Passengers = itinerary.Flights[0].Passengers[1:]
+			}
+			if got := issueCodes(validatePassports(itinerary)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidatePassportsSeverity(t *testing.T) {
+	itinerary := icelandTrip()
+	during, short := august(2), august(5)
+	itinerary.Travellers[0].PassportExpiry = &during
+	itinerary.Travellers[1].PassportExpiry = &short
+
+	issues := validatePassports(itinerary)
+	if len(issues) != 2 || issues[0].Severity != "error" || issues[1].Severity != "warning" {
+		t.Fatalf("got %+v", issues)
+	}
+	if issues[0].Path != "travellers[0].passport_expiry" || issues[1].Path != "travellers[1].passport_expiry" {
+		t.Errorf("got paths %s and %s", issues[0].Path, issues[1].Path)
+	}
+}
+
+func TestValidateTravellers(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(itinerary *models.Itinerary)
+		want   []string
+	}{
+		{"valid", nil, nil},
+		{"duplicate traveller", func(it *models.Itinerary) {
+			it.Travellers[1].ID = "anya"
+		}, []string{CodeDuplicateTraveller, CodeUnknownTraveller, CodeUnknownTraveller}},
+		{"born after the trip starts", func(it *models.Itinerary) {
+			born := august(1)
+			it.Travellers[0].DateOfBirth = &born
+		}, []string{CodeInvalidDateOfBirth}},
+		{"unknown passenger", func(it *models.Itinerary) {
+			it.Flights[0].Passengers[1].TravellerID = "sunil"
+		}, []string{CodeUnknownTraveller}},
+		{"passenger twice on a flight", func(it *models.Itinerary) {
+			it.Flights[0].Passengers[1] = models.FlightPassenger{TravellerID: "anya", Seat: "14C"}
+		}, []string{CodeDuplicateAssignment}},
+		{"seat given twice, ignoring case", func(it *models.Itinerary) {
+			it.Flights[0].Passengers[1].Seat = " 12a"
+		}, []string{CodeDuplicateSeat}},
+		{"traveller in two rooms", func(it *models.Itinerary) {
+			it.Hotels[0].Rooms = append(it.Hotels[0].Rooms, models.RoomAllocation{TravellerIDs: []string{"ravi"}})
+		}, []string{CodeDuplicateAssignment}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itinerary := icelandTrip()
+			if test.change != nil {
+				test.change(itinerary)
+			}
+			if got := issueCodes(validateTravellers(itinerary)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCustomerViewHidesAgencyDetails(t *testing.T) {
+	itinerary := icelandTrip()
+	view := NewItineraryService(repository.NewInMemoryRepo(), nil).CustomerView(itinerary)
+
+	encoded, err := json.Marshal(view)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hidden := range []string{`"user_id"`, "agent-nordic", `"status_history"`, `"changed_by"`,
+		`"date_of_birth"`, `"nationality"`, `"passport_expiry"`, `"emergency_contact"`, "Z1234567"} {
+		if strings.Contains(string(encoded), hidden) {
+			t.Errorf("the customer view shows %s", hidden)
+		}
+	}
+
+	traveller := view.Travellers[0]
+	if traveller.ID != "anya" || traveller.Name != "Anya Rao" || traveller.PassportNumber != "****4567" ||
+		!reflect.DeepEqual(traveller.DietaryNeeds, []string{"vegetarian"}) {
+		t.Errorf("got traveller %+v", traveller)
+	}
+	if view.Status != models.StatusConfirmed {
+		t.Errorf("got status %s, want %s", view.Status, models.StatusConfirmed)
+	}
+
+	// the stored itinerary keeps everything
+	if itinerary.UserID != "agent-nordic" || len(itinerary.StatusHistory) != 3 || itinerary.Travellers[0].PassportNumber != "Z1234567" {
+		t.Error("the customer view changed the itinerary")
+	}
+}
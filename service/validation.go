@@ -0,0 +1,132 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"time"
+)
+
+// issue severities, errors block a save while warnings are only reported
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationIssue describes a single rule violation. Path is the JSON path of
+// the offending field, e.g. days[2].date
+type ValidationIssue struct {
+	Path     string `json:"path"`
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ValidationReport splits the issues found in an itinerary by severity
+type ValidationReport struct {
+	Valid    bool              `json:"valid"`
+	Errors   []ValidationIssue `json:"errors"`
+	Warnings []ValidationIssue `json:"warnings"`
+}
+
+// ValidationError carries every blocking violation found in an itinerary
+// along with the warnings raised at the same time
+type ValidationError struct {
+	Issues   []ValidationIssue
+	Warnings []ValidationIssue
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Issues) == 1 {
+		return fmt.Sprintf("validation failed: %s", e.Issues[0].Message)
+	}
+	return fmt.Sprintf("validation failed with %d issues, first: %s", len(e.Issues), e.Issues[0].Message)
+}
+
+// ValidationRule checks one aspect of an itinerary and returns its violations
+type ValidationRule func(itinerary *models.Itinerary) []ValidationIssue
+
+// Validator runs a set of rules and collects every violation at once
+type Validator struct {
+	rules []ValidationRule
+}
+
+// NewValidator creates a validator with the given rules
+func NewValidator(rules ...ValidationRule) *Validator {
+	return &Validator{
+		rules: rules,
+	}
+}
+
+// AddRules registers additional rules on the validator
+func (v *Validator) AddRules(rules ...ValidationRule) {
+	v.rules = append(v.rules, rules...)
+}
+
+// Validate runs every rule against the itinerary
+func (v *Validator) Validate(itinerary *models.Itinerary) []ValidationIssue {
+	var issues []ValidationIssue
+	for _, rule := range v.rules {
+		issues = append(issues, rule(itinerary)...)
+	}
+	return issues
+}
+
+// Report validates the itinerary and classifies the issues by severity
+func (v *Validator) Report(itinerary *models.Itinerary) *ValidationReport {
+	report := &ValidationReport{
+		Errors:   []ValidationIssue{},
+		Warnings: []ValidationIssue{},
+	}
+	for _, found := range v.Validate(itinerary) {
+		if found.Severity == SeverityWarning {
+			report.Warnings = append(report.Warnings, found)
+		} else {
+			report.Errors = append(report.Errors, found)
+		}
+	}
+	report.Valid = len(report.Errors) == 0
+	return report
+}
+
+// Check validates the itinerary and returns a *ValidationError if any
+// error level rule failed, warnings alone never block
+func (v *Validator) Check(itinerary *models.Itinerary) error {
+	report := v.Report(itinerary)
+	if !report.Valid {
+		return &ValidationError{Issues: report.Errors, Warnings: report.Warnings}
+	}
+	return nil
+}
+
+// issue builds an error level ValidationIssue with a formatted message
+func issue(path, code, format string, args ...interface{}) ValidationIssue {
+	return ValidationIssue{
+		Path:     path,
+		Code:     code,
+		Severity: SeverityError,
+		Message:  fmt.Sprintf(format, args...),
+	}
+}
+
+// warning builds a warning level ValidationIssue with a formatted message
+func warning(path, code, format string, args ...interface{}) ValidationIssue {
+	found := issue(path, code, format, args...)
+	found.Severity = SeverityWarning
+	return found
+}
+
+// dateOf returns the calendar date of t, dropping the time of day
+func dateOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween returns the number of calendar days from a to b
+func daysBetween(a, b time.Time) int {
+	return int(dateOf(b).Sub(dateOf(a)).Hours() / 24)
+}
+
+// tripLength returns the number of days an itinerary spans, both ends included
+func tripLength(startDate, endDate time.Time) int {
+	return daysBetween(startDate, endDate) + 1
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"fmt"
+	"sort"
+)
+
+// validation issue codes
+const (
+	CodeInvalidDateRange       = "invalid_date_range"
+	CodeDayCountMismatch       = "day_count_mismatch"
+	CodeDuplicateDayNumber     = "duplicate_day_number"
+	CodeDayNumberOutOfSequence = "day_number_out_of_sequence"
+	CodeDayDateMismatch        = "day_date_mismatch"
+	CodeInvalidHotelDates      = "invalid_hotel_dates"
+	CodeHotelNightsMismatch    = "hotel_nights_mismatch"
+	CodeHotelOutsideTrip       = "hotel_outside_trip"
+	CodeHotelOverlap           = "hotel_overlap"
+	CodeInvalidFlightTimes     = "invalid_flight_times"
+	CodeTransferOutsideTrip    = "transfer_outside_trip"
+	CodeInvalidAmount          = "invalid_amount"
+	CodeMissingInstallments    = "missing_installments"
+	CodeInstallmentSumMismatch = "installment_sum_mismatch"
+
+	// warnings
+	CodeNightWithoutHotel    = "night_without_hotel"
+	CodeDayWithoutActivities = "day_without_activities"
+)
+
+// DefaultValidationRules returns the rules every itinerary must satisfy,
+// connections are checked against the minimum connection time of journeys
+func DefaultValidationRules(journeys *JourneyService) []ValidationRule {
+	return []ValidationRule{
+		validateDateRange,
+		validateTimeZones,
+		validateDayCount,
+		validateDaySequence,
+		validateHotelStays,
+		validateHotelOverlaps,
+		validateFlightTimes,
+		validateFlightReferences,
+		journeys.ValidationRule(),
+		validateTransferTimes,
+		validatePaymentPlan,
+		validatePrices,
+		validateTravellers,
+		validatePassports,
+		NewCoverageService().ValidationRule(),
+		NewScheduleService().ValidationRule(),
+		NewFeasibilityService().ValidationRule(),
+		warnDaysWithoutActivities,
+	}
+}
+
+// validateDateRange checks that the trip ends after it starts
+func validateDateRange(itinerary *models.Itinerary) []ValidationIssue {
+	if !itinerary.EndDate.After(itinerary.StartDate) {
+		return []ValidationIssue{issue("end_date", CodeInvalidDateRange, "%s", ErrInvalidDateRange)}
+	}
+	return nil
+}
+
+// validateDayCount checks that there is one day per calendar day of the trip
+func validateDayCount(itinerary *models.Itinerary) []ValidationIssue {
+	if !itinerary.EndDate.After(itinerary.StartDate) {
+		return nil
+	}
+
+	expected := tripLength(itinerary.StartDate, itinerary.EndDate)
+	if len(itinerary.Days) != expected {
+		return []ValidationIssue{issue("days", CodeDayCountMismatch,
+			"%s: expected %d days, got %d", ErrInvalidDays, expected, len(itinerary.Days))}
+	}
+	return nil
+}
+
+// validateDaySequence checks that day numbers are unique and consecutive and
+// that each day's date is StartDate plus DayNumber-1
+func validateDaySequence(itinerary *models.Itinerary) []ValidationIssue {
+	var issues []ValidationIssue
+
+	seen := make(map[int]int)
+	for i, day := range itinerary.Days {
+		path := fmt.Sprintf("days[%d]", i)
+
+		if first, ok := seen[day.DayNumber]; ok {
+			issues = append(issues, issue(path+".day_number", CodeDuplicateDayNumber,
+				"day number %d is already used by days[%d]", day.DayNumber, first))
+		} else {
+			seen[day.DayNumber] = i
+		}
+
+		if day.DayNumber != i+1 {
+			issues = append(issues, issue(path+".day_number", CodeDayNumberOutOfSequence,
+				"expected day number %d, got %d", i+1, day.DayNumber))
+		}
+
+		expected := dateOf(itinerary.StartDate).AddDate(0, 0, day.DayNumber-1)
+		if !dateOf(day.Date).Equal(expected) {
+			issues = append(issues, issue(path+".date", CodeDayDateMismatch,
+				"day %d should be on %s, got %s", day.DayNumber,
+				expected.Format("2006-01-02"), dateOf(day.Date).Format("2006-01-02")))
+		}
+	}
+
+	return issues
+}
+
+// validateHotelStays checks each stay's night count and that it lies within the trip
+func validateHotelStays(itinerary *models.Itinerary) []ValidationIssue {
+	var issues []ValidationIssue
+
+	for i, hotel := range itinerary.Hotels {
+		path := fmt.Sprintf("hotels[%d]", i)
+
+		nights := daysBetween(hotel.CheckInDate, hotel.CheckOutDate)
+		if nights <= 0 {
+			issues = append(issues, issue(path+".check_out_date", CodeInvalidHotelDates,
+				"check-out of %s must be after check-in", hotel.Name))
+			continue
+		}
+
+		if hotel.Nights != nights {
+			issues = append(issues, issue(path+".nights", CodeHotelNightsMismatch,
+				"%s is booked from %s to %s which is %d nights, got %d", hotel.Name,
+				hotel.CheckInDate.Format("2006-01-02"), hotel.CheckOutDate.Format("2006-01-02"), nights, hotel.Nights))
+		}
+
+		if dateOf(hotel.CheckInDate).Before(dateOf(itinerary.StartDate)) || dateOf(hotel.CheckOutDate).After(dateOf(itinerary.EndDate)) {
+			issues = append(issues, issue(path, CodeHotelOutsideTrip,
+				"%s stay from %s to %s falls outside the trip", hotel.Name,
+				hotel.CheckInDate.Format("2006-01-02"), hotel.CheckOutDate.Format("2006-01-02")))
+		}
+	}
+
+	return issues
+}
+
+// validateHotelOverlaps checks that no two hotel stays overlap
+func validateHotelOverlaps(itinerary *models.Itinerary) []ValidationIssue {
+	order := make([]int, len(itinerary.Hotels))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return itinerary.Hotels[order[a]].CheckInDate.Before(itinerary.Hotels[order[b]].CheckInDate)
+	})
+
+	var issues []ValidationIssue
+	for k := 1; k < len(order); k++ {
+		prev := itinerary.Hotels[order[k-1]]
+		next := itinerary.Hotels[order[k]]
+		if dateOf(next.CheckInDate).Before(dateOf(prev.CheckOutDate)) {
+			issues = append(issues, issue(fmt.Sprintf("hotels[%d]", order[k]), CodeHotelOverlap,
+				"%s check-in on %s overlaps the stay at %s (hotels[%d])", next.Name,
+				next.CheckInDate.Format("2006-01-02"), prev.Name, order[k-1]))
+		}
+	}
+
+	return issues
+}
+
+// validateFlightTimes checks that every flight arrives after it departs
+func validateFlightTimes(itinerary *models.Itinerary) []ValidationIssue {
+	var issues []ValidationIssue
+	for i, flight := range itinerary.Flights {
+		if !flight.Arrival.After(flight.Departure) {
+			issues = append(issues, issue(fmt.Sprintf("flights[%d].arrival", i), CodeInvalidFlightTimes,
+				"flight %s must arrive after it departs", flight.FlightNumber))
+		}
+	}
+	return issues
+}
+
+// validateTransferTimes checks that every transfer happens during the trip
+func validateTransferTimes(itinerary *models.Itinerary) []ValidationIssue {
+	var issues []ValidationIssue
+	for i, transfer := range itinerary.Transfers {
+		date := localDateOf(transfer.Timing, transferZone(itinerary, transfer))
+		if date.Before(dateOf(itinerary.StartDate)) || date.After(dateOf(itinerary.EndDate)) {
+			issues = append(issues, issue(fmt.Sprintf("transfers[%d].time", i), CodeTransferOutsideTrip,
+				"transfer from %s to %s on %s falls outside the trip", transfer.From, transfer.To,
+				date.Format("2006-01-02")))
+		}
+	}
+	return issues
+}
+
+// validatePaymentPlan checks payment plan consistency
+func validatePaymentPlan(itinerary *models.Itinerary) []ValidationIssue {
+	plan := &itinerary.PaymentPlan
+	var issues []ValidationIssue
+
+	if !plan.AmountDue.Amount.IsPositive() {
+		issues = append(issues, issue("payment_plan.amount_due", CodeInvalidAmount, "total amount must be positive"))
+	}
+
+	if len(plan.Installments) == 0 {
+		return append(issues, issue("payment_plan.installments", CodeMissingInstallments,
+			"at least one installment is required"))
+	}
+
+	for i, inst := range plan.Installments {
+		if !inst.Amount.Amount.IsPositive() {
+			issues = append(issues, issue(fmt.Sprintf("payment_plan.installments[%d].amount", i), CodeInvalidAmount,
+				"installment %d must have a positive amount (%s)", inst.InstallmentNumber, inst.Amount.Amount))
+		}
+	}
+
+	// the installments are added up exactly, in the currency of the plan, by
+	// the currency service's rule
+	return issues
+}
+
+// warnDaysWithoutActivities flags days with nothing planned
+func warnDaysWithoutActivities(itinerary *models.Itinerary) []ValidationIssue {
+	var issues []ValidationIssue
+	for i, day := range itinerary.Days {
+		if len(allActivities(day.Activities)) == 0 {
+			issues = append(issues, warning(fmt.Sprintf("days[%d].activities", i), CodeDayWithoutActivities,
+				"day %d has no activities planned", day.DayNumber))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"example/vigovia-itenary-api/models"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// june returns midnight UTC of a date in June 2025
+func june(d int) time.Time {
+	return time.Date(2025, time.June, d, 0, 0, 0, 0, time.UTC)
+}
+
+// rupees returns an amount in Indian rupees
+func rupees(amount string) models.Money {
+	return models.NewMoney(decimal.RequireFromString(amount), "INR")
+}
+
+// validTrip returns a valid three day trip with one hotel stay and a single
+// installment
+func validTrip() *models.Itinerary {
+	return &models.Itinerary{
+		ID:        "it-1",
+		Title:     "Test trip",
+		StartDate: june(1),
+		EndDate:   june(3),
+		Days: []models.Day{
+			{DayNumber: 1, Date: june(1)},
+			{DayNumber: 2, Date: june(2)},
+			{DayNumber: 3, Date: june(3)},
+		},
+		Hotels: []models.Hotel{
+			{Name: "Hotel One", City: "Paris", CheckInDate: june(1), CheckOutDate: june(3), Nights: 2},
+		},
+		PaymentPlan: models.PaymentPlan{
+			AmountDue: rupees("1000"),
+			DueDate:   june(1),
+			Installments: []models.Installment{
+				{InstallmentNumber: 1, Amount: rupees("1000"), DueDate: june(1), Status: "Pending"},
+			},
+		},
+		Status: models.StatusDraft,
+	}
+}
+
+// issueCodes returns the codes of a list of issues in order
+func issueCodes(issues []ValidationIssue) []string {
+	var codes []string
+	for _, found := range issues {
+		codes = append(codes, found.Code)
+	}
+	return codes
+}
+
+func TestValidationRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		rule   ValidationRule
+		change func(itinerary *models.Itinerary)
+		want   []string
+	}{
+		{"date range ok", validateDateRange, nil, nil},
+		{"end before start", validateDateRange, func(it *models.Itinerary) {
+			it.EndDate = june(1).AddDate(0, 0, -1)
+		}, []string{CodeInvalidDateRange}},
+		{"one day per date", validateDayCount, nil, nil},
+		{"missing day", validateDayCount, func(it *models.Itinerary) {
+			it.Days = it.Days[:2]
+		}, []string{CodeDayCountMismatch}},
+		{"day count skipped for an invalid range", validateDayCount, func(it *models.Itinerary) {
+			it.EndDate = it.StartDate
+			it.Days = nil
+		}, nil},
+		{"days in sequence", validateDaySequence, nil, nil},
+		{"duplicate day number", validateDaySequence, func(it *models.Itinerary) {
+			it.Days[1].DayNumber = 1
+		}, []string{CodeDuplicateDayNumber, CodeDayNumberOutOfSequence, CodeDayDateMismatch}},
+		{"day on the wrong date", validateDaySequence, func(it *models.Itinerary) {
+			it.Days[2].Date = june(4)
+		}, []string{CodeDayDateMismatch}},
+		{"hotel stay ok", validateHotelStays, nil, nil},
+		{"hotel nights mismatch", validateHotelStays, func(it *models.Itinerary) {
+			it.Hotels[0].Nights = 3
+		}, []string{CodeHotelNightsMismatch}},
+		{"hotel check-out before check-in", validateHotelStays, func(it *models.Itinerary) {
+			it.Hotels[0].CheckOutDate = june(1)
+		}, []string{CodeInvalidHotelDates}},
+		{"hotel outside the trip", validateHotelStays, func(it *models.Itinerary) {
+			it.Hotels[0].CheckOutDate, it.Hotels[0].Nights = june(5), 4
+		}, []string{CodeHotelOutsideTrip}},
+		{"back to back stays", validateHotelOverlaps, func(it *models.Itinerary) {
+			it.Hotels = []models.Hotel{
+				{Name: "Hotel Two", CheckInDate: june(2), CheckOutDate: june(3), Nights: 1},
+				{Name: "Hotel One", CheckInDate: june(1), CheckOutDate: june(2), Nights: 1},
+			}
+		}, nil},
+		{"overlapping stays", validateHotelOverlaps, func(it *models.Itinerary) {
+			it.Hotels = append(it.Hotels, models.Hotel{Name: "Hotel Two", CheckInDate: june(2), CheckOutDate: june(3), Nights: 1})
+		}, []string{CodeHotelOverlap}},
+		{"flight arrives after departure", validateFlightTimes, func(it *models.Itinerary) {
+			it.Flights = []models.Flight{{FlightNumber: "AF1", Departure: june(1).Add(8 * time.Hour), Arrival: june(1).Add(10 * time.Hour)}}
+		}, nil},
+		{"flight arrives before departure", validateFlightTimes, func(it *models.Itinerary) {
+			it.Flights = []models.Flight{{FlightNumber: "AF1", Departure: june(1).Add(10 * time.Hour), Arrival: june(1).Add(8 * time.Hour)}}
+		}, []string{CodeInvalidFlightTimes}},
+		{"transfer during the trip", validateTransferTimes, func(it *models.Itinerary) {
+			it.Transfers = []models.Transfer{{From: "Airport", To: "Hotel One", Timing: june(3).Add(23 * time.Hour)}}
+		}, nil},
+		{"transfer after the trip", validateTransferTimes, func(it *models.Itinerary) {
+			it.Transfers = []models.Transfer{{From: "Airport", To: "Hotel One", Timing: june(4)}}
+		}, []string{CodeTransferOutsideTrip}},
+		{"payment plan ok", validatePaymentPlan, nil, nil},
+		{"no installments", validatePaymentPlan, func(it *models.Itinerary) {
+			it.PaymentPlan.Installments = nil
+		}, []string{CodeMissingInstallments}},
+		{"nothing due", validatePaymentPlan, func(it *models.Itinerary) {
+			it.PaymentPlan.AmountDue = rupees("0")
+		}, []string{CodeInvalidAmount}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			itinerary := validTrip()
+			if test.change != nil {
+				test.change(itinerary)
+			}
+			if got := issueCodes(test.rule(itinerary)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDefaultValidationRulesAcceptValidItinerary(t *testing.T) {
+	validator := NewValidator(DefaultValidationRules(NewJourneyService(DefaultMinConnectionTime))...)
+	report := validator.Report(validTrip())
+	if !report.Valid {
+		t.Errorf("got errors %v", report.Errors)
+	}
+}